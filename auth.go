@@ -0,0 +1,336 @@
+package kamacache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/SuperJinggg/mycache-go/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnauthenticated 无法认证调用方身份
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ErrPermissionDenied 调用方无权执行该操作
+var ErrPermissionDenied = errors.New("permission denied")
+
+// authorizationHeader 携带令牌的元数据键
+const authorizationHeader = "authorization"
+
+// Operation 表示需要授权的缓存操作
+type Operation string
+
+const (
+	OpGet    Operation = "get"    // Get、Lease
+	OpSet    Operation = "set"    // Set、Incr
+	OpDelete Operation = "delete" // Delete
+)
+
+// Authenticator 从请求上下文中认证调用方身份
+type Authenticator interface {
+	Authenticate(ctx context.Context) (identity string, err error)
+}
+
+// AuthenticatorFunc 函数类型实现 Authenticator 接口
+type AuthenticatorFunc func(ctx context.Context) (string, error)
+
+// Authenticate 实现 Authenticator 接口
+func (f AuthenticatorFunc) Authenticate(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// Authorizer 判断身份是否可以对组执行操作
+type Authorizer interface {
+	Authorize(identity, group string, op Operation) bool
+}
+
+// identityKey 身份在上下文中的键
+type identityKey struct{}
+
+// IdentityFromContext 返回认证拦截器写入上下文的调用方身份
+func IdentityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityKey{}).(string)
+	return identity, ok
+}
+
+// bearerToken 从元数据中读取 Bearer 令牌
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return "", false
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	return token, ok && token != ""
+}
+
+// StaticTokenAuthenticator 使用静态 Bearer 令牌认证，键为令牌，值为对应的身份
+type StaticTokenAuthenticator map[string]string
+
+// Authenticate 实现 Authenticator 接口
+func (a StaticTokenAuthenticator) Authenticate(ctx context.Context) (string, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+
+	// 使用常量时间比较，避免通过耗时猜测令牌
+	var identity string
+	found := false
+	for t, id := range a {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			identity, found = id, true
+		}
+	}
+	if !found {
+		return "", ErrUnauthenticated
+	}
+	return identity, nil
+}
+
+// HMACAuthenticator 使用 HMAC 签名令牌认证，令牌格式为 "身份:时间戳:签名"
+type HMACAuthenticator struct {
+	Secret  []byte        // 签名密钥
+	MaxSkew time.Duration // 允许的时间偏差，0表示默认5分钟
+}
+
+// Authenticate 实现 Authenticator 接口
+func (a *HMACAuthenticator) Authenticate(ctx context.Context) (string, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+
+	sigIdx := strings.LastIndex(token, ":")
+	if sigIdx <= 0 {
+		return "", ErrUnauthenticated
+	}
+	payload, sig := token[:sigIdx], token[sigIdx+1:]
+
+	tsIdx := strings.LastIndex(payload, ":")
+	if tsIdx <= 0 {
+		return "", ErrUnauthenticated
+	}
+	identity := payload[:tsIdx]
+	ts, err := strconv.ParseInt(payload[tsIdx+1:], 10, 64)
+	if err != nil {
+		return "", ErrUnauthenticated
+	}
+
+	maxSkew := a.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = 5 * time.Minute
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > maxSkew || d < -maxSkew {
+		return "", ErrUnauthenticated
+	}
+
+	expected := signHMAC(a.Secret, payload)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", ErrUnauthenticated
+	}
+	return identity, nil
+}
+
+// signHMAC 计算签名
+func signHMAC(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewHMACToken 为身份生成当前时刻的 HMAC 令牌
+func NewHMACToken(identity string, secret []byte) string {
+	payload := fmt.Sprintf("%s:%d", identity, time.Now().Unix())
+	return payload + ":" + signHMAC(secret, payload)
+}
+
+// MTLSAuthenticator 使用已校验的客户端证书认证，需要服务端启用双向TLS
+type MTLSAuthenticator struct {
+	// IdentityFunc 将证书映射为身份，为空时使用证书的 CommonName
+	IdentityFunc func(cert *x509.Certificate) string
+}
+
+// Authenticate 实现 Authenticator 接口
+func (a *MTLSAuthenticator) Authenticate(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", ErrUnauthenticated
+	}
+
+	cert := info.State.VerifiedChains[0][0]
+	identity := cert.Subject.CommonName
+	if a.IdentityFunc != nil {
+		identity = a.IdentityFunc(cert)
+	}
+	if identity == "" {
+		return "", ErrUnauthenticated
+	}
+	return identity, nil
+}
+
+// ChainAuthenticators 依次尝试多个认证方式，返回第一个成功的身份
+func ChainAuthenticators(auths ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context) (string, error) {
+		for _, a := range auths {
+			if identity, err := a.Authenticate(ctx); err == nil {
+				return identity, nil
+			}
+		}
+		return "", ErrUnauthenticated
+	})
+}
+
+// ACLRule 访问控制规则，Identity 和 Group 为 "*" 时匹配任意值，Ops 为空时允许所有操作
+type ACLRule struct {
+	Identity string
+	Group    string
+	Ops      []Operation
+}
+
+// ACL 基于规则列表的授权器，任意一条规则匹配即允许
+type ACL struct {
+	rules []ACLRule
+}
+
+// NewACL 创建访问控制列表
+func NewACL(rules ...ACLRule) *ACL {
+	return &ACL{rules: rules}
+}
+
+// Authorize 实现 Authorizer 接口
+func (a *ACL) Authorize(identity, group string, op Operation) bool {
+	for _, r := range a.rules {
+		if r.Identity != "*" && r.Identity != identity {
+			continue
+		}
+		if r.Group != "*" && r.Group != group {
+			continue
+		}
+		if len(r.Ops) == 0 {
+			return true
+		}
+		for _, o := range r.Ops {
+			if o == op {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// methodOperations 将 gRPC 方法映射为需要授权的操作
+var methodOperations = map[string]Operation{
+	"/pb.MyCache/Get":    OpGet,
+	"/pb.MyCache/Lease":  OpGet,
+	"/pb.MyCache/Set":    OpSet,
+	"/pb.MyCache/Incr":   OpSet,
+	"/pb.MyCache/Delete": OpDelete,
+
+	"/pb.MyCache/GetStream": OpGet,
+	"/pb.MyCache/SetStream": OpSet,
+}
+
+// cacheServicePrefix 缓存服务方法名的前缀
+var cacheServicePrefix = "/" + pb.MyCache_ServiceDesc.ServiceName + "/"
+
+// methodOperation 返回方法需要授权的操作，protected 为 false 表示不属于缓存服务（如健康检查），不需要认证。
+// 缓存服务中没有映射的方法一律拒绝，之后新增的RPC需要先加入 methodOperations 才能访问
+func methodOperation(method string) (op Operation, protected bool, err error) {
+	if op, ok := methodOperations[method]; ok {
+		return op, true, nil
+	}
+	if strings.HasPrefix(method, cacheServicePrefix) {
+		return "", true, status.Errorf(codes.PermissionDenied, "method %s is not mapped to an operation", method)
+	}
+	return "", false, nil
+}
+
+// authorize 认证调用方并检查对组的操作权限，成功时返回携带身份的上下文
+func authorize(ctx context.Context, authn Authenticator, authz Authorizer, group string, op Operation) (context.Context, error) {
+	identity, err := authn.Authenticate(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if authz != nil && !authz.Authorize(identity, group, op) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to %s on group %s", identity, op, group)
+	}
+
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+// authUnaryInterceptor 对缓存服务的请求进行认证和授权，健康检查等其他服务不受影响
+func authUnaryInterceptor(authn Authenticator, authz Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		op, protected, err := methodOperation(info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if !protected {
+			return handler(ctx, req)
+		}
+
+		var group string
+		if r, ok := req.(interface{ GetGroup() string }); ok {
+			group = r.GetGroup()
+		}
+
+		ctx, err = authorize(ctx, authn, authz, group, op)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// tokenCredentials 在每次RPC中携带 Bearer 令牌
+type tokenCredentials struct {
+	token func() string
+}
+
+// GetRequestMetadata 实现 credentials.PerRPCCredentials 接口
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authorizationHeader: "Bearer " + t.token()}, nil
+}
+
+// RequireTransportSecurity 实现 credentials.PerRPCCredentials 接口
+// 允许在未加密的连接上使用，生产环境应配合 WithClientTLS 使用
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithBearerToken 在每次RPC中携带静态令牌
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) {
+		c.perRPCCreds = tokenCredentials{token: func() string { return token }}
+	}
+}
+
+// WithHMACToken 在每次RPC中携带新签名的 HMAC 令牌
+func WithHMACToken(identity string, secret []byte) ClientOption {
+	return func(c *Client) {
+		c.perRPCCreds = tokenCredentials{token: func() string { return NewHMACToken(identity, secret) }}
+	}
+}
@@ -0,0 +1,82 @@
+package kamacache
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/SuperJinggg/mycache-go/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 测试认证拦截器对各方法的处理
+func TestAuthUnaryInterceptor(t *testing.T) {
+	authn := StaticTokenAuthenticator{"reader-token": "reader", "writer-token": "writer"}
+	authz := NewACL(
+		ACLRule{Identity: "reader", Group: "users", Ops: []Operation{OpGet}},
+		ACLRule{Identity: "writer", Group: "*"},
+	)
+	interceptor := authUnaryInterceptor(authn, authz)
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		req      interface{}
+		wantCode codes.Code
+		wantID   string
+	}{
+		{"有权限的读取", "/pb.MyCache/Get", "reader-token", &pb.Request{Group: "users"}, codes.OK, "reader"},
+		{"缺少令牌", "/pb.MyCache/Get", "", &pb.Request{Group: "users"}, codes.Unauthenticated, ""},
+		{"无效令牌", "/pb.MyCache/Get", "bad", &pb.Request{Group: "users"}, codes.Unauthenticated, ""},
+		{"无权限的写入", "/pb.MyCache/Set", "reader-token", &pb.Request{Group: "users"}, codes.PermissionDenied, ""},
+		{"无权限的组", "/pb.MyCache/Get", "reader-token", &pb.Request{Group: "orders"}, codes.PermissionDenied, ""},
+		{"计数器需要写权限", "/pb.MyCache/Incr", "writer-token", &pb.IncrRequest{Group: "users"}, codes.OK, "writer"},
+		{"未映射的缓存服务方法被拒绝", "/pb.MyCache/Future", "writer-token", &pb.Request{Group: "users"}, codes.PermissionDenied, ""},
+		{"健康检查不需要认证", "/grpc.health.v1.Health/Check", "", nil, codes.OK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationHeader, "Bearer "+tt.token))
+			}
+
+			called := false
+			var identity string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				identity, _ = IdentityFromContext(ctx)
+				return nil, nil
+			}
+			_, err := interceptor(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("状态码应为 %v，实际为 %v (%v)", tt.wantCode, code, err)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("处理函数是否被调用: %v", called)
+			}
+			if identity != tt.wantID {
+				t.Errorf("身份应为 %q，实际为 %q", tt.wantID, identity)
+			}
+		})
+	}
+}
+
+// 测试流式认证拦截器拒绝未映射的方法
+func TestAuthStreamInterceptor(t *testing.T) {
+	interceptor := authStreamInterceptor(StaticTokenAuthenticator{"token": "user"}, nil)
+	handler := func(srv interface{}, ss grpc.ServerStream) error { return nil }
+
+	err := interceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: "/pb.MyCache/FutureStream"}, handler)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("未映射的流式方法应被拒绝，实际为 %v", err)
+	}
+	if err := interceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}, handler); err != nil {
+		t.Errorf("其他服务的流式方法不需要认证: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package kamacache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器处于打开状态，请求被快速拒绝
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerOptions 熔断器配置，设置 FailureRate 时按窗口内的失败比例熔断，否则按连续失败次数熔断
+type BreakerOptions struct {
+	FailureThreshold int           // 连续失败次数达到该值后熔断，0表示不启用熔断
+	OpenTimeout      time.Duration // 熔断持续时间，之后放行一个试探请求
+
+	FailureRate float64       // 窗口内失败的比例达到该值后熔断，取值 (0, 1]，0表示按连续失败次数熔断
+	MinRequests int           // 窗口内的请求数达到该值后才按比例熔断，0表示默认10
+	Window      time.Duration // 统计失败比例的窗口，0表示默认10秒
+}
+
+// DefaultBreakerOptions 返回默认的熔断器配置
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		FailureThreshold: 5,
+		OpenTimeout:      5 * time.Second,
+	}
+}
+
+// breakerState 熔断器状态
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 正常放行
+	breakerOpen                         // 拒绝所有请求
+	breakerHalfOpen                     // 只放行一个试探请求
+)
+
+// circuitBreaker 基于连续失败次数或窗口内失败比例的熔断器
+type circuitBreaker struct {
+	mu          sync.Mutex
+	opts        BreakerOptions
+	state       breakerState
+	failures    int // 连续失败次数，按比例熔断时为窗口内的失败次数
+	requests    int // 窗口内的请求数，只在按比例熔断时使用
+	windowStart time.Time
+	openedAt    time.Time
+	probing     bool // 半开状态下是否已有试探请求
+}
+
+// newCircuitBreaker 创建熔断器，FailureThreshold 和 FailureRate 都不大于0时返回 nil
+func newCircuitBreaker(opts BreakerOptions) *circuitBreaker {
+	if opts.FailureThreshold <= 0 && opts.FailureRate <= 0 {
+		return nil
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = DefaultBreakerOptions().OpenTimeout
+	}
+	if opts.FailureRate > 0 {
+		if opts.MinRequests <= 0 {
+			opts.MinRequests = 10
+		}
+		if opts.Window <= 0 {
+			opts.Window = 10 * time.Second
+		}
+	}
+	return &circuitBreaker{opts: opts, windowStart: time.Now()}
+}
+
+// allow 判断是否放行请求，半开状态下只放行一个试探请求
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.opts.OpenTimeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// available 判断当前是否可能放行请求，不占用试探名额，用于选择节点
+func (b *circuitBreaker) available() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		return time.Since(b.openedAt) >= b.opts.OpenTimeout
+	case breakerHalfOpen:
+		return !b.probing
+	default:
+		return true
+	}
+}
+
+// onSuccess 记录成功，试探成功时关闭熔断器
+func (b *circuitBreaker) onSuccess() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != breakerClosed {
+		b.reset(breakerClosed)
+		return
+	}
+	if b.opts.FailureRate <= 0 {
+		b.failures = 0
+		return
+	}
+	b.roll()
+	b.requests++
+}
+
+// onFailure 记录失败，连续失败达到阈值、窗口内失败比例达到阈值或试探失败时打开熔断器
+func (b *circuitBreaker) onFailure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.reset(breakerOpen)
+		return
+	}
+
+	if b.opts.FailureRate <= 0 {
+		b.failures++
+		if b.failures >= b.opts.FailureThreshold {
+			b.reset(breakerOpen)
+		}
+		return
+	}
+
+	b.roll()
+	b.requests++
+	b.failures++
+	if b.requests >= b.opts.MinRequests && float64(b.failures) >= b.opts.FailureRate*float64(b.requests) {
+		b.reset(breakerOpen)
+	}
+}
+
+// onIgnored 请求的结果不代表后端是否正常（例如调用方取消），不计入统计，只归还试探名额
+func (b *circuitBreaker) onIgnored() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// roll 统计窗口结束时开始新的窗口，调用方需持有锁
+func (b *circuitBreaker) roll() {
+	if now := time.Now(); now.Sub(b.windowStart) >= b.opts.Window {
+		b.windowStart = now
+		b.failures = 0
+		b.requests = 0
+	}
+}
+
+// reset 切换到打开或关闭状态并清空统计，调用方需持有锁
+func (b *circuitBreaker) reset(state breakerState) {
+	b.state = state
+	b.failures = 0
+	b.requests = 0
+	b.probing = false
+	b.windowStart = time.Now()
+	if state == breakerOpen {
+		b.openedAt = b.windowStart
+	}
+}
+
+// isOpen 返回熔断器是否处于打开或半开状态
+func (b *circuitBreaker) isOpen() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != breakerClosed
+}
@@ -1,20 +1,121 @@
 package kamacache
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCorruptValue 压缩保存的值无法解压
+var ErrCorruptValue = errors.New("cached value is corrupt")
+
 // ByteView 只读的字节视图，用于缓存数据
+// 开启压缩时本地缓存中的视图保存压缩后的数据，Group.Get 返回前解压一次，返回给调用方的视图都已解压
 type ByteView struct {
-	b []byte
+	b       []byte
+	codec   Compressor // 压缩算法，nil表示未压缩
+	size    int        // 压缩前的长度
+	version uint64     // 写入本地缓存时分配的版本号，用于条件写入
 }
 
+// Len 返回值的长度，压缩时为压缩前的长度
 func (b ByteView) Len() int {
+	if b.codec != nil {
+		return b.size
+	}
+	return len(b.b)
+}
+
+// Size 返回在缓存中占用的字节数，压缩时为压缩后的长度
+func (b ByteView) Size() int {
 	return len(b.b)
 }
 
+// ByteSLice 返回值的副本，调用方可以任意修改
 func (b ByteView) ByteSLice() []byte {
+	if b.codec != nil {
+		return b.data()
+	}
 	return cloneBytes(b.b)
 }
 
 func (b ByteView) String() string {
-	return string(b.b)
+	return string(b.data())
+}
+
+// Reader 返回读取值的 io.ReadSeeker，不复制数据
+func (b ByteView) Reader() io.ReadSeeker {
+	return bytes.NewReader(b.data())
+}
+
+// WriteTo 将值写入 w，不复制数据，实现 io.WriterTo
+func (b ByteView) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(b.data())
+	return int64(n), err
+}
+
+// At 返回第 i 个字节，压缩时每次调用都需要解压
+func (b ByteView) At(i int) byte {
+	return b.data()[i]
+}
+
+// Slice 返回 [from, to) 范围的视图，与原视图共享数据
+func (b ByteView) Slice(from, to int) ByteView {
+	return ByteView{b: b.data()[from:to]}
+}
+
+// Equal 判断两个视图的值是否相同
+func (b ByteView) Equal(other ByteView) bool {
+	if b.Len() != other.Len() {
+		return false
+	}
+	return bytes.Equal(b.data(), other.data())
+}
+
+// Copy 将值复制到 dst，返回复制的字节数
+func (b ByteView) Copy(dst []byte) int {
+	return copy(dst, b.data())
+}
+
+// data 返回值的底层数据，未压缩时不复制。仅供内部只读使用，不能修改或交给外部调用方。
+// Group.Get 返回的视图已经解压，压缩的视图只会出现在内部路径上，解压失败时返回 nil
+func (b ByteView) data() []byte {
+	if b.codec == nil {
+		return b.b
+	}
+	out, err := b.decompress()
+	if err != nil {
+		logrus.Errorf("[KamaCache] %v", err)
+		return nil
+	}
+	return out
+}
+
+// decompress 解压数据，返回新分配的切片，数据损坏时返回 ErrCorruptValue
+func (b ByteView) decompress() ([]byte, error) {
+	out, err := b.codec.Decompress(b.b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decompress value with %s: %v", ErrCorruptValue, b.codec.Name(), err)
+	}
+	if len(out) != b.size {
+		return nil, fmt.Errorf("%w: decompressed %d bytes, expected %d", ErrCorruptValue, len(out), b.size)
+	}
+	return out, nil
+}
+
+// decode 返回解压后的视图，未压缩时原样返回
+func (b ByteView) decode() (ByteView, error) {
+	if b.codec == nil {
+		return b, nil
+	}
+	out, err := b.decompress()
+	if err != nil {
+		return ByteView{}, err
+	}
+	return ByteView{b: out, version: b.version}, nil
 }
 
 func cloneBytes(b []byte) []byte {
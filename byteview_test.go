@@ -0,0 +1,56 @@
+package kamacache
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// 测试 ByteView 的零拷贝访问方法，压缩与未压缩的视图行为一致
+func TestByteViewAccessors(t *testing.T) {
+	raw := []byte("hello mycache hello mycache hello mycache")
+	compressed, _ := SnappyCompressor().Compress(raw)
+	views := map[string]ByteView{
+		"未压缩": {b: raw},
+		"已压缩": {b: compressed, codec: SnappyCompressor(), size: len(raw)},
+	}
+
+	for name, v := range views {
+		t.Run(name, func(t *testing.T) {
+			out, err := io.ReadAll(v.Reader())
+			if err != nil || !bytes.Equal(out, raw) {
+				t.Fatalf("Reader 读取结果不正确: %q, %v", out, err)
+			}
+
+			var buf bytes.Buffer
+			if n, err := v.WriteTo(&buf); err != nil || n != int64(len(raw)) || !bytes.Equal(buf.Bytes(), raw) {
+				t.Fatalf("WriteTo 写入结果不正确: %d, %v", n, err)
+			}
+
+			if v.At(1) != 'e' {
+				t.Fatalf("At(1) 应为 'e'，实际为 %q", v.At(1))
+			}
+			if s := v.Slice(6, 13); s.String() != "mycache" || s.Len() != 7 {
+				t.Fatalf("Slice 结果不正确: %q", s.String())
+			}
+
+			dst := make([]byte, 5)
+			if n := v.Copy(dst); n != 5 || string(dst) != "hello" {
+				t.Fatalf("Copy 结果不正确: %d, %q", n, dst)
+			}
+
+			if !v.Equal(views["未压缩"]) || v.Equal(ByteView{b: []byte("hello")}) {
+				t.Fatal("Equal 比较结果不正确")
+			}
+		})
+	}
+
+	t.Run("ByteSLice返回副本", func(t *testing.T) {
+		v := ByteView{b: cloneBytes(raw)}
+		b := v.ByteSLice()
+		b[0] = 'H'
+		if v.At(0) != 'h' {
+			t.Fatal("修改 ByteSLice 的结果不应影响视图")
+		}
+	})
+}
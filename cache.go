@@ -14,12 +14,14 @@ import (
 // Cache 是对底层缓存存储的封装
 type Cache struct {
 	mu          sync.RWMutex
-	store       store.Store  // 底层存储实现
-	opts        CacheOptions // 缓存配置选项
-	hits        int64        // 缓存命中次数
-	misses      int64        // 缓存未命中次数
-	initialized int32        // 原子变量，标记缓存是否已初始化
-	closed      int32        // 原子变量，标记缓存是否已关闭
+	store       store.Store                     // 底层存储实现
+	opts        CacheOptions                    // 缓存配置选项
+	hits        int64                           // 缓存命中次数
+	misses      int64                           // 缓存未命中次数
+	evictions   [store.NumEvictionReasons]int64 // 按移除原因统计的缓存项被移除的次数
+	version     uint64                          // 最近一次分配的版本号
+	initialized int32                           // 原子变量，标记缓存是否已初始化
+	closed      int32                           // 原子变量，标记缓存是否已关闭
 }
 
 // CacheOptions 缓存配置选项
@@ -31,6 +33,7 @@ type CacheOptions struct {
 	Level2Cap    uint16                              // 二级缓存桶的容量 (用于 LRU2)
 	CleanupTime  time.Duration                       // 清理间隔
 	OnEvicted    func(key string, value store.Value) // 驱逐回调
+	OnEviction   func(e store.Eviction)              // 带有移除原因的驱逐回调
 }
 
 // DefaultCacheOptions 返回默认的缓存配置
@@ -49,7 +52,8 @@ func DefaultCacheOptions() CacheOptions {
 // NewCache 创建一个新的缓存实例
 func NewCache(opts CacheOptions) *Cache {
 	return &Cache{
-		opts: opts,
+		opts:    opts,
+		version: uint64(time.Now().UnixNano()), // 避免节点重启后版本号重复
 	}
 }
 
@@ -73,6 +77,7 @@ func (c *Cache) ensureInitialized() {
 			Level2Cap:       c.opts.Level2Cap,
 			CleanupInterval: c.opts.CleanupTime,
 			OnEvicted:       c.opts.OnEvicted,
+			OnEviction:      c.onEviction,
 		}
 
 		// 创建存储实例
@@ -94,6 +99,10 @@ func (c *Cache) Add(key string, value ByteView) {
 
 	c.ensureInitialized()
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	value.version = atomic.AddUint64(&c.version, 1)
 	if err := c.store.Set(key, value); err != nil {
 		logrus.Warnf("Failed to add key %s to cache: %v", key, err)
 	}
@@ -151,12 +160,45 @@ func (c *Cache) AddWithExpiration(key string, value ByteView, expirationTime tim
 		return
 	}
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	// 设置到底层存储
+	value.version = atomic.AddUint64(&c.version, 1)
 	if err := c.store.SetWithExpiration(key, value, expiration); err != nil {
 		logrus.Warnf("Failed to add key %s to cache with expiration: %v", key, err)
 	}
 }
 
+// addIf 在 check 返回 nil 时写入 value 并返回分配的版本号，检查和写入之间不会有其他写入。
+// check 的 ok 为 false 表示 key 不存在，expireAt 为零值表示永不过期
+func (c *Cache) addIf(key string, value ByteView, expireAt time.Time, check func(current ByteView, ok bool) error) (uint64, error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return 0, ErrGroupClosed
+	}
+
+	c.ensureInitialized()
+
+	// 持有写锁，Add 和 AddWithExpiration 持有读锁，因此检查期间不会被其他写入打断
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var current ByteView
+	v, _, ok := c.store.Peek(key)
+	if ok {
+		current, ok = v.(ByteView)
+	}
+	if err := check(current, ok); err != nil {
+		return 0, err
+	}
+
+	value.version = atomic.AddUint64(&c.version, 1)
+	if expireAt.IsZero() {
+		return value.version, c.store.Set(key, value)
+	}
+	return value.version, c.store.SetWithExpiration(key, value, max(time.Until(expireAt), time.Nanosecond))
+}
+
 // Delete 从缓存中删除一个 key
 func (c *Cache) Delete(key string) bool {
 	if atomic.LoadInt32(&c.closed) == 1 || atomic.LoadInt32(&c.initialized) == 0 {
@@ -185,6 +227,79 @@ func (c *Cache) Clear() {
 	atomic.StoreInt64(&c.misses, 0)
 }
 
+// onEviction 按原因统计移除次数并调用配置的驱逐回调
+func (c *Cache) onEviction(e store.Eviction) {
+	if int(e.Reason) < len(c.evictions) {
+		atomic.AddInt64(&c.evictions[e.Reason], 1)
+	}
+	if c.opts.OnEviction != nil {
+		c.opts.OnEviction(e)
+	}
+}
+
+// Evictions 返回因 reason 被移除的缓存项数量
+func (c *Cache) Evictions(reason store.EvictionReason) int64 {
+	if int(reason) >= len(c.evictions) {
+		return 0
+	}
+	return atomic.LoadInt64(&c.evictions[reason])
+}
+
+// Bytes 返回缓存项占用的字节数
+func (c *Cache) Bytes() int64 {
+	if atomic.LoadInt32(&c.closed) == 1 || atomic.LoadInt32(&c.initialized) == 0 {
+		return 0
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if bc, ok := c.store.(store.ByteCounter); ok {
+		return bc.UsedBytes()
+	}
+	var n int64
+	c.store.Range(func(key string, value store.Value) bool {
+		n += int64(len(key))
+		if s, ok := value.(store.Sizer); ok {
+			n += int64(s.Size())
+		} else {
+			n += int64(value.Len())
+		}
+		return true
+	})
+	return n
+}
+
+// MaxBytes 返回缓存的最大内存
+func (c *Cache) MaxBytes() int64 {
+	if atomic.LoadInt32(&c.closed) == 0 && atomic.LoadInt32(&c.initialized) == 1 {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		if r, ok := c.store.(store.Resizer); ok {
+			return r.MaxBytes()
+		}
+	}
+	return c.opts.MaxBytes
+}
+
+// SetMaxBytes 调整缓存的最大内存，缩小时立即淘汰超出的缓存项，底层存储不支持调整时返回 false
+func (c *Cache) SetMaxBytes(maxBytes int64) bool {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return false
+	}
+	c.ensureInitialized()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	r, ok := c.store.(store.Resizer)
+	if !ok {
+		return false
+	}
+	r.SetMaxBytes(maxBytes)
+	return true
+}
+
 // Len 返回缓存的当前存储项数量
 func (c *Cache) Len() int {
 	if atomic.LoadInt32(&c.closed) == 1 || atomic.LoadInt32(&c.initialized) == 0 {
@@ -197,6 +312,70 @@ func (c *Cache) Len() int {
 	return c.store.Len()
 }
 
+// peek 返回 key 的值和过期时间，永不过期时 expireAt 为零值。不计入命中统计
+func (c *Cache) peek(key string) (value ByteView, expireAt time.Time, ok bool) {
+	if atomic.LoadInt32(&c.closed) == 1 || atomic.LoadInt32(&c.initialized) == 0 {
+		return ByteView{}, time.Time{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.store == nil {
+		return ByteView{}, time.Time{}, false
+	}
+
+	v, expireAt, found := c.store.Peek(key)
+	if !found {
+		return ByteView{}, time.Time{}, false
+	}
+	view, ok := v.(ByteView)
+	return view, expireAt, ok
+}
+
+// TTL 返回 key 在本地缓存中的剩余过期时间，永不过期时返回0，不存在时 ok 为 false。不计入命中统计
+func (c *Cache) TTL(key string) (ttl time.Duration, ok bool) {
+	_, expireAt, found := c.peek(key)
+	if !found {
+		return 0, false
+	}
+	if expireAt.IsZero() {
+		return 0, true
+	}
+	return max(time.Until(expireAt), time.Millisecond), true
+}
+
+// Range 遍历缓存中所有未过期的项，fn 返回 false 时停止遍历
+func (c *Cache) Range(fn func(key string, value ByteView) bool) {
+	if atomic.LoadInt32(&c.closed) == 1 || atomic.LoadInt32(&c.initialized) == 0 {
+		return
+	}
+
+	// 先取快照再回调，fn 中可以安全地读写缓存
+	type item struct {
+		key   string
+		value ByteView
+	}
+	var items []item
+
+	c.mu.RLock()
+	if c.store != nil {
+		c.store.Range(func(key string, value store.Value) bool {
+			if view, ok := value.(ByteView); ok {
+				items = append(items, item{key, view})
+			}
+			return true
+		})
+	}
+	c.mu.RUnlock()
+
+	for _, it := range items {
+		if !fn(it.key, it.value) {
+			return
+		}
+	}
+}
+
 // Close 关闭缓存，释放资源
 func (c *Cache) Close() {
 	// 如果已经关闭，直接返回
@@ -230,6 +409,16 @@ func (c *Cache) Stats() map[string]interface{} {
 		"misses":      atomic.LoadInt64(&c.misses),
 	}
 
+	stats["max_bytes"] = c.MaxBytes()
+
+	var evictions int64
+	for _, reason := range store.EvictionReasons {
+		n := c.Evictions(reason)
+		stats["evictions_"+reason.String()] = n
+		evictions += n
+	}
+	stats["evictions"] = evictions
+
 	if atomic.LoadInt32(&c.initialized) == 1 {
 		stats["size"] = c.Len()
 
@@ -3,26 +3,86 @@ package kamacache
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
+	"strconv"
 	"time"
 
 	pb "github.com/SuperJinggg/mycache-go/pb"
-	"github.com/sirupsen/logrus"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// defaultCallTimeout 默认的单次RPC超时时间
+const defaultCallTimeout = 3 * time.Second
+
 type Client struct {
-	addr    string
-	svcName string
-	etcdCli *clientv3.Client
-	conn    *grpc.ClientConn
-	grpcCli pb.MyCacheClient
+	addr        string
+	svcName     string
+	etcdCli     *clientv3.Client
+	conn        *grpc.ClientConn
+	grpcCli     pb.MyCacheClient
+	callTimeout time.Duration // 单次RPC超时时间，0表示只使用调用方的截止时间
+
+	creds    credentials.TransportCredentials // 传输层凭证，默认不加密
+	credsErr error                            // 加载凭证时的错误
+
+	perRPCCreds credentials.PerRPCCredentials // 每次RPC携带的认证信息
+
+	retry   RetryPolicy     // Get 请求的重试策略
+	breaker *circuitBreaker // 熔断器，nil表示不启用
+	latency *latencyWindow  // 最近 Get 请求的耗时
+	health  *peerHealth     // 健康状态，nil表示不检查
+
+	streamThreshold int          // 超过该长度的值通过流式RPC分片传输，不大于0表示不使用
+	tracer          trace.Tracer // 链路追踪，nil表示不开启
+	maxMsgSize      int          // 收发单条消息的最大长度，0表示使用 gRPC 默认值
+	maxStreamSize   int64        // 流式读取的值的最大长度，0表示使用默认值
+
+	metrics *rpcMetrics // RPC 指标，由节点选择器设置，nil表示不统计
+	peer    bool        // 是否由节点选择器创建，请求携带 peerMetadataKey 表明来自集群中的节点
+}
+
+// RetryPolicy Get 请求的重试策略，只有幂等的 Get 会重试，且只重试节点不可用类错误
+type RetryPolicy struct {
+	MaxAttempts    int           // 最大尝试次数（包含首次），不大于1表示不重试
+	InitialBackoff time.Duration // 首次重试前的等待时间
+	MaxBackoff     time.Duration // 最大等待时间
 }
 
 var _ Peer = (*Client)(nil)
 
-func NewClient(addr string, svcName string, etcdCli *clientv3.Client) (*Client, error) {
+// ClientOption 定义Client的配置选项
+type ClientOption func(*Client)
+
+// WithCallTimeout 设置单次RPC的超时时间，调用方的截止时间更早时以调用方为准
+func WithCallTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.callTimeout = d
+	}
+}
+
+// WithRetryPolicy 设置 Get 请求的重试策略
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithCircuitBreaker 设置熔断器，FailureThreshold 为0时关闭熔断
+func WithCircuitBreaker(opts BreakerOptions) ClientOption {
+	return func(c *Client) {
+		c.breaker = newCircuitBreaker(opts)
+	}
+}
+
+func NewClient(addr string, svcName string, etcdCli *clientv3.Client, opts ...ClientOption) (*Client, error) {
 	var err error
 	if etcdCli == nil {
 		etcdCli, err = clientv3.New(clientv3.Config{
@@ -34,73 +94,322 @@ func NewClient(addr string, svcName string, etcdCli *clientv3.Client) (*Client,
 		}
 	}
 
-	conn, err := grpc.Dial(addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithTimeout(10*time.Second),
-		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial server: %v", err)
+	client := &Client{
+		addr:        addr,
+		svcName:     svcName,
+		etcdCli:     etcdCli,
+		callTimeout: defaultCallTimeout,
+		creds:       insecure.NewCredentials(),
+		breaker:     newCircuitBreaker(DefaultBreakerOptions()),
+		latency:     newLatencyWindow(),
+
+		streamThreshold: defaultStreamThreshold,
 	}
 
-	grpcClient := pb.NewMyCacheClient(conn)
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.credsErr != nil {
+		return nil, client.credsErr
+	}
 
-	client := &Client{
-		addr:    addr,
-		svcName: svcName,
-		etcdCli: etcdCli,
-		conn:    conn,
-		grpcCli: grpcClient,
+	// 连接在后台建立，节点不可用时请求立即失败而不是阻塞到超时
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(client.creds),
+		grpc.WithChainUnaryInterceptor(peerMetricsInterceptor(client)),
+		grpc.WithChainStreamInterceptor(peerMetricsStreamInterceptor(client)),
+	}
+	if client.perRPCCreds != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(client.perRPCCreds))
 	}
+	if client.tracer != nil {
+		dialOpts = append(dialOpts,
+			grpc.WithChainUnaryInterceptor(tracingUnaryClientInterceptor(client.tracer, addr)),
+			grpc.WithChainStreamInterceptor(tracingStreamClientInterceptor(client.tracer, addr)),
+		)
+	}
+	if client.maxMsgSize > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(client.maxMsgSize),
+			grpc.MaxCallSendMsgSize(client.maxMsgSize),
+		))
+	}
+
+	conn, err := grpc.NewClient(addr, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc client: %v", err)
+	}
+
+	client.conn = conn
+	client.grpcCli = pb.NewMyCacheClient(conn)
 
 	return client, nil
 }
 
-func (c *Client) Get(group, key string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// callContext 为单次RPC创建上下文，保留调用方的截止时间、取消信号和元数据
+func (c *Client) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.peer {
+		ctx = metadata.AppendToOutgoingContext(ctx, peerMetadataKey, "1")
+	}
+	if c.callTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.callTimeout)
+}
+
+// invoke 在熔断器的保护下执行一次RPC
+func (c *Client) invoke(ctx context.Context, call func(ctx context.Context) error) error {
+	if !c.breaker.allow() {
+		return status.Errorf(codes.Unavailable, "%v: %s", ErrCircuitOpen, c.addr)
+	}
+
+	callCtx, cancel := c.callContext(ctx)
 	defer cancel()
 
-	resp, err := c.grpcCli.Get(ctx, &pb.Request{
-		Group: group,
-		Key:   key,
+	err := call(callCtx)
+	switch {
+	case err == nil:
+		c.breaker.onSuccess()
+		c.health.observe(false)
+	case ctx.Err() != nil:
+		// 调用方自身超时或取消（包括对冲请求中落败的一方）不代表节点是否正常
+		c.breaker.onIgnored()
+	case isTransientError(err):
+		c.breaker.onFailure()
+		c.health.observe(true)
+	default:
+		c.breaker.onSuccess()
+		c.health.observe(false)
+	}
+	return err
+}
+
+// isTransientError 判断是否为连接失败、超时等节点不可用类错误，
+// 对端业务逻辑返回的错误（携带本项目错误详情，即使状态码相同）不属于此类
+func isTransientError(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	if st.Code() != codes.Unavailable && st.Code() != codes.DeadlineExceeded {
+		return false
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain {
+			return false
+		}
+	}
+	return true
+}
+
+// Available 返回节点当前是否可用，熔断或判定为不健康期间返回 false
+func (c *Client) Available() bool {
+	return c.breaker.available() && c.health.available()
+}
+
+func (c *Client) Get(ctx context.Context, group, key string) ([]byte, error) {
+	backoff := c.retry.InitialBackoff
+	if backoff <= 0 {
+		backoff = 10 * time.Millisecond
+	}
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		var value []byte
+		err := c.invoke(ctx, func(ctx context.Context) error {
+			resp, err := c.grpcCli.Get(ctx, &pb.Request{
+				Group:           group,
+				Key:             key,
+				StreamThreshold: int64(max(c.streamThreshold, 0)),
+				AcceptEncoding:  compressorNames(),
+			})
+			if err != nil {
+				return err
+			}
+			// 值超过阈值时服务端只返回标记，改用流式获取
+			if resp.GetStream() {
+				value, err = c.getStream(ctx, group, key)
+				return err
+			}
+			value, err = decodeValue(resp.GetEncoding(), resp.GetValue(), c.streamLimit())
+			return err
+		})
+		if err == nil {
+			c.latency.record(time.Since(start))
+			return value, nil
+		}
+
+		if attempt >= c.retry.MaxAttempts || !isTransientError(err) {
+			return nil, fmt.Errorf("failed to get value from kamacache: %w", fromStatusError(err))
+		}
+
+		// 带随机抖动的指数退避
+		timer := time.NewTimer(backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to get value from kamacache: %w", fromStatusError(err))
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if c.retry.MaxBackoff > 0 && backoff > c.retry.MaxBackoff {
+			backoff = c.retry.MaxBackoff
+		}
+	}
+}
+
+func (c *Client) Delete(ctx context.Context, group, key string) (bool, error) {
+	var resp *pb.ResponseForDelete
+	err := c.invoke(peerWriteContext(ctx), func(ctx context.Context) error {
+		var err error
+		resp, err = c.grpcCli.Delete(ctx, &pb.Request{
+			Group: group,
+			Key:   key,
+		})
+		return err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get value from kamacache: %v", err)
+		return false, fmt.Errorf("failed to delete value from kamacache: %w", fromStatusError(err))
 	}
 
 	return resp.GetValue(), nil
 }
 
-func (c *Client) Delete(group, key string) (bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (c *Client) Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	err := c.invoke(peerWriteContext(ctx), func(ctx context.Context) error {
+		if c.streamThreshold > 0 && len(value) > c.streamThreshold {
+			return c.setStream(ctx, group, key, value, ttl)
+		}
+
+		_, err := c.grpcCli.Set(ctx, &pb.Request{
+			Group: group,
+			Key:   key,
+			Value: value,
+			TtlMs: ttl.Milliseconds(),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set value to kamacache: %w", fromStatusError(err))
+	}
+
+	return nil
+}
 
-	resp, err := c.grpcCli.Delete(ctx, &pb.Request{
-		Group: group,
-		Key:   key,
+func (c *Client) Incr(ctx context.Context, group, key string, delta int64, ttl time.Duration) (int64, error) {
+	var resp *pb.ResponseForIncr
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.grpcCli.Incr(ctx, &pb.IncrRequest{
+			Group: group,
+			Key:   key,
+			Delta: delta,
+			TtlMs: ttl.Milliseconds(),
+		})
+		return err
 	})
 	if err != nil {
-		return false, fmt.Errorf("failed to delete value from kamacache: %v", err)
+		return 0, fmt.Errorf("failed to incr value in kamacache: %w", fromStatusError(err))
 	}
 
 	return resp.GetValue(), nil
 }
 
-func (c *Client) Set(ctx context.Context, group, key string, value []byte) error {
-	resp, err := c.grpcCli.Set(ctx, &pb.Request{
-		Group: group,
-		Key:   key,
-		Value: value,
+func (c *Client) Lease(ctx context.Context, group, key string) (LeaseResult, error) {
+	var resp *pb.ResponseForLease
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.grpcCli.Lease(ctx, &pb.Request{
+			Group: group,
+			Key:   key,
+		})
+		return err
 	})
 	if err != nil {
-		return fmt.Errorf("failed to set value to kamacache: %v", err)
+		return LeaseResult{}, fmt.Errorf("failed to get lease from kamacache: %w", fromStatusError(err))
+	}
+
+	return LeaseResult{
+		Status: LeaseStatus(resp.GetStatus()),
+		Value:  ByteView{b: resp.GetValue()},
+		Token:  resp.GetToken(),
+	}, nil
+}
+
+func (c *Client) SetWithLease(ctx context.Context, group, key string, value []byte, token uint64) error {
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		_, err := c.grpcCli.Set(ctx, &pb.Request{
+			Group:      group,
+			Key:        key,
+			Value:      value,
+			LeaseToken: token,
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set value with lease to kamacache: %w", fromStatusError(err))
 	}
-	logrus.Infof("grpc set request resp: %+v", resp)
 
 	return nil
 }
 
+// GetWithVersion 获取值及其在所有者节点上的版本号，不使用流式传输
+func (c *Client) GetWithVersion(ctx context.Context, group, key string) ([]byte, uint64, error) {
+	var value []byte
+	var version uint64
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var header metadata.MD
+		ctx = metadata.AppendToOutgoingContext(ctx, versionMetadataKey, "0")
+		resp, err := c.grpcCli.Get(ctx, &pb.Request{
+			Group:          group,
+			Key:            key,
+			AcceptEncoding: compressorNames(),
+		}, grpc.Header(&header))
+		if err != nil {
+			return err
+		}
+		if version, err = headerVersion(header); err != nil {
+			return err
+		}
+		value, err = decodeValue(resp.GetEncoding(), resp.GetValue(), c.streamLimit())
+		return err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get versioned value from kamacache: %w", fromStatusError(err))
+	}
+
+	return value, version, nil
+}
+
+// SetIf 在所有者节点上按条件写入，返回新值的版本号
+func (c *Client) SetIf(ctx context.Context, group, key string, value []byte, ttl time.Duration, cond WriteCondition, version uint64) (uint64, error) {
+	var newVersion uint64
+	err := c.invoke(ctx, func(ctx context.Context) error {
+		var header metadata.MD
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			conditionMetadataKey, strconv.Itoa(int(cond)),
+			versionMetadataKey, strconv.FormatUint(version, 10),
+		)
+		_, err := c.grpcCli.Set(ctx, &pb.Request{
+			Group: group,
+			Key:   key,
+			Value: value,
+			TtlMs: ttl.Milliseconds(),
+		}, grpc.Header(&header))
+		if err != nil {
+			return err
+		}
+		newVersion, err = headerVersion(header)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to conditionally set value to kamacache: %w", fromStatusError(err))
+	}
+
+	return newVersion, nil
+}
+
 func (c *Client) Close() error {
 	if c.conn != nil {
 		return c.conn.Close()
@@ -0,0 +1,226 @@
+package kamacache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/SuperJinggg/mycache-go/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeCacheClient 依次返回预设错误的 gRPC 客户端，错误用完后返回 "v"，记录每次调用的上下文
+type fakeCacheClient struct {
+	pb.MyCacheClient
+	mu   sync.Mutex
+	errs []error
+	ctxs []context.Context
+}
+
+func (f *fakeCacheClient) Get(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.ResponseForGet, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ctxs = append(f.ctxs, ctx)
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &pb.ResponseForGet{Value: []byte("v")}, nil
+}
+
+// calls 返回调用次数
+func (f *fakeCacheClient) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.ctxs)
+}
+
+// newFakeClient 创建使用 fakeCacheClient 的客户端，不建立连接
+func newFakeClient(fake *fakeCacheClient, opts ...ClientOption) *Client {
+	c := &Client{
+		addr:        "fake",
+		grpcCli:     fake,
+		callTimeout: defaultCallTimeout,
+		latency:     newLatencyWindow(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// 测试 Get 只重试节点不可用类错误，且尝试次数不超过重试策略
+func TestClientGetRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	retry := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		retry     RetryPolicy
+		errs      []error
+		wantCode  codes.Code
+		wantCalls int
+	}{
+		{"不可用时重试后成功", retry, []error{unavailable, unavailable}, codes.OK, 3},
+		{"超过最大尝试次数", retry, []error{unavailable, unavailable, unavailable, unavailable}, codes.Unavailable, 3},
+		{"超时也重试", retry, []error{status.Error(codes.DeadlineExceeded, "timeout")}, codes.OK, 2},
+		{"业务错误不重试", retry, []error{toStatusError(ErrNotFound)}, codes.NotFound, 1},
+		{"未配置重试时只尝试一次", RetryPolicy{}, []error{unavailable}, codes.Unavailable, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeCacheClient{errs: tt.errs}
+			c := newFakeClient(fake, WithRetryPolicy(tt.retry))
+
+			value, err := c.Get(context.Background(), "g", "k")
+			if status.Code(err) != tt.wantCode || (err == nil && string(value) != "v") {
+				t.Fatalf("状态码应为 %v，实际为 %q, %v", tt.wantCode, value, err)
+			}
+			if got := fake.calls(); got != tt.wantCalls {
+				t.Fatalf("应调用 %d 次，实际为 %d 次", tt.wantCalls, got)
+			}
+		})
+	}
+
+	t.Run("调用方取消时停止退避", func(t *testing.T) {
+		fake := &fakeCacheClient{errs: []error{unavailable, unavailable}}
+		c := newFakeClient(fake, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := c.Get(ctx, "g", "k"); err == nil {
+			t.Fatal("调用方超时后应返回错误")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second || fake.calls() != 1 {
+			t.Fatalf("应在调用方超时后立即返回，耗时 %v，调用 %d 次", elapsed, fake.calls())
+		}
+	})
+}
+
+// 测试单次RPC的超时与调用方的截止时间、元数据一起传递给 gRPC
+func TestClientCallContext(t *testing.T) {
+	tests := []struct {
+		name         string
+		callTimeout  time.Duration
+		callerWait   time.Duration // 调用方的超时，0表示没有截止时间
+		wantDeadline time.Duration // 期望的截止时间，0表示没有截止时间
+	}{
+		{"使用单次RPC超时", 50 * time.Millisecond, 0, 50 * time.Millisecond},
+		{"调用方的截止时间更早", time.Second, 50 * time.Millisecond, 50 * time.Millisecond},
+		{"单次RPC超时更早", 50 * time.Millisecond, time.Second, 50 * time.Millisecond},
+		{"不限制单次RPC时只使用调用方的截止时间", 0, 50 * time.Millisecond, 50 * time.Millisecond},
+		{"都不设置时没有截止时间", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeCacheClient{}
+			c := newFakeClient(fake, WithCallTimeout(tt.callTimeout))
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+			if tt.callerWait > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.callerWait)
+				defer cancel()
+			}
+
+			start := time.Now()
+			if _, err := c.Get(ctx, "g", "k"); err != nil {
+				t.Fatalf("读取失败: %v", err)
+			}
+
+			got := fake.ctxs[0]
+			deadline, ok := got.Deadline()
+			if ok != (tt.wantDeadline > 0) {
+				t.Fatalf("是否有截止时间应为 %v，实际为 %v", tt.wantDeadline > 0, ok)
+			}
+			if ok {
+				if d := deadline.Sub(start); d > tt.wantDeadline+20*time.Millisecond || d < tt.wantDeadline-20*time.Millisecond {
+					t.Fatalf("截止时间应约为 %v 之后，实际为 %v", tt.wantDeadline, d)
+				}
+			}
+			if md, _ := metadata.FromOutgoingContext(got); len(md.Get("x-request-id")) == 0 {
+				t.Fatal("调用方的元数据应传递给 gRPC")
+			}
+		})
+	}
+}
+
+// 测试调用方取消的请求不计入熔断器统计，半开状态的试探请求被取消时不会关闭熔断器
+func TestClientInvokeCallerCanceled(t *testing.T) {
+	fake := &fakeCacheClient{errs: []error{
+		status.Error(codes.Unavailable, "connection refused"),
+		status.Error(codes.Canceled, "context canceled"),
+	}}
+	c := newFakeClient(fake, WithCircuitBreaker(BreakerOptions{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond}))
+
+	if _, err := c.Get(context.Background(), "g", "k"); err == nil {
+		t.Fatal("节点不可用时应返回错误")
+	}
+	if !c.breaker.isOpen() {
+		t.Fatal("失败后熔断器应打开")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(ctx, "g", "k"); err == nil {
+		t.Fatal("调用方取消后应返回错误")
+	}
+	if !c.breaker.isOpen() {
+		t.Fatal("被取消的试探请求不应关闭熔断器")
+	}
+	if !c.breaker.allow() {
+		t.Fatal("被取消的试探请求应归还试探名额")
+	}
+}
+
+// ctxPeer 记录 Get 收到的上下文
+type ctxPeer struct {
+	*fakePeer
+	got chan context.Context
+}
+
+func (p *ctxPeer) Get(ctx context.Context, group, key string) ([]byte, error) {
+	p.got <- ctx
+	return []byte("v"), nil
+}
+
+// onePeerPicker 将所有 key 分配给同一个节点
+type onePeerPicker struct {
+	peer Peer
+}
+
+func (p *onePeerPicker) PickPeer(key string) (Peer, bool, bool) { return p.peer, true, false }
+
+func (p *onePeerPicker) Close() error { return nil }
+
+// 测试 Group 将调用方上下文中的值和加载超时传递给对等节点
+func TestGroupPeerContext(t *testing.T) {
+	type ctxKey struct{}
+	peer := &ctxPeer{fakePeer: newFakePeer(), got: make(chan context.Context, 1)}
+	g := NewGroup("peer-context-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}), WithPeers(&onePeerPicker{peer: peer}), WithLoaderTimeout(time.Second))
+	t.Cleanup(func() { g.Close() })
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+	if _, err := g.Get(ctx, "k"); err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+
+	got := <-peer.got
+	if got.Value(ctxKey{}) != "req-1" {
+		t.Fatal("调用方上下文中的值应传递给对等节点")
+	}
+	if _, ok := got.Deadline(); !ok {
+		t.Fatal("对等节点的请求应受加载超时限制")
+	}
+}
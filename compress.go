@@ -0,0 +1,249 @@
+package kamacache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor 值的压缩算法，Name 用于节点间协商，自定义算法需要在所有节点上通过 RegisterCompressor 注册
+type Compressor interface {
+	Name() string
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = make(map[string]Compressor)
+)
+
+func init() {
+	RegisterCompressor(SnappyCompressor())
+	RegisterCompressor(ZstdCompressor())
+	RegisterCompressor(GzipCompressor(gzip.DefaultCompression))
+}
+
+// RegisterCompressor 注册压缩算法，同名的算法会被替换
+func RegisterCompressor(c Compressor) {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+	compressors[c.Name()] = c
+}
+
+// getCompressor 按名称获取已注册的压缩算法
+func getCompressor(name string) (Compressor, bool) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	c, ok := compressors[name]
+	return c, ok
+}
+
+// compressorNames 返回已注册的压缩算法名称，请求时告知对端本节点能解压的算法
+func compressorNames() []string {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	names := make([]string, 0, len(compressors))
+	for name := range compressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// limitedDecompressor 内置压缩算法实现的可选接口，解压后的长度超过 limit 时返回 ErrValueTooLarge，
+// 不会为对端声明或构造的超大数据分配内存
+type limitedDecompressor interface {
+	decompressLimit(src []byte, limit int64) ([]byte, error)
+}
+
+// decodeValue 按对端返回的压缩算法解压值，解压后的长度不能超过 limit。
+// 自定义算法没有实现 limitedDecompressor 时只能在解压后检查长度
+func decodeValue(encoding string, b []byte, limit int64) ([]byte, error) {
+	if encoding == "" {
+		return b, nil
+	}
+	c, ok := getCompressor(encoding)
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+	if lc, ok := c.(limitedDecompressor); ok {
+		return lc.decompressLimit(b, limit)
+	}
+	out, err := c.Decompress(b)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, tooLarge(int64(len(out)), limit)
+	}
+	return out, nil
+}
+
+// tooLarge 返回解压后的值超过上限的错误
+func tooLarge(n, limit int64) error {
+	return fmt.Errorf("%w: decompressed %d bytes exceeds %d", ErrValueTooLarge, n, limit)
+}
+
+// encode 返回发送给对端的数据，对端支持视图的压缩算法时直接发送压缩后的数据，否则发送解压后的数据
+func (b ByteView) encode(accept []string) ([]byte, string, error) {
+	if b.codec == nil {
+		return b.b, "", nil
+	}
+	if slices.Contains(accept, b.codec.Name()) {
+		return b.b, b.codec.Name(), nil
+	}
+	out, err := b.decompress()
+	return out, "", err
+}
+
+// WithCompression 开启值压缩，不小于 minSize 的值压缩后再写入本地缓存，Get 返回前解压一次，
+// 缓存容量按压缩后的长度计算。压缩后没有变小的值保持原样
+func WithCompression(c Compressor, minSize int) GroupOption {
+	return func(g *Group) {
+		g.compressor = c
+		g.compressMinSize = minSize
+	}
+}
+
+// snappyCompressor snappy 压缩，速度快，适合对延迟敏感的场景
+type snappyCompressor struct{}
+
+// SnappyCompressor 返回 snappy 压缩算法
+func SnappyCompressor() Compressor {
+	return snappyCompressor{}
+}
+
+func (snappyCompressor) Name() string { return "snappy" }
+
+func (snappyCompressor) Compress(src []byte) ([]byte, error) {
+	return snappy.Encode(nil, src), nil
+}
+
+func (snappyCompressor) Decompress(src []byte) ([]byte, error) {
+	return snappy.Decode(nil, src)
+}
+
+// decompressLimit snappy 在数据头部记录解压后的长度，解压前即可检查
+func (snappyCompressor) decompressLimit(src []byte, limit int64) ([]byte, error) {
+	n, err := snappy.DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	if int64(n) > limit {
+		return nil, tooLarge(int64(n), limit)
+	}
+	return snappy.Decode(nil, src)
+}
+
+// zstdCompressor zstd 压缩，压缩率高，编码器和解码器可以并发使用
+type zstdCompressor struct {
+	enc     *zstd.Encoder
+	dec     *zstd.Decoder
+	limited sync.Map // 解压上限 -> 限制了输出长度的 *zstd.Decoder，上限只有少数几种取值
+}
+
+var (
+	zstdOnce     sync.Once
+	zstdInstance *zstdCompressor
+)
+
+// ZstdCompressor 返回 zstd 压缩算法
+func ZstdCompressor() Compressor {
+	zstdOnce.Do(func() {
+		enc, _ := zstd.NewWriter(nil)
+		dec, _ := zstd.NewReader(nil)
+		zstdInstance = &zstdCompressor{enc: enc, dec: dec}
+	})
+	return zstdInstance
+}
+
+func (*zstdCompressor) Name() string { return "zstd" }
+
+func (z *zstdCompressor) Compress(src []byte) ([]byte, error) {
+	return z.enc.EncodeAll(src, nil), nil
+}
+
+func (z *zstdCompressor) Decompress(src []byte) ([]byte, error) {
+	return z.dec.DecodeAll(src, nil)
+}
+
+// decompressLimit 使用 WithDecoderMaxMemory 限制输出长度的解码器解压
+func (z *zstdCompressor) decompressLimit(src []byte, limit int64) ([]byte, error) {
+	dec, ok := z.limited.Load(limit)
+	if !ok {
+		d, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(max(limit, 1))))
+		if err != nil {
+			return nil, err
+		}
+		if dec, ok = z.limited.LoadOrStore(limit, d); ok {
+			d.Close()
+		}
+	}
+	out, err := dec.(*zstd.Decoder).DecodeAll(src, nil)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		return nil, fmt.Errorf("%w: decompressed value exceeds %d bytes", ErrValueTooLarge, limit)
+	}
+	return out, err
+}
+
+// gzipCompressor gzip 压缩，兼容性最好
+type gzipCompressor struct {
+	level int
+}
+
+// GzipCompressor 返回指定压缩级别的 gzip 压缩算法
+func GzipCompressor(level int) Compressor {
+	return gzipCompressor{level: level}
+}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (c gzipCompressor) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// decompressLimit 最多读取 limit+1 字节，多读出的一个字节说明超过上限
+func (gzipCompressor) decompressLimit(src []byte, limit int64) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("%w: decompressed value exceeds %d bytes", ErrValueTooLarge, limit)
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package kamacache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 测试内置压缩算法的压缩与解压
+func TestCompressors(t *testing.T) {
+	src := []byte(strings.Repeat(`{"id":42,"name":"mycache","tags":["a","b"]}`, 100))
+	for _, name := range []string{"snappy", "zstd", "gzip"} {
+		t.Run(name, func(t *testing.T) {
+			c, ok := getCompressor(name)
+			if !ok {
+				t.Fatalf("%s 应已注册", name)
+			}
+			compressed, err := c.Compress(src)
+			if err != nil {
+				t.Fatalf("压缩失败: %v", err)
+			}
+			if len(compressed) >= len(src) {
+				t.Fatalf("压缩后长度 %d 应小于 %d", len(compressed), len(src))
+			}
+			out, err := decodeValue(name, compressed, int64(len(src)))
+			if err != nil || !bytes.Equal(out, src) {
+				t.Fatalf("解压结果与原值不一致: %v", err)
+			}
+			if _, err := decodeValue(name, compressed, int64(len(src)-1)); !errors.Is(err, ErrValueTooLarge) {
+				t.Fatalf("解压后超过上限应返回 ErrValueTooLarge，实际为 %v", err)
+			}
+		})
+	}
+}
+
+// 测试组内的透明压缩与节点间的协商
+func TestGroupCompression(t *testing.T) {
+	value := []byte(strings.Repeat("hello mycache ", 200))
+	g := NewGroup("compress-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return value, nil
+	}), WithCompression(ZstdCompressor(), 64))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	if _, err := g.Get(ctx, "k"); err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+	view, err := g.get(ctx, "k")
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+
+	t.Run("缓存中保存压缩后的值", func(t *testing.T) {
+		if view.Len() != len(value) || view.Size() >= len(value) {
+			t.Fatalf("Len 应为原长度 %d，Size 应更小，实际为 %d/%d", len(value), view.Len(), view.Size())
+		}
+		got, err := g.Get(ctx, "k")
+		if err != nil || got.codec != nil || !bytes.Equal(got.ByteSLice(), value) || got.String() != string(value) {
+			t.Fatalf("Get 应返回解压后的值: %v", err)
+		}
+		if ratio, ok := g.Stats()["compression_ratio"].(float64); !ok || ratio <= 1 {
+			t.Fatalf("压缩率应大于1，实际为 %v", g.Stats()["compression_ratio"])
+		}
+	})
+
+	t.Run("按对端支持的算法发送", func(t *testing.T) {
+		b, encoding, err := view.encode([]string{"gzip", "zstd"})
+		if err != nil || encoding != "zstd" || len(b) != view.Size() {
+			t.Fatalf("对端支持 zstd 时应发送压缩后的值，实际编码为 %q: %v", encoding, err)
+		}
+		b, encoding, err = view.encode(nil)
+		if err != nil || encoding != "" || !bytes.Equal(b, value) {
+			t.Fatalf("对端不支持时应发送解压后的值: %v", err)
+		}
+	})
+
+	t.Run("小于阈值的值不压缩", func(t *testing.T) {
+		if err := g.Set(ctx, "small", []byte("tiny")); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		small, _ := g.Get(ctx, "small")
+		if small.codec != nil || small.String() != "tiny" {
+			t.Fatal("小于阈值的值不应压缩")
+		}
+	})
+}
+
+// 测试本地缓存中的值损坏时 Get 重新加载而不是返回空值
+func TestGroupCorruptValue(t *testing.T) {
+	value := []byte(strings.Repeat("hello mycache ", 200))
+	var loads int
+	g := NewGroup("corrupt-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		loads++
+		return value, nil
+	}), WithCompression(ZstdCompressor(), 64))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	corrupt := ByteView{b: []byte("not zstd"), codec: ZstdCompressor(), size: len(value)}
+
+	t.Run("Get 删除损坏的值并重新加载", func(t *testing.T) {
+		g.mainCache.Add("k", corrupt)
+		view, err := g.Get(ctx, "k")
+		if err != nil {
+			t.Fatalf("应重新加载成功: %v", err)
+		}
+		if view.Len() != len(value) || !bytes.Equal(view.ByteSLice(), value) || loads != 1 {
+			t.Fatalf("应返回重新加载的值，长度 %d，加载次数 %d", view.Len(), loads)
+		}
+	})
+
+	t.Run("发送给对端时返回错误", func(t *testing.T) {
+		if _, _, err := corrupt.encode(nil); !errors.Is(err, ErrCorruptValue) {
+			t.Fatalf("应返回 ErrCorruptValue，实际为 %v", err)
+		}
+	})
+
+	t.Run("解压后长度不一致视为损坏", func(t *testing.T) {
+		compressed, _ := ZstdCompressor().Compress(value)
+		view := ByteView{b: compressed, codec: ZstdCompressor(), size: len(value) + 1}
+		if _, err := view.decode(); !errors.Is(err, ErrCorruptValue) {
+			t.Fatalf("应返回 ErrCorruptValue，实际为 %v", err)
+		}
+	})
+}
@@ -21,8 +21,8 @@ type Map struct {
 	hashMap map[int]string
 	// 节点到虚拟节点数量的映射
 	nodeReplicas map[string]int
-	// 节点负载统计
-	nodeCounts map[string]int64
+	// 节点负载统计，计数器在持有读锁时原子更新
+	nodeCounts map[string]*int64
 	// 总请求数
 	totalRequests int64
 }
@@ -33,7 +33,7 @@ func New(opts ...Option) *Map {
 		config:       DefaultConfig,
 		hashMap:      make(map[int]string),
 		nodeReplicas: make(map[string]int),
-		nodeCounts:   make(map[string]int64),
+		nodeCounts:   make(map[string]*int64),
 	}
 
 	for _, opt := range opts {
@@ -86,12 +86,16 @@ func (m *Map) Remove(node string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.removeNode(node)
+}
+
+// removeNode 移除节点的所有虚拟节点，调用方需持有写锁
+func (m *Map) removeNode(node string) error {
 	replicas := m.nodeReplicas[node]
 	if replicas == 0 {
 		return fmt.Errorf("node %s not found", node)
 	}
 
-	// 移除节点的所有虚拟节点
 	for i := 0; i < replicas; i++ {
 		hash := int(m.config.HashFunc([]byte(fmt.Sprintf("%s-%d", node, i))))
 		delete(m.hashMap, hash)
@@ -133,13 +137,46 @@ func (m *Map) Get(key string) string {
 	}
 
 	node := m.hashMap[m.keys[idx]]
-	count := m.nodeCounts[node]
-	m.nodeCounts[node] = count + 1
+	atomic.AddInt64(m.nodeCounts[node], 1)
 	atomic.AddInt64(&m.totalRequests, 1)
 
 	return node
 }
 
+// GetN 按顺时针方向返回 key 对应的最多 n 个不同节点，第一个即 Get 返回的节点，不计入负载统计
+func (m *Map) GetN(key string, n int) []string {
+	if key == "" || n <= 0 {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.keys) == 0 {
+		return nil
+	}
+	if n > len(m.nodeReplicas) {
+		n = len(m.nodeReplicas)
+	}
+
+	hash := int(m.config.HashFunc([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if _, ok := seen[node]; ok {
+			continue
+		}
+		seen[node] = struct{}{}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
 // addNode 添加节点的虚拟节点
 func (m *Map) addNode(node string, replicas int) {
 	for i := 0; i < replicas; i++ {
@@ -148,6 +185,9 @@ func (m *Map) addNode(node string, replicas int) {
 		m.hashMap[hash] = node
 	}
 	m.nodeReplicas[node] = replicas
+	if _, ok := m.nodeCounts[node]; !ok {
+		m.nodeCounts[node] = new(int64)
+	}
 }
 
 // checkAndRebalance 检查并重新平衡虚拟节点
@@ -157,15 +197,17 @@ func (m *Map) checkAndRebalance() {
 	}
 
 	// 计算负载情况
-	avgLoad := float64(m.totalRequests) / float64(len(m.nodeReplicas))
+	m.mu.RLock()
+	avgLoad := float64(atomic.LoadInt64(&m.totalRequests)) / float64(len(m.nodeReplicas))
 	var maxDiff float64
 
 	for _, count := range m.nodeCounts {
-		diff := math.Abs(float64(count) - avgLoad)
+		diff := math.Abs(float64(atomic.LoadInt64(count)) - avgLoad)
 		if diff/avgLoad > maxDiff {
 			maxDiff = diff / avgLoad
 		}
 	}
+	m.mu.RUnlock()
 
 	// 如果负载不均衡度超过阈值，调整虚拟节点
 	if maxDiff > m.config.LoadBalanceThreshold {
@@ -178,10 +220,16 @@ func (m *Map) rebalanceNodes() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	avgLoad := float64(m.totalRequests) / float64(len(m.nodeReplicas))
+	avgLoad := float64(atomic.LoadInt64(&m.totalRequests)) / float64(len(m.nodeReplicas))
 
-	// 调整每个节点的虚拟节点数量
+	// 先记录各节点的负载，调整时会重新创建节点的计数器
+	loads := make(map[string]int64, len(m.nodeCounts))
 	for node, count := range m.nodeCounts {
+		loads[node] = atomic.LoadInt64(count)
+	}
+
+	// 调整每个节点的虚拟节点数量
+	for node, count := range loads {
 		currentReplicas := m.nodeReplicas[node]
 		loadRatio := float64(count) / avgLoad
 
@@ -204,7 +252,7 @@ func (m *Map) rebalanceNodes() {
 
 		if newReplicas != currentReplicas {
 			// 重新添加节点的虚拟节点
-			if err := m.Remove(node); err != nil {
+			if err := m.removeNode(node); err != nil {
 				continue // 如果移除失败，跳过这个节点
 			}
 			m.addNode(node, newReplicas)
@@ -212,8 +260,8 @@ func (m *Map) rebalanceNodes() {
 	}
 
 	// 重置计数器
-	for node := range m.nodeCounts {
-		m.nodeCounts[node] = 0
+	for _, count := range m.nodeCounts {
+		atomic.StoreInt64(count, 0)
 	}
 	atomic.StoreInt64(&m.totalRequests, 0)
 
@@ -233,7 +281,7 @@ func (m *Map) GetStats() map[string]float64 {
 	}
 
 	for node, count := range m.nodeCounts {
-		stats[node] = float64(count) / float64(total)
+		stats[node] = float64(atomic.LoadInt64(count)) / float64(total)
 	}
 	return stats
 }
@@ -0,0 +1,352 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrNotInteger 计数器的值不是整数或超出范围
+var ErrNotInteger = errors.New("value is not an integer or out of range")
+
+// WithIncrBatching 开启计数器本地预聚合，每隔 interval 将累积的增量批量发送给 key 的所有者节点
+// 开启后非所有者节点上的 Incr 返回的是估算值（最近一次所有者返回的值加上本地未发送的增量）
+func WithIncrBatching(interval time.Duration) GroupOption {
+	return func(g *Group) {
+		g.incrBatchInterval = interval
+	}
+}
+
+// Incr 对 key 对应的计数器原子地加上 delta 并返回新值
+// 计数器以十进制字符串形式存储，不存在的 key 视为 0。过期时间只在创建计数器时设置，ttl 大于 0 时使用 ttl，
+// 否则使用组的默认过期时间；之后的 Incr 保留原有的过期时间，不会延长计数器的有效期
+func (g *Group) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return g.incr(ctx, key, delta, ttl, math.MinInt64, false)
+}
+
+// IncrWithFloor 与 Incr 相同，但结果小于 floor 时保存为 floor，例如 memcached 的 decr 不会使计数器小于0。
+// 检查和写入在所有者节点上一次完成，不经过预聚合
+func (g *Group) IncrWithFloor(ctx context.Context, key string, delta int64, ttl time.Duration, floor int64) (int64, error) {
+	return g.incr(ctx, key, delta, ttl, floor, false)
+}
+
+// IncrIfExists 与 IncrWithFloor 相同，但 key 不存在时返回 ErrNotFound 而不创建计数器，
+// 例如 memcached 的 incr 和 decr。存在性检查和写入在所有者节点上一次完成，不会与 Delete 交错
+func (g *Group) IncrIfExists(ctx context.Context, key string, delta int64, floor int64) (int64, error) {
+	return g.incr(ctx, key, delta, 0, floor, true)
+}
+
+const (
+	// incrFloorMetadataKey 转发给所有者的 Incr 请求携带的下限
+	incrFloorMetadataKey = "kamacache-incr-floor"
+	// incrExistsMetadataKey 转发给所有者的 Incr 请求要求 key 已存在
+	incrExistsMetadataKey = "kamacache-incr-exists"
+)
+
+// errIncrConflict 读取计数器之后 key 被其他写入修改，需要重新读取
+var errIncrConflict = errors.New("counter modified concurrently")
+
+// requestedFloor 解析请求中的下限元数据，请求没有携带时返回 math.MinInt64
+func requestedFloor(ctx context.Context) (int64, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return math.MinInt64, nil
+	}
+	values := md.Get(incrFloorMetadataKey)
+	if len(values) == 0 {
+		return math.MinInt64, nil
+	}
+	return strconv.ParseInt(values[0], 10, 64)
+}
+
+// requestedExists 返回请求是否要求 key 已存在
+func requestedExists(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(incrExistsMetadataKey)) > 0
+}
+
+// incr 执行 Incr，floor 为 math.MinInt64 表示没有下限，mustExist 为 true 时不创建计数器
+func (g *Group) incr(ctx context.Context, key string, delta int64, ttl time.Duration, floor int64, mustExist bool) (int64, error) {
+	// 检查组是否已关闭
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return 0, ErrGroupClosed
+	}
+
+	if key == "" {
+		return 0, ErrKeyRequired
+	}
+
+	// 检查是否是从其他节点转发过来的请求
+	isPeerRequest := ctx.Value("from_peer") != nil
+
+	// 非所有者节点将请求转发给所有者，保证同一个 key 只在一个节点上累加
+	if !isPeerRequest && g.peers != nil {
+		peer, err := g.pickOwner(key)
+		if err != nil {
+			return 0, err
+		}
+		if peer != nil {
+			// 本地副本不再可信，直接删除
+			g.mainCache.Delete(key)
+
+			if mustExist {
+				ctx = metadata.AppendToOutgoingContext(ctx, incrExistsMetadataKey, "1")
+			}
+			if floor != math.MinInt64 {
+				ctx = metadata.AppendToOutgoingContext(ctx, incrFloorMetadataKey, strconv.FormatInt(floor, 10))
+			}
+			if floor != math.MinInt64 || mustExist {
+				return peer.Incr(ctx, g.name, key, delta, ttl)
+			}
+			if g.incrBatcher != nil {
+				return g.incrBatcher.add(key, delta, ttl), nil
+			}
+			return peer.Incr(ctx, g.name, key, delta, ttl)
+		}
+	}
+
+	// 本地没有缓存时先从数据源加载，计数器可能只存在于数据源中
+	if mustExist {
+		if _, _, ok := g.mainCache.peek(key); !ok {
+			if _, err := g.Get(ctx, key); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return g.incrLocal(ctx, key, delta, ttl, floor, mustExist)
+}
+
+// incrLocal 在本地缓存上执行读-改-写，结果小于 floor 时保存为 floor，mustExist 为 true 时 key 不存在返回 ErrNotFound。
+// 读取之后 key 被 Set 或 Delete 修改时重新读取，避免覆盖其他写入或重新创建已删除的 key
+func (g *Group) incrLocal(ctx context.Context, key string, delta int64, ttl time.Duration, floor int64, mustExist bool) (int64, error) {
+	g.incrMu.Lock()
+	defer g.incrMu.Unlock()
+
+	for {
+		next, err := g.incrOnce(key, delta, ttl, floor, mustExist)
+		if err != errIncrConflict {
+			return next, err
+		}
+	}
+}
+
+// incrOnce 读取计数器并在其未被修改时写入新值，被修改时返回 errIncrConflict
+func (g *Group) incrOnce(key string, delta int64, ttl time.Duration, floor int64, mustExist bool) (int64, error) {
+	var current int64
+	view, expireAt, exists := g.mainCache.peek(key)
+	if !exists && mustExist {
+		return 0, ErrNotFound
+	}
+	if exists {
+		decoded, err := view.decode()
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.ParseInt(string(decoded.b), 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+		current = n
+	}
+
+	// 检查溢出，有下限时向下溢出的结果即为下限
+	var next int64
+	switch {
+	case delta > 0 && current > math.MaxInt64-delta:
+		return 0, ErrNotInteger
+	case delta < 0 && current < math.MinInt64-delta:
+		if floor == math.MinInt64 {
+			return 0, ErrNotInteger
+		}
+		next = floor
+	default:
+		next = max(current+delta, floor)
+	}
+
+	// 已有的计数器保留原有的过期时间
+	if !exists {
+		if ttl <= 0 {
+			ttl = g.expiration
+		}
+		if ttl > 0 {
+			expireAt = time.Now().Add(ttl)
+		}
+	}
+
+	version := view.version
+	value := ByteView{b: []byte(strconv.FormatInt(next, 10))}
+	_, err := g.mainCache.addIf(key, value, expireAt, func(current ByteView, ok bool) error {
+		if ok != exists || current.version != version {
+			return errIncrConflict
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	g.dropStale(key)
+
+	return next, nil
+}
+
+// incrEstimateIdle 所有者返回的值超过该时长没有被使用时不再保留
+const incrEstimateIdle = 10 * time.Minute
+
+// incrBatcher 在本地累积发往其他节点的增量，定期批量发送
+type incrBatcher struct {
+	g         *Group
+	interval  time.Duration
+	mu        sync.Mutex
+	pending   map[string]*pendingIncr  // 尚未发送的增量
+	last      map[string]*incrEstimate // 所有者最近一次返回的值
+	nextPrune time.Time                // 下次清理 last 的时间
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+// incrEstimate 所有者最近一次返回的值
+type incrEstimate struct {
+	value   int64
+	touched time.Time // 最近一次更新或被 add 使用的时间
+}
+
+// pendingIncr 表示一个 key 上累积的增量
+type pendingIncr struct {
+	delta int64
+	ttl   time.Duration
+}
+
+// newIncrBatcher 创建并启动预聚合器
+func newIncrBatcher(g *Group, interval time.Duration) *incrBatcher {
+	b := &incrBatcher{
+		g:        g,
+		interval: interval,
+		pending:  make(map[string]*pendingIncr),
+		last:     make(map[string]*incrEstimate),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// add 累积增量并返回估算值
+func (b *incrBatcher) add(key string, delta int64, ttl time.Duration) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p, ok := b.pending[key]
+	if !ok {
+		p = &pendingIncr{}
+		b.pending[key] = p
+	}
+	p.delta += delta
+	if ttl > 0 {
+		p.ttl = ttl
+	}
+
+	if e, ok := b.last[key]; ok {
+		e.touched = time.Now()
+		return e.value + p.delta
+	}
+	return p.delta
+}
+
+// loop 定期发送累积的增量
+func (b *incrBatcher) loop() {
+	defer close(b.doneCh)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stopCh:
+			b.flush()
+			return
+		}
+	}
+}
+
+// flush 将累积的增量发送给所有者节点
+func (b *incrBatcher) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string]*pendingIncr)
+	b.mu.Unlock()
+
+	for key, p := range pending {
+		if p.delta == 0 {
+			continue
+		}
+
+		value, err := b.send(key, p)
+		if err != nil {
+			logrus.Errorf("[KamaCache] failed to flush incr for key %s: %v", key, err)
+			// 发送失败时将增量放回，下次重试
+			b.mu.Lock()
+			if cur, ok := b.pending[key]; ok {
+				cur.delta += p.delta
+			} else {
+				b.pending[key] = p
+			}
+			b.mu.Unlock()
+			continue
+		}
+
+		b.mu.Lock()
+		b.last[key] = &incrEstimate{value: value, touched: time.Now()}
+		b.mu.Unlock()
+	}
+
+	b.prune()
+}
+
+// prune 删除长时间没有使用的估算值，避免 last 随着 key 的数量无限增长
+func (b *incrBatcher) prune() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(b.nextPrune) {
+		return
+	}
+	b.nextPrune = now.Add(incrEstimateIdle / 10)
+
+	for key, e := range b.last {
+		if _, ok := b.pending[key]; !ok && now.Sub(e.touched) > incrEstimateIdle {
+			delete(b.last, key)
+		}
+	}
+}
+
+// send 发送单个 key 的增量，所有者在此期间可能已变为本节点
+func (b *incrBatcher) send(key string, p *pendingIncr) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	peer, err := b.g.pickOwner(key)
+	if err != nil {
+		return 0, err
+	}
+	if peer == nil {
+		return b.g.incrLocal(ctx, key, p.delta, p.ttl, math.MinInt64, false)
+	}
+	return peer.Incr(ctx, b.g.name, key, p.delta, p.ttl)
+}
+
+// close 停止预聚合器并发送剩余的增量
+func (b *incrBatcher) close() {
+	close(b.stopCh)
+	<-b.doneCh
+}
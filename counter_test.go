@@ -0,0 +1,114 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// 测试计数器只在创建时设置过期时间
+func TestIncrTTL(t *testing.T) {
+	g := NewGroup("incr-ttl-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		firstTTL  time.Duration
+		secondTTL time.Duration
+		wantTTL   time.Duration // 第二次 Incr 后剩余时间的上限，0表示永不过期
+	}{
+		{"后续调用不延长过期时间", 200 * time.Millisecond, time.Hour, 200 * time.Millisecond},
+		{"后续调用不设置过期时间", 0, time.Hour, 0},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := string(rune('a' + i))
+			if n, err := g.Incr(ctx, key, 1, tt.firstTTL); err != nil || n != 1 {
+				t.Fatalf("创建计数器失败: %d, %v", n, err)
+			}
+			if n, err := g.Incr(ctx, key, 2, tt.secondTTL); err != nil || n != 3 {
+				t.Fatalf("计数器的值应为3，实际为 %d: %v", n, err)
+			}
+
+			ttl, ok := g.mainCache.TTL(key)
+			if !ok {
+				t.Fatal("计数器应存在")
+			}
+			if tt.wantTTL == 0 && ttl != 0 {
+				t.Fatalf("计数器不应过期，剩余 %v", ttl)
+			}
+			if tt.wantTTL > 0 && (ttl == 0 || ttl > tt.wantTTL) {
+				t.Fatalf("剩余时间应不超过 %v，实际为 %v", tt.wantTTL, ttl)
+			}
+		})
+	}
+
+	t.Run("过期后重新创建", func(t *testing.T) {
+		// LRU2 的时钟精度为100ms
+		g.Incr(ctx, "expiring", 5, 50*time.Millisecond)
+		time.Sleep(250 * time.Millisecond)
+		if n, err := g.Incr(ctx, "expiring", 1, 0); err != nil || n != 1 {
+			t.Fatalf("过期的计数器应从0开始，实际为 %d: %v", n, err)
+		}
+	})
+}
+
+// 测试预聚合器清理长时间未使用的估算值
+func TestIncrBatcherPrune(t *testing.T) {
+	b := &incrBatcher{
+		pending: make(map[string]*pendingIncr),
+		last: map[string]*incrEstimate{
+			"idle":    {value: 1, touched: time.Now().Add(-2 * incrEstimateIdle)},
+			"active":  {value: 2, touched: time.Now()},
+			"pending": {value: 3, touched: time.Now().Add(-2 * incrEstimateIdle)},
+		},
+	}
+	b.pending["pending"] = &pendingIncr{delta: 1}
+
+	b.prune()
+
+	if _, ok := b.last["idle"]; ok {
+		t.Error("长时间未使用的估算值应被删除")
+	}
+	if _, ok := b.last["active"]; !ok {
+		t.Error("最近使用的估算值应保留")
+	}
+	if _, ok := b.last["pending"]; !ok {
+		t.Error("仍有未发送增量的估算值应保留")
+	}
+	if got := b.add("active", 5, 0); got != 7 {
+		t.Errorf("估算值应为 7，实际为 %d", got)
+	}
+}
+
+// 测试 IncrIfExists 不创建计数器，也不会重新创建已删除的 key
+func TestIncrIfExists(t *testing.T) {
+	g := NewGroup("incr-exists-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	if _, err := g.IncrIfExists(ctx, "n", 1, math.MinInt64); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("不存在的 key 应返回 ErrNotFound，实际为 %v", err)
+	}
+	if _, ok := g.mainCache.TTL("n"); ok {
+		t.Fatal("不应创建计数器")
+	}
+
+	g.Set(ctx, "n", []byte("10"))
+	if n, err := g.IncrIfExists(ctx, "n", -20, 0); err != nil || n != 0 {
+		t.Fatalf("计数器应截断为0，实际为 %d: %v", n, err)
+	}
+
+	g.Delete(ctx, "n")
+	if _, err := g.IncrIfExists(ctx, "n", 1, math.MinInt64); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("删除后应返回 ErrNotFound，实际为 %v", err)
+	}
+}
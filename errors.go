@@ -0,0 +1,129 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotFound 键不存在，Getter 在数据源中找不到数据时应返回该错误（可包装）
+var ErrNotFound = errors.New("key not found")
+
+// ErrGroupNotFound 组不存在
+var ErrGroupNotFound = errors.New("group not found")
+
+// errorDomain 错误详情中标识本项目错误的域
+const errorDomain = "mycache"
+
+// errorMapping 本地错误与 gRPC 状态码的对应关系，reason 用于在远端还原具体的错误
+var errorMapping = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{ErrNotFound, codes.NotFound, "NOT_FOUND"},
+	{ErrGroupNotFound, codes.FailedPrecondition, "GROUP_NOT_FOUND"},
+	{ErrLeasesDisabled, codes.FailedPrecondition, "LEASES_DISABLED"},
+	{ErrKeyRequired, codes.InvalidArgument, "KEY_REQUIRED"},
+	{ErrValueRequired, codes.InvalidArgument, "VALUE_REQUIRED"},
+	{ErrValueTooLarge, codes.ResourceExhausted, "VALUE_TOO_LARGE"},
+	{ErrNotInteger, codes.InvalidArgument, "NOT_INTEGER"},
+	{ErrCorruptValue, codes.DataLoss, "CORRUPT_VALUE"},
+	{ErrWriteBacklogFull, codes.Unavailable, "WRITE_BACKLOG_FULL"},
+	{ErrLoaderBusy, codes.Unavailable, "LOADER_BUSY"},
+	{ErrRateLimited, codes.Unavailable, "RATE_LIMITED"},
+	{ErrCircuitOpen, codes.Unavailable, "CIRCUIT_OPEN"},
+	{ErrGroupClosed, codes.Unavailable, "GROUP_CLOSED"},
+	{ErrOwnerUnavailable, codes.Unavailable, "OWNER_UNAVAILABLE"},
+	{ErrLeaseInvalid, codes.Aborted, "LEASE_INVALID"},
+	{ErrKeyExists, codes.AlreadyExists, "KEY_EXISTS"},
+	{ErrVersionMismatch, codes.Aborted, "VERSION_MISMATCH"},
+	{ErrUnauthenticated, codes.Unauthenticated, "UNAUTHENTICATED"},
+	{ErrPermissionDenied, codes.PermissionDenied, "PERMISSION_DENIED"},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+	{context.Canceled, codes.Canceled, "CANCELED"},
+}
+
+// toStatusError 将本地错误转换为携带错误详情的 gRPC 状态错误
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, m := range errorMapping {
+		if errors.Is(err, m.err) {
+			st := status.New(m.code, err.Error())
+			if detailed, derr := st.WithDetails(&errdetails.ErrorInfo{Reason: m.reason, Domain: errorDomain}); derr == nil {
+				st = detailed
+			}
+			return st.Err()
+		}
+	}
+
+	// 已经是 gRPC 状态错误（例如转发时从其他节点收到的错误）时保留原状态码
+	if st, ok := status.FromError(err); ok {
+		return st.Err()
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// fromStatusError 将远端返回的 gRPC 状态错误还原为本地错误，
+// 返回的错误同时满足 errors.Is(err, 本地错误) 和 status.Code(err)
+func fromStatusError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+
+	// 优先使用错误详情还原具体错误
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != errorDomain {
+			continue
+		}
+		for _, m := range errorMapping {
+			if m.reason == info.Reason {
+				return &remoteError{sentinel: m.err, status: st}
+			}
+		}
+	}
+
+	// 没有错误详情时（例如对端版本较旧）按状态码还原无歧义的错误
+	switch st.Code() {
+	case codes.NotFound:
+		return &remoteError{sentinel: ErrNotFound, status: st}
+	case codes.DeadlineExceeded:
+		return &remoteError{sentinel: context.DeadlineExceeded, status: st}
+	case codes.Canceled:
+		return &remoteError{sentinel: context.Canceled, status: st}
+	case codes.Unauthenticated:
+		return &remoteError{sentinel: ErrUnauthenticated, status: st}
+	case codes.PermissionDenied:
+		return &remoteError{sentinel: ErrPermissionDenied, status: st}
+	}
+	return err
+}
+
+// remoteError 远端节点返回的错误
+type remoteError struct {
+	sentinel error
+	status   *status.Status
+}
+
+// Error 实现 error 接口
+func (e *remoteError) Error() string {
+	return e.status.Message()
+}
+
+// Unwrap 返回对应的本地错误，使 errors.Is 可用
+func (e *remoteError) Unwrap() error {
+	return e.sentinel
+}
+
+// GRPCStatus 返回原始状态，使 status.Code 可用
+func (e *remoteError) GRPCStatus() *status.Status {
+	return e.status
+}
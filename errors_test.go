@@ -0,0 +1,63 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 测试错误在本地与 gRPC 状态之间的双向转换
+func TestErrorRoundTrip(t *testing.T) {
+	cases := []struct {
+		err  error
+		code codes.Code
+	}{
+		{fmt.Errorf("failed to get data: %w", ErrNotFound), codes.NotFound},
+		{fmt.Errorf("%w: users", ErrGroupNotFound), codes.FailedPrecondition},
+		{ErrKeyRequired, codes.InvalidArgument},
+		{ErrValueRequired, codes.InvalidArgument},
+		{ErrGroupClosed, codes.Unavailable},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+	}
+
+	for _, c := range cases {
+		st := toStatusError(c.err)
+		if got := status.Code(st); got != c.code {
+			t.Fatalf("%v 应转换为 %v，实际为 %v", c.err, c.code, got)
+		}
+
+		remote := fmt.Errorf("failed to get value from kamacache: %w", fromStatusError(st))
+		for _, m := range errorMapping {
+			if errors.Is(c.err, m.err) && !errors.Is(remote, m.err) {
+				t.Fatalf("远端错误 %v 应满足 errors.Is(%v)", remote, m.err)
+			}
+		}
+		if errors.Is(c.err, ErrKeyRequired) && errors.Is(remote, ErrValueRequired) {
+			t.Fatalf("相同状态码的错误不应混淆: %v", remote)
+		}
+		if got := status.Code(remote); got != c.code {
+			t.Fatalf("远端错误应保留状态码 %v，实际为 %v", c.code, got)
+		}
+	}
+}
+
+// 测试没有错误详情时按状态码还原
+func TestFromStatusErrorWithoutDetails(t *testing.T) {
+	err := fromStatusError(status.Error(codes.NotFound, "missing"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("NotFound 应还原为 ErrNotFound，实际为 %v", err)
+	}
+
+	err = fromStatusError(status.Error(codes.Internal, "boom"))
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("未知错误应保留原状态码，实际为 %v", status.Code(err))
+	}
+
+	if fromStatusError(nil) != nil {
+		t.Fatal("nil 错误应保持为 nil")
+	}
+}
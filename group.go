@@ -8,13 +8,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/SuperJinggg/mycache-go/metrics"
 	"github.com/SuperJinggg/mycache-go/singleflight"
+	"github.com/SuperJinggg/mycache-go/store"
 	"github.com/sirupsen/logrus"
-)
-
-var (
-	groupsMu sync.RWMutex
-	groups   = make(map[string]*Group)
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrKeyRequired 键不能为空错误
@@ -23,6 +22,12 @@ var ErrKeyRequired = errors.New("key is required")
 // ErrValueRequired 值不能为空错误
 var ErrValueRequired = errors.New("value is required")
 
+// ErrSetterRequired write-through 和 write-behind 模式没有提供 Setter
+var ErrSetterRequired = errors.New("setter is required for write-through and write-behind")
+
+// ErrValueTooLarge 值超过组的大小限制错误
+var ErrValueTooLarge = errors.New("value too large")
+
 // ErrGroupClosed 组已关闭错误
 var ErrGroupClosed = errors.New("cache group is closed")
 
@@ -41,6 +46,7 @@ func (f GetterFunc) Get(ctx context.Context, key string) ([]byte, error) {
 
 // Group 是一个缓存命名空间
 type Group struct {
+	node       *Node // 所属的节点
 	name       string
 	getter     Getter
 	mainCache  *Cache
@@ -49,8 +55,52 @@ type Group struct {
 	expiration time.Duration // 缓存过期时间，0表示永不过期
 	closed     int32         // 原子变量，标记组是否已关闭
 	stats      groupStats    // 统计信息
+
+	incrMu            sync.Mutex    // 保护本地计数器的读-改-写
+	incrBatchInterval time.Duration // 计数器预聚合间隔，0表示不开启
+	incrBatcher       *incrBatcher  // 计数器预聚合器
+
+	leases    *leaseManager   // 租约管理器，nil表示未开启租约模式
+	ownerLoad OwnerLoadPolicy // 严格所有者加载策略
+
+	maxValueSize int            // 单个值的最大长度，0表示不限制
+	oversize     OversizePolicy // 值超过最大长度时的处理方式
+
+	compressor      Compressor // 压缩算法，nil表示不压缩
+	compressMinSize int        // 开始压缩的最小长度
+
+	writeMode       WriteMode          // 写入模式
+	setter          Setter             // 后端存储的写入接口
+	deleter         Deleter            // 后端存储的删除接口
+	writeBehindOpts WriteBehindOptions // write-behind 模式的配置
+	writeBehind     *writeBehind       // write-behind 队列
+
+	loaderSem       chan struct{}   // 加载并发名额，nil表示不限制
+	loaderQueueWait time.Duration   // 等待加载名额的最长时间
+	loaderTimeout   time.Duration   // 单次加载的超时时间
+	loaderLimiter   *tokenBucket    // 加载限流器
+	loaderBreaker   *circuitBreaker // 数据源熔断器
+	stale           store.Store     // 加载失败时使用的旧值
+	staleFor        time.Duration   // 旧值的保留时长
+
+	memManager *MemoryManager // 内存管理器，nil表示使用固定的 cacheBytes
+	memQuota   MemoryQuota    // 在内存管理器中的配额
+
+	loadLatency *metrics.Histogram // 加载耗时分布
+	tracer      trace.Tracer       // 链路追踪，nil表示不开启
+	traceKeys   bool               // 是否在 span 中记录 key
 }
 
+// OversizePolicy 值超过组的最大长度时的处理方式
+type OversizePolicy int
+
+const (
+	// OversizeReject 拒绝写入，也不缓存加载到的值，Set 和 Get 返回 ErrValueTooLarge
+	OversizeReject OversizePolicy = iota
+	// OversizeLocalOnly 只缓存在本节点，不同步给其他节点，其他节点请求时返回 ErrValueTooLarge 由其自行加载
+	OversizeLocalOnly
+)
+
 // groupStats 保存组的统计信息
 type groupStats struct {
 	loads        int64 // 加载次数
@@ -61,6 +111,12 @@ type groupStats struct {
 	loaderHits   int64 // 从加载器获取成功次数
 	loaderErrors int64 // 从加载器获取失败次数
 	loadDuration int64 // 加载总耗时（纳秒）
+
+	rawBytes        int64 // 尝试压缩的值压缩前的总长度
+	compressedBytes int64 // 尝试压缩的值写入缓存的总长度
+
+	loaderRejected int64 // 被并发限制、限流或熔断拒绝的加载次数
+	staleHits      int64 // 加载失败时返回旧值的次数
 }
 
 // GroupOption 定义Group的配置选项
@@ -87,8 +143,26 @@ func WithCacheOptions(opts CacheOptions) GroupOption {
 	}
 }
 
-// NewGroup 创建一个新的 Group 实例
+// WithMaxValueSize 设置单个值的最大长度及超过时的处理方式
+func WithMaxValueSize(n int, policy OversizePolicy) GroupOption {
+	return func(g *Group) {
+		g.maxValueSize = n
+		g.oversize = policy
+	}
+}
+
+// NewGroup 在默认节点上创建一个新的 Group 实例
 func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
+	return defaultNode.NewGroup(name, cacheBytes, getter, opts...)
+}
+
+// CreateGroup 与 NewGroup 相同，但无法满足配置（例如内存管理器的预算不足）时返回错误而不是 panic
+func CreateGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) (*Group, error) {
+	return defaultNode.CreateGroup(name, cacheBytes, getter, opts...)
+}
+
+// newGroup 创建 Group 实例，由 Node 负责注册。返回错误时不会留下已启动的后台任务
+func newGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) (*Group, error) {
 	if getter == nil {
 		panic("nil Getter")
 	}
@@ -102,6 +176,8 @@ func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption)
 		getter:    getter,
 		mainCache: NewCache(cacheOpts),
 		loader:    &singleflight.Group{},
+
+		loadLatency: metrics.NewHistogram(nil),
 	}
 
 	// 应用选项
@@ -109,29 +185,69 @@ func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption)
 		opt(g)
 	}
 
-	// 注册到全局组映射
-	groupsMu.Lock()
-	defer groupsMu.Unlock()
+	// 返回错误前释放选项创建的缓存
+	release := func() {
+		g.mainCache.Close()
+		if g.stale != nil {
+			g.stale.Close()
+		}
+	}
+
+	if g.writeMode != WriteAround && g.setter == nil {
+		release()
+		return nil, ErrSetterRequired
+	}
+
+	// 缓存项过期或被淘汰时才保存旧值，缓存中的值不重复保存
+	if g.stale != nil {
+		onEviction := g.mainCache.opts.OnEviction
+		g.mainCache.opts.OnEviction = func(e store.Eviction) {
+			g.keepStale(e)
+			if onEviction != nil {
+				onEviction(e)
+			}
+		}
+	}
 
-	if _, exists := groups[name]; exists {
-		logrus.Warnf("Group with name %s already exists, will be replaced", name)
+	// 先向内存管理器注册，失败时还没有启动任何后台任务
+	if g.memManager != nil {
+		if err := g.memManager.Register(name, g.mainCache, g.memQuota); err != nil {
+			release()
+			return nil, err
+		}
 	}
 
-	groups[name] = g
-	logrus.Infof("Created cache group [%s] with cacheBytes=%d, expiration=%v", name, cacheBytes, g.expiration)
+	if g.incrBatchInterval > 0 {
+		g.incrBatcher = newIncrBatcher(g, g.incrBatchInterval)
+	}
+	if g.writeMode == WriteBehind {
+		g.writeBehind = newWriteBehind(name, g.setter, g.deleter, g.writeBehindOpts)
+	}
 
-	return g
+	return g, nil
 }
 
-// GetGroup 获取指定名称的组
+// GetGroup 获取默认节点上指定名称的组
 func GetGroup(name string) *Group {
-	groupsMu.RLock()
-	defer groupsMu.RUnlock()
-	return groups[name]
+	return defaultNode.GetGroup(name)
+}
+
+// Name 返回组名
+func (g *Group) Name() string {
+	return g.name
 }
 
 // Get 从缓存获取数据
 func (g *Group) Get(ctx context.Context, key string) (ByteView, error) {
+	view, err := g.get(ctx, key)
+	if err != nil {
+		return ByteView{}, err
+	}
+	return g.decode(ctx, key, view)
+}
+
+// get 获取缓存值，本地缓存中压缩保存的值原样返回，由调用方决定是否解压
+func (g *Group) get(ctx context.Context, key string) (value ByteView, err error) {
 	// 检查组是否已关闭
 	if atomic.LoadInt32(&g.closed) == 1 {
 		return ByteView{}, ErrGroupClosed
@@ -141,8 +257,14 @@ func (g *Group) Get(ctx context.Context, key string) (ByteView, error) {
 		return ByteView{}, ErrKeyRequired
 	}
 
+	ctx, span := g.startSpan(ctx, "kamacache.Group.Get", g.keyAttributes(key)...)
+	defer func() { endSpan(span, err) }()
+
 	// 从本地缓存获取
 	view, ok := g.mainCache.Get(ctx, key)
+	if span != nil {
+		span.SetAttributes(attribute.Bool("cache.hit", ok))
+	}
 	if ok {
 		atomic.AddInt64(&g.stats.localHits, 1)
 		return view, nil
@@ -154,8 +276,36 @@ func (g *Group) Get(ctx context.Context, key string) (ByteView, error) {
 	return g.load(ctx, key)
 }
 
-// Set 设置缓存值
+// decode 解压 get 返回的视图，只解压一次，之后的读取都不再需要解压。
+// 本地缓存中的值损坏时删除该缓存项并重新加载，而不是返回空值
+func (g *Group) decode(ctx context.Context, key string, view ByteView) (ByteView, error) {
+	decoded, err := view.decode()
+	if err == nil {
+		return decoded, nil
+	}
+
+	g.dropCorrupt(key, err)
+	view, err = g.load(ctx, key)
+	if err != nil {
+		return ByteView{}, err
+	}
+	return view.decode()
+}
+
+// dropCorrupt 删除无法解压的本地缓存项和旧值
+func (g *Group) dropCorrupt(key string, err error) {
+	logrus.Errorf("[KamaCache] dropping corrupt value for key %s in group %s: %v", key, g.name, err)
+	g.mainCache.Delete(key)
+	g.dropStale(key)
+}
+
+// Set 设置缓存值，使用组的过期时间
 func (g *Group) Set(ctx context.Context, key string, value []byte) error {
+	return g.SetWithTTL(ctx, key, value, 0)
+}
+
+// SetWithTTL 设置缓存值并指定过期时间，ttl 不大于0时使用组的过期时间
+func (g *Group) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	// 检查组是否已关闭
 	if atomic.LoadInt32(&g.closed) == 1 {
 		return ErrGroupClosed
@@ -167,23 +317,41 @@ func (g *Group) Set(ctx context.Context, key string, value []byte) error {
 	if len(value) == 0 {
 		return ErrValueRequired
 	}
+	if g.tooLarge(len(value)) && g.oversize == OversizeReject {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrValueTooLarge, len(value), g.maxValueSize)
+	}
 
-	// 检查是否是从其他节点同步过来的请求
-	isPeerRequest := ctx.Value("from_peer") != nil
+	// 需要持久化的客户端写入先同步转发给所有者，由所有者写入后端存储
+	forwarded := false
+	if ctx.Value("from_peer") == nil {
+		var err error
+		if forwarded, err = g.forwardWrite(ctx, key, value, ttl, false); err != nil {
+			return err
+		}
+	}
+	if !forwarded && persistsHere(ctx) {
+		if err := g.persist(ctx, key, value, false); err != nil {
+			return err
+		}
+	}
+
+	// 检查是否是从其他节点同步过来的请求，已转发给所有者和只保存在本地的值也不同步
+	isPeerRequest := ctx.Value("from_peer") != nil || forwarded || g.localOnly(len(value))
 
 	// 创建缓存视图
 	view := ByteView{b: cloneBytes(value)}
 
-	// 设置到本地缓存
-	if g.expiration > 0 {
-		g.mainCache.AddWithExpiration(key, view, time.Now().Add(g.expiration))
-	} else {
-		g.mainCache.Add(key, view)
+	// 设置到本地缓存，同时删除之前的旧值，避免加载失败时旧值覆盖本次写入
+	g.populateCacheTTL(key, view, ttl)
+
+	// 新值写入后，之前发放的租约不再允许回写
+	if g.leases != nil {
+		g.leases.invalidate(key, ByteView{}, false)
 	}
 
 	// 如果不是从其他节点同步过来的请求，且启用了分布式模式，同步到其他节点
 	if !isPeerRequest && g.peers != nil {
-		go g.syncToPeers(ctx, "set", key, value)
+		go g.syncToPeers(ctx, "set", key, value, ttl)
 	}
 
 	return nil
@@ -200,41 +368,65 @@ func (g *Group) Delete(ctx context.Context, key string) error {
 		return ErrKeyRequired
 	}
 
-	// 从本地缓存删除
-	g.mainCache.Delete(key)
+	// 需要持久化的客户端删除先同步转发给所有者，由所有者从后端存储删除
+	forwarded := false
+	if ctx.Value("from_peer") == nil {
+		var err error
+		if forwarded, err = g.forwardWrite(ctx, key, nil, 0, true); err != nil {
+			return err
+		}
+	}
+	if !forwarded && persistsHere(ctx) {
+		if err := g.persist(ctx, key, nil, true); err != nil {
+			return err
+		}
+	}
+
+	// 检查是否是从其他节点同步过来的请求，已转发给所有者的删除也不再同步
+	isPeerRequest := ctx.Value("from_peer") != nil || forwarded
 
-	// 检查是否是从其他节点同步过来的请求
-	isPeerRequest := ctx.Value("from_peer") != nil
+	// 撤销租约并保留旧值，供等待租约的调用方使用
+	if g.leases != nil {
+		old, ok := g.mainCache.Get(ctx, key)
+		g.leases.invalidate(key, old, ok)
+	}
+
+	// 从本地缓存删除，被删除的 key 也不再返回旧值
+	g.mainCache.Delete(key)
+	g.dropStale(key)
 
 	// 如果不是从其他节点同步过来的请求，且启用了分布式模式，同步到其他节点
 	if !isPeerRequest && g.peers != nil {
-		go g.syncToPeers(ctx, "delete", key, nil)
+		go g.syncToPeers(ctx, "delete", key, nil, 0)
 	}
 
 	return nil
 }
 
 // syncToPeers 同步操作到其他节点
-func (g *Group) syncToPeers(ctx context.Context, op string, key string, value []byte) {
+func (g *Group) syncToPeers(ctx context.Context, op string, key string, value []byte, ttl time.Duration) {
 	if g.peers == nil {
 		return
 	}
 
-	// 选择对等节点
-	peer, ok, isSelf := g.peers.PickPeer(key)
-	if !ok || isSelf {
+	// 只同步给所有者，不在其他节点上故障转移，避免同一个 key 出现多份互相不一致的副本
+	peer, err := g.pickOwner(key)
+	if err != nil {
+		logrus.Errorf("[KamaCache] failed to sync %s to peer: %v", op, err)
+		return
+	}
+	if peer == nil {
 		return
 	}
 
-	// 创建同步请求上下文
-	syncCtx := context.WithValue(context.Background(), "from_peer", true)
+	// 创建同步请求上下文，异步同步不随原请求取消，但保留其中的值
+	syncCtx := context.WithValue(context.WithoutCancel(ctx), "from_peer", true)
 
-	var err error
 	switch op {
 	case "set":
-		err = peer.Set(syncCtx, g.name, key, value)
+		err = peer.Set(syncCtx, g.name, key, value, ttl)
 	case "delete":
-		_, err = peer.Delete(g.name, key)
+		_, err = peer.Delete(syncCtx, g.name, key)
 	}
 
 	if err != nil {
@@ -242,6 +434,78 @@ func (g *Group) syncToPeers(ctx context.Context, op string, key string, value []
 	}
 }
 
+// TTL 返回 key 在本节点缓存中的剩余过期时间，永不过期时返回0，本节点未缓存时 ok 为 false
+func (g *Group) TTL(key string) (ttl time.Duration, ok bool) {
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return 0, false
+	}
+	return g.mainCache.TTL(key)
+}
+
+// Range 遍历本节点缓存的所有键值，不包含其他节点的数据，fn 返回 false 时停止遍历
+func (g *Group) Range(fn func(key string, value ByteView) bool) {
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return
+	}
+	g.mainCache.Range(fn)
+}
+
+// Handoff 将本地缓存的数据发送给各 key 当前的所有者节点，返回发送成功的数量。
+// 需要在本节点离开哈希环之后调用，新所有者使用自己的过期时间
+func (g *Group) Handoff(ctx context.Context) (int, error) {
+	if g.peers == nil {
+		return 0, nil
+	}
+
+	// 标记为来自其他节点的请求，新所有者不再同步给其他节点
+	ctx = context.WithValue(ctx, "from_peer", true)
+
+	var (
+		moved    int
+		failures int
+		lastErr  error
+	)
+	g.mainCache.Range(func(key string, value ByteView) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if g.localOnly(value.Len()) {
+			return true
+		}
+		peer, err := g.pickOwner(key)
+		if err != nil {
+			failures++
+			lastErr = err
+			return true
+		}
+		if peer == nil {
+			return true
+		}
+		decoded, err := value.decode()
+		if err != nil {
+			failures++
+			lastErr = err
+			return true
+		}
+		if err := peer.Set(ctx, g.name, key, decoded.b, 0); err != nil {
+			failures++
+			lastErr = err
+			return true
+		}
+		moved++
+		return true
+	})
+
+	if err := ctx.Err(); err != nil {
+		return moved, err
+	}
+	if failures > 0 {
+		return moved, fmt.Errorf("failed to hand off %d keys of group %s: %w", failures, g.name, lastErr)
+	}
+	return moved, nil
+}
+
 // Clear 清空缓存
 func (g *Group) Clear() {
 	// 检查组是否已关闭
@@ -260,15 +524,31 @@ func (g *Group) Close() error {
 		return nil
 	}
 
+	// 发送剩余的计数器增量
+	if g.incrBatcher != nil {
+		g.incrBatcher.close()
+	}
+
+	// 写入队列中剩余的数据
+	if g.writeBehind != nil {
+		g.writeBehind.close()
+	}
+
 	// 关闭本地缓存
+	if g.memManager != nil {
+		g.memManager.Unregister(g.mainCache)
+	}
 	if g.mainCache != nil {
 		g.mainCache.Close()
 	}
+	if g.stale != nil {
+		g.stale.Close()
+	}
 
-	// 从全局组映射中移除
-	groupsMu.Lock()
-	delete(groups, g.name)
-	groupsMu.Unlock()
+	// 从所属的节点上移除
+	if g.node != nil {
+		g.node.removeGroup(g)
+	}
 
 	logrus.Infof("[KamaCache] closed cache group [%s]", g.name)
 	return nil
@@ -276,14 +556,41 @@ func (g *Group) Close() error {
 
 // load 加载数据
 func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
-	// 使用 singleflight 确保并发请求只加载一次
+	// 使用 singleflight 确保并发请求只加载一次，调用方超时或取消时只停止等待，不中断共享的加载
 	startTime := time.Now()
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
-		return g.loadData(ctx, key)
+	ctx, span := g.startSpan(ctx, "kamacache.singleflight")
+	viewi, err, shared := g.loader.DoContext(ctx, key, func() (interface{}, error) {
+		loadCtx, cancel := detachContext(ctx, g.loaderTimeout)
+		defer cancel()
+
+		view, err := g.loadData(loadCtx, key)
+		if err != nil {
+			// 返回旧值时不写入本地缓存，之后的请求仍会尝试加载
+			if stale, ok := g.loadStale(key, err); ok {
+				return stale, nil
+			}
+			return nil, err
+		}
+		if g.tooLarge(view.Len()) && g.oversize == OversizeReject {
+			return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrValueTooLarge, view.Len(), g.maxValueSize)
+		}
+
+		// 设置到本地缓存，租约模式下由回写负责
+		if g.leases == nil {
+			g.populateCache(key, view)
+		}
+		return view, nil
 	})
 
+	if span != nil {
+		span.SetAttributes(attribute.Bool("cache.shared", shared))
+	}
+	endSpan(span, err)
+
 	// 记录加载时间
-	loadDuration := time.Since(startTime).Nanoseconds()
+	elapsed := time.Since(startTime)
+	g.loadLatency.Observe(elapsed.Seconds())
+	loadDuration := elapsed.Nanoseconds()
 	atomic.AddInt64(&g.stats.loadDuration, loadDuration)
 	atomic.AddInt64(&g.stats.loads, 1)
 
@@ -292,24 +599,91 @@ func (g *Group) load(ctx context.Context, key string) (value ByteView, err error
 		return ByteView{}, err
 	}
 
-	view := viewi.(ByteView)
+	return viewi.(ByteView), nil
+}
+
+// detachContext 返回不随 ctx 取消而取消的上下文，只保留其中的值。
+// 共享的加载不继承第一个调用方的截止时间，只受 timeout（组的加载超时时间）限制，timeout 不大于0时不限制
+func detachContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if timeout > 0 {
+		return context.WithTimeout(detached, timeout)
+	}
+	return detached, func() {}
+}
+
+// tooLarge 判断长度为 n 的值是否超过组的大小限制
+func (g *Group) tooLarge(n int) bool {
+	return g.maxValueSize > 0 && n > g.maxValueSize
+}
+
+// localOnly 判断长度为 n 的值是否只保存在本节点、不发送给其他节点
+func (g *Group) localOnly(n int) bool {
+	return g.oversize == OversizeLocalOnly && g.tooLarge(n)
+}
+
+// populateCache 按组的过期时间将数据写入本地缓存
+func (g *Group) populateCache(key string, view ByteView) {
+	g.populateCacheTTL(key, view, 0)
+}
 
-	// 设置到本地缓存
-	if g.expiration > 0 {
-		g.mainCache.AddWithExpiration(key, view, time.Now().Add(g.expiration))
+// populateCacheTTL 按指定的过期时间将数据写入本地缓存，ttl 不大于0时使用组的过期时间
+func (g *Group) populateCacheTTL(key string, view ByteView, ttl time.Duration) {
+	view = g.compress(view)
+	if ttl <= 0 {
+		ttl = g.expiration
+	}
+	if ttl > 0 {
+		g.mainCache.AddWithExpiration(key, view, time.Now().Add(ttl))
 	} else {
 		g.mainCache.Add(key, view)
 	}
+	g.dropStale(key)
+}
+
+// compress 按组的压缩配置压缩值，压缩后没有变小时保持原样
+func (g *Group) compress(view ByteView) ByteView {
+	if g.compressor == nil || view.codec != nil || len(view.b) == 0 || len(view.b) < g.compressMinSize {
+		return view
+	}
 
-	return view, nil
+	out, err := g.compressor.Compress(view.b)
+	if err != nil {
+		logrus.Warnf("[KamaCache] failed to compress value with %s: %v", g.compressor.Name(), err)
+		return view
+	}
+
+	atomic.AddInt64(&g.stats.rawBytes, int64(len(view.b)))
+	if len(out) >= len(view.b) {
+		atomic.AddInt64(&g.stats.compressedBytes, int64(len(view.b)))
+		return view
+	}
+	atomic.AddInt64(&g.stats.compressedBytes, int64(len(out)))
+	return ByteView{b: out, codec: g.compressor, size: len(view.b)}
 }
 
 // loadData 实际加载数据的方法
 func (g *Group) loadData(ctx context.Context, key string) (value ByteView, err error) {
-	// 尝试从远程节点获取
-	if g.peers != nil {
-		peer, ok, isSelf := g.peers.PickPeer(key)
-		if ok && !isSelf {
+	// 租约模式下由持有租约的调用方加载
+	if g.leases != nil {
+		return g.loadWithLease(ctx, key)
+	}
+
+	// 尝试从远程节点获取，来自其他节点的请求说明本节点被视为所有者，不再转发
+	if g.peers != nil && ctx.Value("from_peer") == nil {
+		var peer Peer
+		if g.ownerLoad != OwnerLoadDisabled {
+			// 严格模式下只向所有者获取，故障转移到其他节点会使其在本地加载
+			p, err := g.pickOwner(key)
+			if err != nil {
+				atomic.AddInt64(&g.stats.peerMisses, 1)
+				return g.loadFromOwner(ctx, key, err)
+			}
+			peer = p
+		} else if p, ok, isSelf := g.peers.PickPeer(key); ok && !isSelf {
+			peer = p
+		}
+		if peer != nil {
 			value, err := g.getFromPeer(ctx, peer, key)
 			if err == nil {
 				atomic.AddInt64(&g.stats.peerHits, 1)
@@ -317,12 +691,41 @@ func (g *Group) loadData(ctx context.Context, key string) (value ByteView, err e
 			}
 
 			atomic.AddInt64(&g.stats.peerMisses, 1)
+
+			// 所有者已确认数据不存在，无需在本地再加载一次
+			if errors.Is(err, ErrNotFound) {
+				return ByteView{}, err
+			}
+
+			// 所有者不发送超过大小限制的值，由本节点自行加载
+			if errors.Is(err, ErrValueTooLarge) {
+				return g.loadFromGetter(ctx, key)
+			}
+
+			// 严格模式下非所有者节点不在本地加载
+			if g.ownerLoad != OwnerLoadDisabled {
+				return g.loadFromOwner(ctx, key, err)
+			}
 			logrus.Warnf("[KamaCache] failed to get from peer: %v", err)
 		}
 	}
 
-	// 从数据源加载
-	bytes, err := g.getter.Get(ctx, key)
+	return g.loadFromGetter(ctx, key)
+}
+
+// loadFromGetter 从数据源加载数据，返回未压缩的值，由调用方通过 populateCache 压缩后写入缓存并保存旧值
+func (g *Group) loadFromGetter(ctx context.Context, key string) (ByteView, error) {
+	// 尚未写入后端存储的数据以队列中的为准，写入由所有者持久化，因此队列也在所有者节点上
+	if g.writeBehind != nil {
+		if p, ok := g.writeBehind.lookup(key); ok {
+			if p.delete {
+				return ByteView{}, fmt.Errorf("failed to get data: %w", ErrNotFound)
+			}
+			return ByteView{b: cloneBytes(p.value)}, nil
+		}
+	}
+
+	bytes, err := g.callGetter(ctx, key)
 	if err != nil {
 		return ByteView{}, fmt.Errorf("failed to get data: %w", err)
 	}
@@ -333,7 +736,9 @@ func (g *Group) loadData(ctx context.Context, key string) (value ByteView, err e
 
 // getFromPeer 从其他节点获取数据
 func (g *Group) getFromPeer(ctx context.Context, peer Peer, key string) (ByteView, error) {
-	bytes, err := peer.Get(g.name, key)
+	ctx, span := g.startSpan(ctx, "kamacache.peer.Get")
+	bytes, err := peer.Get(ctx, g.name, key)
+	endSpan(span, err)
 	if err != nil {
 		return ByteView{}, fmt.Errorf("failed to get from peer: %w", err)
 	}
@@ -370,6 +775,37 @@ func (g *Group) Stats() map[string]interface{} {
 		stats["hit_rate"] = float64(stats["local_hits"].(int64)) / float64(totalGets)
 	}
 
+	// 压缩率为压缩前与压缩后长度之比
+	if g.compressor != nil {
+		stats["compression"] = g.compressor.Name()
+		if compressed := atomic.LoadInt64(&g.stats.compressedBytes); compressed > 0 {
+			stats["compression_ratio"] = float64(atomic.LoadInt64(&g.stats.rawBytes)) / float64(compressed)
+		}
+	}
+
+	if g.loaderSem != nil {
+		stats["loader_in_flight"] = len(g.loaderSem)
+	}
+	if g.loaderSem != nil || g.loaderLimiter != nil || g.loaderBreaker != nil {
+		stats["loader_rejected"] = atomic.LoadInt64(&g.stats.loaderRejected)
+	}
+	if g.loaderBreaker != nil {
+		stats["loader_breaker_open"] = g.loaderBreaker.isOpen()
+	}
+	if g.stale != nil {
+		stats["stale_hits"] = atomic.LoadInt64(&g.stats.staleHits)
+	}
+
+	if g.writeMode != WriteAround {
+		stats["write_mode"] = g.writeMode.String()
+	}
+	if w := g.writeBehind; w != nil {
+		stats["write_queue_depth"] = w.depth()
+		stats["write_flushed"] = atomic.LoadInt64(&w.flushed)
+		stats["write_retries"] = atomic.LoadInt64(&w.retries)
+		stats["write_flush_failures"] = atomic.LoadInt64(&w.failures)
+	}
+
 	totalLoads := stats["loads"].(int64)
 	if totalLoads > 0 {
 		stats["avg_load_time_ms"] = float64(atomic.LoadInt64(&g.stats.loadDuration)) / float64(totalLoads) / float64(time.Millisecond)
@@ -386,42 +822,17 @@ func (g *Group) Stats() map[string]interface{} {
 	return stats
 }
 
-// ListGroups 返回所有缓存组的名称
+// ListGroups 返回默认节点上所有缓存组的名称
 func ListGroups() []string {
-	groupsMu.RLock()
-	defer groupsMu.RUnlock()
-
-	names := make([]string, 0, len(groups))
-	for name := range groups {
-		names = append(names, name)
-	}
-
-	return names
+	return defaultNode.ListGroups()
 }
 
-// DestroyGroup 销毁指定名称的缓存组
+// DestroyGroup 销毁默认节点上指定名称的缓存组
 func DestroyGroup(name string) bool {
-	groupsMu.Lock()
-	defer groupsMu.Unlock()
-
-	if g, exists := groups[name]; exists {
-		g.Close()
-		delete(groups, name)
-		logrus.Infof("[KamaCache] destroyed cache group [%s]", name)
-		return true
-	}
-
-	return false
+	return defaultNode.DestroyGroup(name)
 }
 
-// DestroyAllGroups 销毁所有缓存组
+// DestroyAllGroups 销毁默认节点上所有缓存组
 func DestroyAllGroups() {
-	groupsMu.Lock()
-	defer groupsMu.Unlock()
-
-	for name, g := range groups {
-		g.Close()
-		delete(groups, name)
-		logrus.Infof("[KamaCache] destroyed cache group [%s]", name)
-	}
+	defaultNode.DestroyAllGroups()
 }
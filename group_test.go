@@ -0,0 +1,104 @@
+package kamacache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePeer 在内存中记录写入的远程节点
+type fakePeer struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newFakePeer() *fakePeer {
+	return &fakePeer{data: make(map[string][]byte)}
+}
+
+func (p *fakePeer) Get(ctx context.Context, group, key string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if v, ok := p.data[key]; ok {
+		return v, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (p *fakePeer) Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.data[key] = value
+	return nil
+}
+
+func (p *fakePeer) Delete(ctx context.Context, group, key string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.data[key]
+	delete(p.data, key)
+	return ok, nil
+}
+
+func (p *fakePeer) Incr(ctx context.Context, group, key string, delta int64, ttl time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (p *fakePeer) Lease(ctx context.Context, group, key string) (LeaseResult, error) {
+	return LeaseResult{}, ErrLeasesDisabled
+}
+
+func (p *fakePeer) SetWithLease(ctx context.Context, group, key string, value []byte, token uint64) error {
+	return p.Set(ctx, group, key, value, 0)
+}
+
+func (p *fakePeer) Close() error { return nil }
+
+// fakePicker 按 owner 函数将 key 分配给自身或 fakePeer
+type fakePicker struct {
+	peer  *fakePeer
+	owner func(key string) bool // 返回 true 表示由 peer 负责
+}
+
+func (p *fakePicker) PickPeer(key string) (Peer, bool, bool) {
+	if p.owner(key) {
+		return p.peer, true, false
+	}
+	return nil, true, true
+}
+
+func (p *fakePicker) Close() error { return nil }
+
+// 测试下线前将本地数据移交给新的所有者
+func TestGroupHandoff(t *testing.T) {
+	getter := GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	})
+	g := NewGroup("handoff-test", 1<<20, getter)
+	defer g.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		if err := g.Set(ctx, key, []byte("value-"+key)); err != nil {
+			t.Fatalf("写入 %s 失败: %v", key, err)
+		}
+	}
+
+	peer := newFakePeer()
+	g.RegisterPeers(&fakePicker{peer: peer, owner: func(key string) bool { return key != "c" }})
+
+	moved, err := g.Handoff(ctx)
+	if err != nil {
+		t.Fatalf("移交失败: %v", err)
+	}
+	if moved != 2 {
+		t.Fatalf("应移交 2 个键，实际为 %d", moved)
+	}
+	if string(peer.data["a"]) != "value-a" || string(peer.data["b"]) != "value-b" {
+		t.Fatalf("新所有者收到的数据不正确: %v", peer.data)
+	}
+	if _, ok := peer.data["c"]; ok {
+		t.Fatal("仍由本节点负责的键不应被移交")
+	}
+}
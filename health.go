@@ -0,0 +1,185 @@
+package kamacache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// HealthCheckOptions 节点健康检查配置
+type HealthCheckOptions struct {
+	Interval         time.Duration // 主动检查间隔，0表示只使用被动检测
+	Timeout          time.Duration // 单次检查超时时间
+	FailureThreshold int           // 连续检查失败多少次后摘除节点
+
+	ErrorRate   float64       // 被动检测：统计窗口内错误率达到该值时摘除节点，0表示不启用
+	MinRequests int           // 被动检测：统计窗口内至少有多少次请求才计算错误率
+	Window      time.Duration // 被动检测：统计窗口长度
+	EjectTime   time.Duration // 被动检测：摘除后至少经过多久才恢复
+}
+
+// DefaultHealthCheckOptions 返回默认的健康检查配置
+func DefaultHealthCheckOptions() HealthCheckOptions {
+	return HealthCheckOptions{
+		Interval:         5 * time.Second,
+		Timeout:          time.Second,
+		FailureThreshold: 2,
+		ErrorRate:        0.5,
+		MinRequests:      20,
+		Window:           10 * time.Second,
+		EjectTime:        10 * time.Second,
+	}
+}
+
+// WithHealthCheck 启用节点健康检查，不健康的节点仍保留在哈希环上，
+// 但在恢复之前不再接收请求，其负责的 key 由环上的后继节点处理
+func WithHealthCheck(opts HealthCheckOptions) PickerOption {
+	return func(p *ClientPicker) {
+		p.health = &opts
+	}
+}
+
+// peerHealth 记录单个节点的健康状态
+type peerHealth struct {
+	opts HealthCheckOptions
+	addr string
+
+	mu            sync.Mutex
+	checkFailures int       // 连续主动检查失败次数
+	unhealthy     bool      // 主动检查判定为不健康
+	windowStart   time.Time // 被动检测当前窗口的开始时间
+	requests      int       // 窗口内请求数
+	errors        int       // 窗口内节点不可用类错误数
+	ejectedUntil  time.Time // 被动摘除的截止时间
+}
+
+// newPeerHealth 创建节点健康状态
+func newPeerHealth(addr string, opts HealthCheckOptions) *peerHealth {
+	return &peerHealth{
+		opts:        opts,
+		addr:        addr,
+		windowStart: time.Now(),
+	}
+}
+
+// available 返回节点当前是否可以接收请求
+func (h *peerHealth) available() bool {
+	if h == nil {
+		return true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.unhealthy && !time.Now().Before(h.ejectedUntil)
+}
+
+// observe 记录一次请求结果，错误率过高时摘除节点
+func (h *peerHealth) observe(failed bool) {
+	if h == nil || h.opts.ErrorRate <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if h.opts.Window > 0 && now.Sub(h.windowStart) >= h.opts.Window {
+		h.windowStart, h.requests, h.errors = now, 0, 0
+	}
+
+	h.requests++
+	if failed {
+		h.errors++
+	}
+
+	if h.requests < h.opts.MinRequests || now.Before(h.ejectedUntil) {
+		return
+	}
+	if rate := float64(h.errors) / float64(h.requests); rate >= h.opts.ErrorRate {
+		h.ejectedUntil = now.Add(h.opts.EjectTime)
+		h.windowStart, h.requests, h.errors = now, 0, 0
+		logrus.Warnf("[KamaCache] ejecting peer %s for %v, error rate %.2f", h.addr, h.opts.EjectTime, rate)
+	}
+}
+
+// reportCheck 记录一次主动检查结果
+func (h *peerHealth) reportCheck(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err == nil {
+		if h.unhealthy {
+			logrus.Infof("[KamaCache] peer %s is healthy again", h.addr)
+		}
+		h.checkFailures = 0
+		h.unhealthy = false
+		return
+	}
+
+	h.checkFailures++
+	threshold := h.opts.FailureThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	if !h.unhealthy && h.checkFailures >= threshold {
+		h.unhealthy = true
+		logrus.Warnf("[KamaCache] peer %s marked unhealthy: %v", h.addr, err)
+	}
+}
+
+// checkHealth 调用节点的 gRPC 健康检查服务
+func (c *Client) checkHealth(ctx context.Context, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resp, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{Service: c.svcName})
+	if err != nil {
+		// 未注册健康检查服务的节点只依赖被动检测
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return status.Errorf(codes.Unavailable, "peer is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// runHealthChecks 定期检查所有节点的健康状态
+func (p *ClientPicker) runHealthChecks() {
+	ticker := time.NewTicker(p.health.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.mu.RLock()
+			clients := make([]*Client, 0, len(p.clients))
+			for _, c := range p.clients {
+				clients = append(clients, c)
+			}
+			p.mu.RUnlock()
+
+			var wg sync.WaitGroup
+			for _, c := range clients {
+				wg.Add(1)
+				go func(c *Client) {
+					defer wg.Done()
+					c.health.reportCheck(c.checkHealth(p.ctx, p.health.Timeout))
+				}(c)
+			}
+			wg.Wait()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package kamacache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 测试被动检测与主动检查对节点可用性的影响
+func TestPeerHealth(t *testing.T) {
+	opts := HealthCheckOptions{
+		FailureThreshold: 2,
+		ErrorRate:        0.5,
+		MinRequests:      4,
+		Window:           time.Minute,
+		EjectTime:        50 * time.Millisecond,
+	}
+
+	t.Run("错误率过高时摘除并在到期后恢复", func(t *testing.T) {
+		h := newPeerHealth("peer", opts)
+		h.observe(false)
+		h.observe(true)
+		h.observe(false)
+		if !h.available() {
+			t.Fatal("请求数不足时不应摘除节点")
+		}
+		h.observe(true)
+		if h.available() {
+			t.Fatal("错误率达到阈值后应摘除节点")
+		}
+		time.Sleep(60 * time.Millisecond)
+		if !h.available() {
+			t.Fatal("摘除时间到期后应恢复节点")
+		}
+	})
+
+	t.Run("主动检查连续失败后摘除", func(t *testing.T) {
+		h := newPeerHealth("peer", opts)
+		h.reportCheck(errors.New("unavailable"))
+		if !h.available() {
+			t.Fatal("失败次数未达到阈值时不应摘除节点")
+		}
+		h.reportCheck(errors.New("unavailable"))
+		if h.available() {
+			t.Fatal("连续失败达到阈值后应摘除节点")
+		}
+		h.reportCheck(nil)
+		if !h.available() {
+			t.Fatal("检查成功后应恢复节点")
+		}
+	})
+
+	t.Run("未启用时始终可用", func(t *testing.T) {
+		var h *peerHealth
+		h.observe(true)
+		if !h.available() {
+			t.Fatal("未启用健康检查时节点应始终可用")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// latencyWindowSize 耗时统计保留的样本数
+const latencyWindowSize = 128
+
+// latencyWindow 保存最近若干次请求的耗时，用于估计对冲请求的触发延迟
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// newLatencyWindow 创建耗时统计窗口
+func newLatencyWindow() *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, 0, latencyWindowSize)}
+}
+
+// record 记录一次耗时
+func (w *latencyWindow) record(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// percentile 返回耗时的分位数，样本不足时返回0
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	w.mu.Lock()
+	sorted := slices.Clone(w.samples)
+	w.mu.Unlock()
+
+	if len(sorted) < 10 {
+		return 0
+	}
+	slices.Sort(sorted)
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+// HedgeOptions 对冲请求配置
+type HedgeOptions struct {
+	Delay    time.Duration // 固定的对冲延迟，0表示使用主节点最近 Get 耗时的 p95，样本不足时只在主节点出错后才请求备用节点
+	MinDelay time.Duration // 对冲延迟的下限，避免耗时很短时过早发出对冲请求
+}
+
+// WithHedging 为 Get 启用对冲请求：主节点在对冲延迟内未返回时，
+// 同时向哈希环上的下一个节点发出相同请求，采用先返回的结果
+func WithHedging(opts HedgeOptions) PickerOption {
+	return func(p *ClientPicker) {
+		p.hedge = &opts
+	}
+}
+
+// hedgedPeer 在 Get 时向备用节点发出对冲请求，其余操作只发往主节点
+type hedgedPeer struct {
+	*Client
+	secondary *Client
+	opts      HedgeOptions
+}
+
+// delay 返回本次请求的对冲延迟，尚无耗时估计时返回 false，此时不按延迟发出对冲请求
+func (h *hedgedPeer) delay() (time.Duration, bool) {
+	d := h.opts.Delay
+	if d <= 0 {
+		if d = h.Client.latency.percentile(0.95); d <= 0 {
+			return 0, false
+		}
+	}
+	if d < h.opts.MinDelay {
+		d = h.opts.MinDelay
+	}
+	return d, true
+}
+
+// Get 先请求主节点，超过对冲延迟或主节点出错时再请求备用节点
+func (h *hedgedPeer) Get(ctx context.Context, group, key string) ([]byte, error) {
+	type result struct {
+		value []byte
+		err   error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, 2)
+	call := func(c *Client) {
+		value, err := c.Get(ctx, group, key)
+		results <- result{value, err}
+	}
+
+	go call(h.Client)
+
+	// 没有对冲延迟时 hedgeC 为 nil，永远不会触发
+	var hedgeC <-chan time.Time
+	if d, ok := h.delay(); ok {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		hedgeC = timer.C
+	}
+
+	pending, hedged := 1, false
+	var firstErr error
+	for {
+		select {
+		case <-hedgeC:
+			if !hedged {
+				hedged = true
+				pending++
+				go call(h.secondary)
+			}
+		case r := <-results:
+			pending--
+			// 键不存在是确定的结果，无需等待备用节点
+			if r.err == nil || errors.Is(r.err, ErrNotFound) {
+				return r.value, r.err
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if !hedged {
+				hedged = true
+				pending++
+				go call(h.secondary)
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
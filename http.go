@@ -0,0 +1,321 @@
+package kamacache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SuperJinggg/mycache-go/metrics"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TTLHeader 写入时指定过期时间的请求头，值为秒数或 Go 的时间格式（如 "1m30s"）
+const TTLHeader = "X-Cache-TTL"
+
+// WithHTTPAddr 在 addr 上启动 HTTP/JSON 网关，与 gRPC 服务共享认证、授权和 TLS 配置
+func WithHTTPAddr(addr string) ServerOption {
+	return func(o *ServerOptions) {
+		o.HTTPAddr = addr
+	}
+}
+
+// newHTTPMux 创建 HTTP 网关的路由
+func (s *Server) newHTTPMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /groups", s.handleListGroups)
+	mux.HandleFunc("GET /groups/{group}/stats", s.handleGroupStats)
+	mux.HandleFunc("GET /groups/{group}/keys/{key}", s.handleGet)
+	mux.HandleFunc("PUT /groups/{group}/keys/{key}", s.handleSet)
+	mux.HandleFunc("DELETE /groups/{group}/keys/{key}", s.handleDelete)
+	if s.opts.MetricsPath != "" {
+		metrics.HandleHTTP(mux, s.opts.MetricsPath, s.node.registry)
+	}
+	return mux
+}
+
+// ServeHTTP 实现 http.Handler 接口，可以挂载到已有的 HTTP 服务中
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.httpMux.ServeHTTP(w, r)
+}
+
+// HandleHTTP 在 HTTP 网关上挂载额外的处理器，需要在 Start 之前调用
+func (s *Server) HandleHTTP(pattern string, handler http.Handler) {
+	s.httpMux.Handle(pattern, handler)
+}
+
+// startHTTP 启动 HTTP 网关
+func (s *Server) startHTTP() error {
+	lis, err := net.Listen("tcp", s.opts.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen http: %v", err)
+	}
+
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if s.tlsReloader != nil {
+		srv.TLSConfig = s.tlsReloader.serverConfig(s.opts.ClientCAFile != "", "h2", "http/1.1")
+	}
+
+	s.mu.Lock()
+	s.httpServer = srv
+	s.mu.Unlock()
+
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ServeTLS(lis, "", "")
+		} else {
+			err = srv.Serve(lis)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("[KamaCache] http gateway stopped: %v", err)
+		}
+	}()
+
+	logrus.Infof("[KamaCache] http gateway listening at %s", s.opts.HTTPAddr)
+	return nil
+}
+
+// authenticateHTTP 使用与 gRPC 相同的认证方式认证 HTTP 请求，Authorization 头和客户端证书都可用于认证
+func (s *Server) authenticateHTTP(r *http.Request) (string, error) {
+	if s.opts.Authenticator == nil {
+		return "", nil
+	}
+
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationHeader, auth))
+	}
+	p := &peer.Peer{}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		p.Addr = addr
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+
+	identity, err := s.opts.Authenticator.Authenticate(peer.NewContext(ctx, p))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	return identity, nil
+}
+
+// authorizeHTTP 认证 HTTP 请求并检查对组的操作权限，成功时返回携带身份的上下文
+func (s *Server) authorizeHTTP(r *http.Request, group string, op Operation) (context.Context, error) {
+	ctx := r.Context()
+	if s.opts.Authenticator == nil {
+		return ctx, nil
+	}
+
+	identity, err := s.authenticateHTTP(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.Authorizer != nil && !s.opts.Authorizer.Authorize(identity, group, op) {
+		return nil, fmt.Errorf("%w: %s is not allowed to %s on group %s", ErrPermissionDenied, identity, op, group)
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+// handleListGroups 列出组，配置了授权时只返回有读权限的组
+func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	identity, err := s.authenticateHTTP(r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	names := []string{}
+	for _, name := range s.node.ListGroups() {
+		if s.opts.Authenticator != nil && s.opts.Authorizer != nil && !s.opts.Authorizer.Authorize(identity, name, OpGet) {
+			continue
+		}
+		names = append(names, name)
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+// handleGroupStats 返回组的统计信息
+func (s *Server) handleGroupStats(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	if _, err := s.authorizeHTTP(r, name, OpGet); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	group := s.node.GetGroup(name)
+	if group == nil {
+		writeHTTPError(w, fmt.Errorf("%w: %s", ErrGroupNotFound, name))
+		return
+	}
+	writeJSON(w, http.StatusOK, group.Stats())
+}
+
+// handleGet 读取缓存值，未命中时按正常流程从所有者节点或数据源加载
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	name, key := r.PathValue("group"), r.PathValue("key")
+	ctx, err := s.authorizeHTTP(r, name, OpGet)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	group := s.node.GetGroup(name)
+	if group == nil {
+		writeHTTPError(w, fmt.Errorf("%w: %s", ErrGroupNotFound, name))
+		return
+	}
+
+	view, err := group.Get(ctx, key)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(view.Len()))
+	w.WriteHeader(http.StatusOK)
+	view.WriteTo(w)
+}
+
+// handleSet 写入缓存值，请求体为原始值，过期时间由 TTLHeader 或 ttl 查询参数指定
+func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
+	name, key := r.PathValue("group"), r.PathValue("key")
+	ctx, err := s.authorizeHTTP(r, name, OpSet)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	group := s.node.GetGroup(name)
+	if group == nil {
+		writeHTTPError(w, fmt.Errorf("%w: %s", ErrGroupNotFound, name))
+		return
+	}
+
+	ttlValue := r.Header.Get(TTLHeader)
+	if ttlValue == "" {
+		ttlValue = r.URL.Query().Get("ttl")
+	}
+	ttl, err := parseTTL(ttlValue)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error(), Code: "INVALID_TTL"})
+		return
+	}
+
+	// 与 SetStream 相同，请求体不受单条消息长度的限制
+	value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.writeLimit(group)))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, httpError{Error: err.Error(), Code: "VALUE_TOO_LARGE"})
+		return
+	}
+
+	if err := group.SetWithTTL(ctx, key, value, ttl); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDelete 删除缓存值
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	name, key := r.PathValue("group"), r.PathValue("key")
+	ctx, err := s.authorizeHTTP(r, name, OpDelete)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	group := s.node.GetGroup(name)
+	if group == nil {
+		writeHTTPError(w, fmt.Errorf("%w: %s", ErrGroupNotFound, name))
+		return
+	}
+
+	if err := group.Delete(ctx, key); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTTL 解析过期时间，支持秒数和 Go 的时间格式，空字符串表示使用组的过期时间
+func parseTTL(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid ttl %q", v)
+	}
+	return d, nil
+}
+
+// httpError HTTP 网关返回的错误
+type httpError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// httpStatusCodes gRPC 状态码对应的 HTTP 状态码
+var httpStatusCodes = map[codes.Code]int{
+	codes.NotFound:           http.StatusNotFound,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusNotFound,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.Aborted:            http.StatusConflict,
+	codes.ResourceExhausted:  http.StatusRequestEntityTooLarge,
+	codes.Canceled:           499, // 客户端关闭连接
+}
+
+// writeHTTPError 复用 gRPC 的错误映射将错误转换为 HTTP 状态码和 JSON 错误
+func writeHTTPError(w http.ResponseWriter, err error) {
+	st := status.Convert(toStatusError(err))
+
+	code := http.StatusInternalServerError
+	if c, ok := httpStatusCodes[st.Code()]; ok {
+		code = c
+	}
+	// 组存在但操作不满足前置条件（例如未开启租约）时返回 412
+	if st.Code() == codes.FailedPrecondition && !errors.Is(err, ErrGroupNotFound) {
+		code = http.StatusPreconditionFailed
+	}
+
+	reason := st.Code().String()
+	for _, m := range errorMapping {
+		if errors.Is(err, m.err) {
+			reason = m.reason
+			break
+		}
+	}
+	writeJSON(w, code, httpError{Error: st.Message(), Code: reason})
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("[KamaCache] failed to write http response: %v", err)
+	}
+}
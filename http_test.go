@@ -0,0 +1,155 @@
+package kamacache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试 HTTP 网关的读写、错误映射和认证
+func TestHTTPGateway(t *testing.T) {
+	getter := GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if key == "loaded" {
+			return []byte("from-getter"), nil
+		}
+		return nil, ErrNotFound
+	})
+	g := NewGroup("http-test", 1<<20, getter)
+	defer g.Close()
+
+	srv, err := NewServer("127.0.0.1:0", "http-test",
+		WithAuth(StaticTokenAuthenticator{"rw-token": "writer", "ro-token": "reader"}, NewACL(
+			ACLRule{Identity: "writer", Group: "*"},
+			ACLRule{Identity: "reader", Group: "http-test", Ops: []Operation{OpGet}},
+		)),
+	)
+	if err != nil {
+		t.Fatalf("创建服务器失败: %v", err)
+	}
+	defer srv.etcdCli.Close()
+
+	do := func(method, path, token, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("写入后读取", func(t *testing.T) {
+		rec := do(http.MethodPut, "/groups/http-test/keys/k1", "rw-token", "v1", map[string]string{TTLHeader: "60"})
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("写入应返回 204，实际为 %d: %s", rec.Code, rec.Body)
+		}
+		rec = do(http.MethodGet, "/groups/http-test/keys/k1", "ro-token", "", nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != "v1" {
+			t.Fatalf("读取结果不正确: %d %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("未命中时通过 Getter 加载", func(t *testing.T) {
+		rec := do(http.MethodGet, "/groups/http-test/keys/loaded", "ro-token", "", nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != "from-getter" {
+			t.Fatalf("加载结果不正确: %d %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("错误映射", func(t *testing.T) {
+		cases := []struct {
+			method, path, token, body string
+			header                    map[string]string
+			code                      int
+			reason                    string
+		}{
+			{http.MethodGet, "/groups/http-test/keys/missing", "ro-token", "", nil, http.StatusNotFound, "NOT_FOUND"},
+			{http.MethodGet, "/groups/nope/keys/k1", "rw-token", "", nil, http.StatusNotFound, "GROUP_NOT_FOUND"},
+			{http.MethodPut, "/groups/http-test/keys/k2", "rw-token", "", nil, http.StatusBadRequest, "VALUE_REQUIRED"},
+			{http.MethodPut, "/groups/http-test/keys/k2", "rw-token", "v", map[string]string{TTLHeader: "soon"}, http.StatusBadRequest, "INVALID_TTL"},
+			{http.MethodGet, "/groups/http-test/keys/k1", "", "", nil, http.StatusUnauthorized, "UNAUTHENTICATED"},
+			{http.MethodDelete, "/groups/http-test/keys/k1", "ro-token", "", nil, http.StatusForbidden, "PERMISSION_DENIED"},
+		}
+		for _, c := range cases {
+			rec := do(c.method, c.path, c.token, c.body, c.header)
+			var body httpError
+			json.Unmarshal(rec.Body.Bytes(), &body)
+			if rec.Code != c.code || body.Code != c.reason {
+				t.Fatalf("%s %s 应返回 %d/%s，实际为 %d/%s", c.method, c.path, c.code, c.reason, rec.Code, body.Code)
+			}
+		}
+	})
+
+	t.Run("请求体按流式写入的上限限制", func(t *testing.T) {
+		small := NewGroup("http-small", 1<<20, getter, WithMaxValueSize(8, OversizeReject))
+		defer small.Close()
+		opts := *srv.opts
+		defer func() { *srv.opts = opts }()
+		srv.opts.MaxMsgSize, srv.opts.MaxStreamSize = 4, 16
+
+		cases := []struct {
+			group, body string
+			code        int
+		}{
+			{"http-test", strings.Repeat("x", 10), http.StatusNoContent},
+			{"http-test", strings.Repeat("x", 17), http.StatusRequestEntityTooLarge},
+			{"http-small", strings.Repeat("x", 9), http.StatusRequestEntityTooLarge},
+		}
+		for _, c := range cases {
+			rec := do(http.MethodPut, "/groups/"+c.group+"/keys/big", "rw-token", c.body, nil)
+			if rec.Code != c.code {
+				t.Fatalf("向 %s 写入 %d 字节应返回 %d，实际为 %d: %s", c.group, len(c.body), c.code, rec.Code, rec.Body)
+			}
+		}
+	})
+
+	t.Run("删除", func(t *testing.T) {
+		rec := do(http.MethodDelete, "/groups/http-test/keys/k1", "rw-token", "", nil)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("删除应返回 204，实际为 %d", rec.Code)
+		}
+		if _, ok := g.mainCache.Get(context.Background(), "k1"); ok {
+			t.Fatal("删除后本地缓存中不应存在该键")
+		}
+	})
+
+	t.Run("列出组和统计信息", func(t *testing.T) {
+		rec := do(http.MethodGet, "/groups", "ro-token", "", nil)
+		var names []string
+		json.Unmarshal(rec.Body.Bytes(), &names)
+		found := false
+		for _, n := range names {
+			found = found || n == "http-test"
+		}
+		if rec.Code != http.StatusOK || !found {
+			t.Fatalf("组列表不正确: %d %v", rec.Code, names)
+		}
+
+		rec = do(http.MethodGet, "/groups/http-test/stats", "ro-token", "", nil)
+		var stats map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &stats)
+		if rec.Code != http.StatusOK || stats["name"] != "http-test" {
+			t.Fatalf("统计信息不正确: %d %v", rec.Code, stats)
+		}
+	})
+}
+
+// 测试过期时间的解析
+func TestParseTTL(t *testing.T) {
+	cases := map[string]time.Duration{"": 0, "30": 30 * time.Second, "1m30s": 90 * time.Second}
+	for in, want := range cases {
+		if got, err := parseTTL(in); err != nil || got != want {
+			t.Fatalf("parseTTL(%q) 应为 %v，实际为 %v (%v)", in, want, got, err)
+		}
+	}
+	if _, err := parseTTL("-5s"); err == nil {
+		t.Fatal("负数过期时间应返回错误")
+	}
+}
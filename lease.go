@@ -0,0 +1,354 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrLeaseInvalid 租约令牌无效或已被撤销
+var ErrLeaseInvalid = errors.New("lease token is invalid or has been revoked")
+
+// ErrLeasesDisabled 组未开启租约模式
+var ErrLeasesDisabled = errors.New("leases are not enabled for this group")
+
+// LeaseStatus 租约请求的结果
+type LeaseStatus int
+
+const (
+	LeaseHit     LeaseStatus = iota // 缓存命中，Value 有效
+	LeaseGranted                    // 获得租约，调用方负责加载数据并携带 Token 回写
+	LeaseWait                       // 其他调用方持有租约，应稍后重试
+	LeaseStale                      // 其他调用方持有租约，Value 为删除前的旧值
+)
+
+// LeaseResult 租约请求的返回值
+type LeaseResult struct {
+	Status LeaseStatus
+	Value  ByteView
+	Token  uint64
+}
+
+// LeaseOptions 租约模式配置
+type LeaseOptions struct {
+	TTL          time.Duration // 租约有效期，超时后其他调用方可以重新获取租约
+	WaitInterval time.Duration // 等待租约时的轮询间隔
+	StaleTTL     time.Duration // 删除后旧值可作为陈旧数据返回的时长，0表示不返回旧值
+}
+
+// DefaultLeaseOptions 返回默认的租约配置
+func DefaultLeaseOptions() LeaseOptions {
+	return LeaseOptions{
+		TTL:          3 * time.Second,
+		WaitInterval: 50 * time.Millisecond,
+		StaleTTL:     0,
+	}
+}
+
+// WithLeases 开启 memcache 风格的租约加载模式
+// 未命中时只有获得租约的调用方会加载数据，其余调用方等待或使用旧值；
+// 租约发放后发生的 Set/Delete 会使租约失效，携带失效租约的回写会被拒绝
+func WithLeases(opts LeaseOptions) GroupOption {
+	return func(g *Group) {
+		defaults := DefaultLeaseOptions()
+		if opts.TTL <= 0 {
+			opts.TTL = defaults.TTL
+		}
+		if opts.WaitInterval <= 0 {
+			opts.WaitInterval = defaults.WaitInterval
+		}
+		g.leases = newLeaseManager(opts)
+	}
+}
+
+// leaseManager 在 key 的所有者节点上管理租约
+type leaseManager struct {
+	mu        sync.Mutex
+	opts      LeaseOptions
+	nextID    uint64
+	leases    map[string]*lease
+	stale     map[string]*staleValue
+	nextSweep time.Time // 下次清理过期租约和旧值的时间
+}
+
+// lease 表示一个已发放的租约
+type lease struct {
+	token    uint64
+	expireAt time.Time
+}
+
+// staleValue 表示删除前的旧值
+type staleValue struct {
+	value    ByteView
+	expireAt time.Time
+}
+
+// newLeaseManager 创建租约管理器
+func newLeaseManager(opts LeaseOptions) *leaseManager {
+	return &leaseManager{
+		opts:   opts,
+		nextID: uint64(time.Now().UnixNano()), // 避免节点重启后令牌重复
+		leases: make(map[string]*lease),
+		stale:  make(map[string]*staleValue),
+	}
+}
+
+// acquire 尝试为 key 获取租约，已有有效租约时返回可用的旧值
+func (m *leaseManager) acquire(key string) (token uint64, granted bool, stale ByteView, hasStale bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweepLocked(now)
+
+	if l, ok := m.leases[key]; ok && now.Before(l.expireAt) {
+		if sv, ok := m.stale[key]; ok && now.Before(sv.expireAt) {
+			return 0, false, sv.value, true
+		}
+		return 0, false, ByteView{}, false
+	}
+
+	m.nextID++
+	m.leases[key] = &lease{token: m.nextID, expireAt: now.Add(m.opts.TTL)}
+	return m.nextID, true, ByteView{}, false
+}
+
+// release 校验并释放租约，令牌无效时返回 false
+func (m *leaseManager) release(key string, token uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.leases[key]
+	if !ok || l.token != token {
+		return false
+	}
+
+	delete(m.leases, key)
+	delete(m.stale, key)
+	return true
+}
+
+// abandon 放弃加载失败的租约，其他调用方可以立即重新获取租约，旧值仍然保留
+func (m *leaseManager) abandon(key string, token uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.leases[key]
+	if !ok || l.token != token {
+		return false
+	}
+
+	delete(m.leases, key)
+	return true
+}
+
+// invalidate 撤销 key 上的租约，并在配置了 StaleTTL 时保留旧值
+func (m *leaseManager) invalidate(key string, old ByteView, hasOld bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.leases, key)
+
+	if !hasOld || m.opts.StaleTTL <= 0 {
+		delete(m.stale, key)
+		return
+	}
+
+	now := time.Now()
+	m.stale[key] = &staleValue{value: old, expireAt: now.Add(m.opts.StaleTTL)}
+	m.sweepLocked(now)
+}
+
+// sweepLocked 每隔一个租约有效期清理一次过期的租约和旧值，避免无人回写的 key 无限增长，调用方需持有锁
+func (m *leaseManager) sweepLocked(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	m.nextSweep = now.Add(m.opts.TTL)
+
+	for k, sv := range m.stale {
+		if !now.Before(sv.expireAt) {
+			delete(m.stale, k)
+		}
+	}
+	for k, l := range m.leases {
+		if !now.Before(l.expireAt) {
+			delete(m.leases, k)
+		}
+	}
+}
+
+// Lease 在本节点查询 key，未命中时尝试发放租约，应在 key 的所有者节点上调用
+func (g *Group) Lease(ctx context.Context, key string) (LeaseResult, error) {
+	// 检查组是否已关闭
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return LeaseResult{}, ErrGroupClosed
+	}
+
+	if key == "" {
+		return LeaseResult{}, ErrKeyRequired
+	}
+
+	if g.leases == nil {
+		return LeaseResult{}, ErrLeasesDisabled
+	}
+
+	if view, ok := g.mainCache.Get(ctx, key); ok {
+		decoded, err := view.decode()
+		if err == nil {
+			return LeaseResult{Status: LeaseHit, Value: decoded}, nil
+		}
+		// 损坏的值按未命中处理，由获得租约的调用方重新加载
+		g.dropCorrupt(key, err)
+	}
+
+	token, granted, stale, hasStale := g.leases.acquire(key)
+	switch {
+	case granted:
+		return LeaseResult{Status: LeaseGranted, Token: token}, nil
+	case hasStale:
+		if decoded, err := stale.decode(); err == nil {
+			return LeaseResult{Status: LeaseStale, Value: decoded}, nil
+		}
+		return LeaseResult{Status: LeaseWait}, nil
+	default:
+		return LeaseResult{Status: LeaseWait}, nil
+	}
+}
+
+// SetWithLease 携带租约令牌回写数据，租约已失效时返回 ErrLeaseInvalid。
+// value 为空表示加载失败，只释放租约而不写入，其他调用方可以立即重新获取租约
+func (g *Group) SetWithLease(ctx context.Context, key string, value []byte, token uint64) error {
+	// 检查组是否已关闭
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return ErrGroupClosed
+	}
+
+	if key == "" {
+		return ErrKeyRequired
+	}
+
+	if g.leases == nil {
+		return ErrLeasesDisabled
+	}
+
+	if len(value) == 0 {
+		if !g.leases.abandon(key, token) {
+			return ErrLeaseInvalid
+		}
+		return nil
+	}
+
+	if !g.leases.release(key, token) {
+		return ErrLeaseInvalid
+	}
+
+	g.populateCache(key, ByteView{b: cloneBytes(value)})
+	return nil
+}
+
+// loadWithLease 以租约模式加载数据，key 的所有者可能是本节点或远程节点
+func (g *Group) loadWithLease(ctx context.Context, key string) (ByteView, error) {
+	var peer Peer
+	if g.peers != nil && ctx.Value("from_peer") == nil {
+		// 租约只能由所有者发放，所有者不可用时不能转移到其他节点
+		p, err := g.pickOwner(key)
+		if err != nil {
+			atomic.AddInt64(&g.stats.peerMisses, 1)
+			if g.ownerLoad != OwnerLoadDisabled {
+				return g.loadFromOwner(ctx, key, err)
+			}
+			logrus.Warnf("[KamaCache] failed to get lease from peer: %v", err)
+			return g.loadFromGetter(ctx, key)
+		}
+		peer = p
+	}
+
+	for {
+		var res LeaseResult
+		var err error
+		if peer != nil {
+			res, err = peer.Lease(ctx, g.name, key)
+			if err != nil {
+				atomic.AddInt64(&g.stats.peerMisses, 1)
+				if g.ownerLoad != OwnerLoadDisabled {
+					return g.loadFromOwner(ctx, key, err)
+				}
+
+				// 所有者不可用时退化为直接加载，且不回写
+				logrus.Warnf("[KamaCache] failed to get lease from peer: %v", err)
+				return g.loadFromGetter(ctx, key)
+			}
+		} else {
+			res, err = g.Lease(ctx, key)
+			if err != nil {
+				return ByteView{}, err
+			}
+		}
+
+		switch res.Status {
+		case LeaseHit:
+			if peer != nil {
+				atomic.AddInt64(&g.stats.peerHits, 1)
+				g.populateCache(key, res.Value)
+			}
+			return res.Value, nil
+
+		case LeaseStale:
+			return res.Value, nil
+
+		case LeaseGranted:
+			view, err := g.loadFromGetter(ctx, key)
+			if err != nil {
+				g.abandonLease(ctx, peer, key, res.Token)
+				return ByteView{}, err
+			}
+
+			// 回写被拒绝说明加载期间数据被修改或删除，本次结果只返回给调用方
+			if peer != nil {
+				err = peer.SetWithLease(ctx, g.name, key, view.data(), res.Token)
+				if err == nil {
+					g.populateCache(key, view)
+				}
+			} else {
+				err = g.SetWithLease(ctx, key, view.data(), res.Token)
+			}
+			if err != nil {
+				logrus.Debugf("[KamaCache] lease set rejected for key %s: %v", key, err)
+			}
+			return view, nil
+
+		case LeaseWait:
+			timer := time.NewTimer(g.leases.opts.WaitInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ByteView{}, ctx.Err()
+			case <-timer.C:
+			}
+
+		default:
+			return ByteView{}, fmt.Errorf("unknown lease status %d", res.Status)
+		}
+	}
+}
+
+// abandonLease 加载失败时释放租约，避免其他调用方一直等到租约过期。
+// 加载超时后 ctx 已经结束，释放请求不随 ctx 取消
+func (g *Group) abandonLease(ctx context.Context, peer Peer, key string, token uint64) {
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	if peer != nil {
+		err = peer.SetWithLease(ctx, g.name, key, nil, token)
+	} else {
+		err = g.SetWithLease(ctx, key, nil, token)
+	}
+	if err != nil {
+		logrus.Debugf("[KamaCache] failed to release lease for key %s: %v", key, err)
+	}
+}
@@ -0,0 +1,88 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试加载失败时释放租约
+func TestLeaseReleasedOnLoadError(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	g := NewGroup("lease-error-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if failing.Load() {
+			return nil, errors.New("db down")
+		}
+		return []byte("v-" + key), nil
+	}), WithLeases(LeaseOptions{TTL: time.Minute}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	if _, err := g.Get(ctx, "k"); err == nil {
+		t.Fatal("数据源故障时加载应失败")
+	}
+
+	t.Run("其他调用方可以立即获得租约", func(t *testing.T) {
+		res, err := g.Lease(ctx, "k")
+		if err != nil || res.Status != LeaseGranted {
+			t.Fatalf("租约应已释放，实际状态为 %v: %v", res.Status, err)
+		}
+		if err := g.SetWithLease(ctx, "k", nil, res.Token); err != nil {
+			t.Fatalf("释放租约失败: %v", err)
+		}
+	})
+
+	t.Run("数据源恢复后加载成功", func(t *testing.T) {
+		failing.Store(false)
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		view, err := g.Get(ctx, "k")
+		if err != nil || view.String() != "v-k" {
+			t.Fatalf("应加载成功，实际为 %q: %v", view.String(), err)
+		}
+	})
+
+	t.Run("无效令牌不能释放租约", func(t *testing.T) {
+		res, _ := g.Lease(ctx, "other")
+		if err := g.SetWithLease(ctx, "other", nil, res.Token+1); !errors.Is(err, ErrLeaseInvalid) {
+			t.Fatalf("应返回 ErrLeaseInvalid，实际为 %v", err)
+		}
+		if err := g.SetWithLease(ctx, "other", []byte("v"), res.Token); err != nil {
+			t.Fatalf("释放失败后原租约仍应有效: %v", err)
+		}
+	})
+}
+
+// 测试过期的租约和旧值会被清理
+func TestLeaseSweep(t *testing.T) {
+	tests := []struct {
+		name     string
+		staleTTL time.Duration
+	}{
+		{"不保留旧值", 0},
+		{"保留旧值", 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newLeaseManager(LeaseOptions{TTL: 10 * time.Millisecond, StaleTTL: tt.staleTTL})
+			for _, key := range []string{"a", "b", "c"} {
+				m.acquire(key)
+				m.invalidate(key, ByteView{b: []byte("old")}, true)
+				m.acquire(key)
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			m.acquire("d")
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if len(m.leases) != 1 || len(m.stale) != 0 {
+				t.Fatalf("过期的租约和旧值应被清理，剩余 %d/%d", len(m.leases), len(m.stale))
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SuperJinggg/mycache-go/store"
+)
+
+// ErrLoaderBusy 等待加载名额超时错误
+var ErrLoaderBusy = errors.New("too many concurrent loads")
+
+// ErrRateLimited 加载超过速率限制错误
+var ErrRateLimited = errors.New("load rate limit exceeded")
+
+// WithLoaderConcurrency 限制同时从数据源加载的数量，超出的请求排队等待，
+// 等待超过 queueWait 时返回 ErrLoaderBusy，queueWait 不大于0时等待至加载超时（见 WithLoaderTimeout）
+func WithLoaderConcurrency(n int, queueWait time.Duration) GroupOption {
+	return func(g *Group) {
+		if n > 0 {
+			g.loaderSem = make(chan struct{}, n)
+		}
+		g.loaderQueueWait = queueWait
+	}
+}
+
+// WithLoaderTimeout 设置单次加载的超时时间，与调用方的上下文无关。
+// 并发请求共享的加载（包括向其他节点获取）只受该超时限制，调用方超时或取消时只停止等待
+func WithLoaderTimeout(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.loaderTimeout = d
+	}
+}
+
+// WithLoaderRateLimit 使用令牌桶限制每秒从数据源加载的次数，超过时返回 ErrRateLimited，
+// burst 不大于0时为 max(1, rate)
+func WithLoaderRateLimit(rate float64, burst int) GroupOption {
+	return func(g *Group) {
+		if rate > 0 {
+			g.loaderLimiter = newTokenBucket(rate, burst)
+		}
+	}
+}
+
+// WithLoaderBreaker 为数据源开启熔断，失败达到阈值后快速返回 ErrCircuitOpen，通常设置 FailureRate 按失败比例熔断。
+// ErrNotFound 不计为失败，取消和调用方的超时不计入统计，只有 WithLoaderTimeout 的超时计为失败
+func WithLoaderBreaker(opts BreakerOptions) GroupOption {
+	return func(g *Group) {
+		g.loaderBreaker = newCircuitBreaker(opts)
+	}
+}
+
+// WithServeStale 加载失败时返回该 key 之前的值（已过期或被淘汰）。旧值在缓存项过期或被淘汰时才保存，
+// 最多保留 staleFor，占用的内存不超过 maxBytes。ErrNotFound 和被删除或重新写入的 key 不返回旧值
+func WithServeStale(staleFor time.Duration, maxBytes int64) GroupOption {
+	return func(g *Group) {
+		if staleFor > 0 && maxBytes > 0 {
+			g.staleFor = staleFor
+			g.stale = store.NewStore(store.LRU, store.Options{
+				MaxBytes:        maxBytes,
+				CleanupInterval: time.Minute,
+			})
+		}
+	}
+}
+
+// callGetter 在加载保护下调用数据源
+func (g *Group) callGetter(ctx context.Context, key string) ([]byte, error) {
+	// 熔断时快速失败，不占用限流和并发名额
+	if !g.loaderBreaker.available() {
+		atomic.AddInt64(&g.stats.loaderRejected, 1)
+		return nil, ErrCircuitOpen
+	}
+
+	if g.loaderLimiter != nil && !g.loaderLimiter.allow() {
+		atomic.AddInt64(&g.stats.loaderRejected, 1)
+		return nil, ErrRateLimited
+	}
+
+	if g.loaderSem != nil {
+		if err := g.acquireLoader(ctx); err != nil {
+			atomic.AddInt64(&g.stats.loaderRejected, 1)
+			return nil, err
+		}
+		defer func() { <-g.loaderSem }()
+	}
+
+	if !g.loaderBreaker.allow() {
+		atomic.AddInt64(&g.stats.loaderRejected, 1)
+		return nil, ErrCircuitOpen
+	}
+
+	if g.loaderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.loaderTimeout)
+		defer cancel()
+	}
+
+	ctx, span := g.startSpan(ctx, "kamacache.loader")
+	bytes, err := g.getter.Get(ctx, key)
+	endSpan(span, err)
+
+	switch {
+	case err == nil || errors.Is(err, ErrNotFound):
+		g.loaderBreaker.onSuccess()
+	case errors.Is(err, context.Canceled):
+		g.loaderBreaker.onIgnored()
+	case errors.Is(err, context.DeadlineExceeded):
+		// 只有加载超时说明数据源变慢，数据源内部的超时不计入统计。
+		// 共享的加载已与调用方的上下文分离（见 detachContext），ctx 到期只可能是加载超时
+		if g.loaderTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			g.loaderBreaker.onFailure()
+		} else {
+			g.loaderBreaker.onIgnored()
+		}
+	default:
+		g.loaderBreaker.onFailure()
+	}
+	return bytes, err
+}
+
+// acquireLoader 获取加载名额
+func (g *Group) acquireLoader(ctx context.Context) error {
+	select {
+	case g.loaderSem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if g.loaderQueueWait > 0 {
+		timer := time.NewTimer(g.loaderQueueWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case g.loaderSem <- struct{}{}:
+		return nil
+	case <-timeout:
+		return fmt.Errorf("%w: waited %v", ErrLoaderBusy, g.loaderQueueWait)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// keepStale 缓存项过期或被淘汰时保存为旧值，供加载失败时使用，缓存中的值不会重复保存
+func (g *Group) keepStale(e store.Eviction) {
+	if e.Reason != store.EvictionExpired && e.Reason != store.EvictionCapacity {
+		return
+	}
+	if view, ok := e.Value.(ByteView); ok {
+		g.stale.SetWithExpiration(e.Key, view, g.staleFor)
+	}
+}
+
+// dropStale 删除 key 的旧值，写入新值后旧值不能再覆盖它
+func (g *Group) dropStale(key string) {
+	if g.stale != nil {
+		g.stale.Delete(key)
+	}
+}
+
+// loadStale 加载失败时返回 key 之前的值
+func (g *Group) loadStale(key string, err error) (ByteView, bool) {
+	if g.stale == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrGroupClosed) {
+		return ByteView{}, false
+	}
+	v, ok := g.stale.Get(key)
+	if !ok {
+		return ByteView{}, false
+	}
+	atomic.AddInt64(&g.stats.staleHits, 1)
+	return v.(ByteView), true
+}
+
+// tokenBucket 令牌桶限流器
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒生成的令牌数
+	burst  float64 // 令牌桶容量
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket 创建装满令牌的令牌桶
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst <= 0 {
+		b = max(1, rate)
+	}
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// allow 取出一个令牌，没有令牌时返回 false
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,227 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试加载并发限制与超时
+func TestLoaderConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	release := make(chan struct{})
+	g := NewGroup("loader-concurrency-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		select {
+		case <-release:
+			return []byte(key), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}), WithLoaderConcurrency(2, 50*time.Millisecond), WithLoaderTimeout(time.Second))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	t.Run("超出并发数的加载等待超时", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make([]error, 3)
+		for i, key := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = g.Get(ctx, key)
+			}()
+		}
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		busy := 0
+		for _, err := range errs {
+			if errors.Is(err, ErrLoaderBusy) {
+				busy++
+			}
+		}
+		if busy != 1 || atomic.LoadInt32(&peak) != 2 {
+			t.Fatalf("应有1个加载因等待超时失败且最多2个并发，实际为 %d/%d", busy, peak)
+		}
+	})
+}
+
+// 测试加载超时与限流
+func TestLoaderTimeoutAndRateLimit(t *testing.T) {
+	g := NewGroup("loader-timeout-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if key == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte(key), nil
+	}), WithLoaderTimeout(20*time.Millisecond), WithLoaderRateLimit(1, 2))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	t.Run("加载超时", func(t *testing.T) {
+		if _, err := g.Get(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("应返回超时错误，实际为 %v", err)
+		}
+	})
+
+	t.Run("超过速率限制", func(t *testing.T) {
+		if _, err := g.Get(ctx, "a"); err != nil {
+			t.Fatalf("令牌桶中仍有令牌，加载应成功: %v", err)
+		}
+		if _, err := g.Get(ctx, "b"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("应返回 ErrRateLimited，实际为 %v", err)
+		}
+	})
+}
+
+// 测试熔断与返回旧值
+func TestLoaderBreakerServeStale(t *testing.T) {
+	var failing atomic.Bool
+	var calls int32
+	g := NewGroup("loader-breaker-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		if failing.Load() {
+			return nil, errors.New("db brownout")
+		}
+		return []byte("v-" + key), nil
+	}), WithExpiration(50*time.Millisecond),
+		WithLoaderBreaker(BreakerOptions{FailureRate: 0.5, MinRequests: 4, Window: time.Minute, OpenTimeout: time.Minute}),
+		WithServeStale(time.Hour, 1<<20))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	if _, err := g.Get(ctx, "k"); err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+	if _, ok := g.stale.Get("k"); ok {
+		t.Fatal("缓存中的值不应重复保存为旧值")
+	}
+
+	// 过期后才保存为旧值，LRU2 的时钟精度为100ms
+	time.Sleep(250 * time.Millisecond)
+	failing.Store(true)
+
+	t.Run("数据源故障时返回旧值", func(t *testing.T) {
+		view, err := g.Get(ctx, "k")
+		if err != nil || view.String() != "v-k" {
+			t.Fatalf("应返回旧值 v-k，实际为 %q, %v", view.String(), err)
+		}
+	})
+
+	t.Run("失败比例达到阈值后熔断", func(t *testing.T) {
+		g.Get(ctx, "other")
+		if g.loaderBreaker.isOpen() {
+			t.Fatal("请求数不足时不应熔断")
+		}
+		g.Get(ctx, "other")
+		before := atomic.LoadInt32(&calls)
+		if _, err := g.Get(ctx, "other"); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("熔断后应返回 ErrCircuitOpen，实际为 %v", err)
+		}
+		if atomic.LoadInt32(&calls) != before {
+			t.Fatal("熔断后不应调用数据源")
+		}
+		if view, err := g.Get(ctx, "k"); err != nil || view.String() != "v-k" {
+			t.Fatalf("熔断时应返回旧值，实际为 %q, %v", view.String(), err)
+		}
+	})
+
+	t.Run("写入后不返回更早的旧值", func(t *testing.T) {
+		g.Set(ctx, "k", []byte("new"))
+		g.mainCache.Delete("k")
+		if _, err := g.Get(ctx, "k"); err == nil {
+			t.Fatal("写入后不应返回写入前的旧值")
+		}
+	})
+
+	t.Run("删除后不返回旧值", func(t *testing.T) {
+		g.Delete(ctx, "k")
+		if _, err := g.Get(ctx, "k"); err == nil {
+			t.Fatal("被删除的 key 不应返回旧值")
+		}
+	})
+}
+
+// 测试共享的加载不继承第一个调用方的截止时间
+func TestLoaderDetachedFromCaller(t *testing.T) {
+	release := make(chan struct{})
+	g := NewGroup("loader-detach-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		select {
+		case <-release:
+			return []byte("v-" + key), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}), WithLoaderBreaker(BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute}))
+	t.Cleanup(func() { g.Close() })
+
+	// 第一个调用方很快超时
+	short, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.Get(short, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("第一个调用方应超时，实际为 %v", err)
+	}
+
+	// 第二个调用方等待同一次加载完成
+	done := make(chan error, 1)
+	go func() {
+		view, err := g.Get(context.Background(), "k")
+		if err == nil && view.String() != "v-k" {
+			err = errors.New("unexpected value " + view.String())
+		}
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("共享的加载不应因第一个调用方超时而失败: %v", err)
+	}
+	if g.loaderBreaker.isOpen() {
+		t.Fatal("调用方超时不应计为数据源失败")
+	}
+}
+
+// 测试熔断器只统计数据源自身的失败和加载超时
+func TestLoaderBreakerOutcomes(t *testing.T) {
+	tests := []struct {
+		name     string
+		getter   func(ctx context.Context) error
+		wantOpen bool
+	}{
+		{"取消不计为失败", func(ctx context.Context) error { return context.Canceled }, false},
+		{"数据源内部的超时不计为失败", func(ctx context.Context) error { return context.DeadlineExceeded }, false},
+		{"加载超时计为失败", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, true},
+		{"数据源错误计为失败", func(ctx context.Context) error { return errors.New("db down") }, true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGroup(fmt.Sprintf("loader-outcome-test-%d", i), 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+				return nil, tt.getter(ctx)
+			}), WithLoaderTimeout(20*time.Millisecond), WithLoaderBreaker(BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute}))
+			t.Cleanup(func() { g.Close() })
+
+			g.Get(context.Background(), "k")
+			if got := g.loaderBreaker.isOpen(); got != tt.wantOpen {
+				t.Fatalf("熔断器是否打开应为 %v，实际为 %v", tt.wantOpen, got)
+			}
+		})
+	}
+}
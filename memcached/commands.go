@@ -0,0 +1,772 @@
+package memcached
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	cache "github.com/SuperJinggg/mycache-go"
+	"github.com/sirupsen/logrus"
+)
+
+// version version 命令和 stats 中报告的版本号
+const version = "1.6.0-mycache"
+
+// commands 支持的命令，返回的错误表示连接已无法继续解析
+var commands = map[string]func(c *conn, args []string) error{
+	"get":       cmdGet,
+	"gets":      cmdGet,
+	"set":       cmdStore,
+	"add":       cmdStore,
+	"replace":   cmdStore,
+	"cas":       cmdStore,
+	"append":    cmdStore,
+	"prepend":   cmdStore,
+	"delete":    cmdDelete,
+	"incr":      cmdIncr,
+	"decr":      cmdIncr,
+	"touch":     cmdTouch,
+	"stats":     cmdStats,
+	"version":   cmdVersion,
+	"verbosity": cmdVerbosity,
+	"quit":      cmdQuit,
+	"mg":        cmdMetaGet,
+	"ms":        cmdMetaSet,
+	"md":        cmdMetaDelete,
+	"mn":        cmdMetaNoop,
+}
+
+// storeMode 写入模式，与 meta 协议 ms 命令的 M 标志一致
+type storeMode byte
+
+const (
+	modeSet     storeMode = 'S'
+	modeAdd     storeMode = 'E'
+	modeReplace storeMode = 'R'
+	modeCAS     storeMode = 'C'
+)
+
+// storeResult 写入结果
+type storeResult int
+
+const (
+	resultStored storeResult = iota
+	resultNotStored
+	resultExists
+	resultNotFound
+)
+
+// textReplies 写入结果在文本协议中的回复
+var textReplies = map[storeResult]string{
+	resultStored:    "STORED",
+	resultNotStored: "NOT_STORED",
+	resultExists:    "EXISTS",
+	resultNotFound:  "NOT_FOUND",
+}
+
+// metaReplies 写入结果在 meta 协议中的回复
+var metaReplies = map[storeResult]string{
+	resultStored:    "HD",
+	resultNotStored: "NS",
+	resultExists:    "EX",
+	resultNotFound:  "NF",
+}
+
+// dispatch 执行一条命令
+func (c *conn) dispatch(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil
+	}
+	handler, ok := commands[args[0]]
+	if !ok {
+		c.reply("ERROR")
+		return nil
+	}
+	return handler(c, args)
+}
+
+func (c *conn) reply(s string) {
+	c.w.WriteString(s + "\r\n")
+}
+
+func (c *conn) clientError(msg string) {
+	c.reply("CLIENT_ERROR " + msg)
+}
+
+func (c *conn) serverError(msg string) {
+	c.reply("SERVER_ERROR " + strings.ReplaceAll(msg, "\r\n", " "))
+}
+
+// resolve 根据键前缀或默认组确定组和组内的键
+func (c *conn) resolve(key string) (*cache.Group, string, error) {
+	if sep := c.srv.opts.keyPrefixSep; sep != "" {
+		if prefix, rest, ok := strings.Cut(key, sep); ok {
+			if g := c.srv.opts.node.GetGroup(prefix); g != nil {
+				return g, rest, nil
+			}
+		}
+	}
+
+	g := c.srv.opts.node.GetGroup(c.srv.group)
+	if g == nil {
+		return nil, "", fmt.Errorf("group %s not found", c.srv.group)
+	}
+	return g, key, nil
+}
+
+// noreply 判断命令是否以 noreply 结尾
+func noreply(args []string, n int) bool {
+	return len(args) == n+1 && args[n] == "noreply"
+}
+
+// cmdGet get/gets <key>*，未命中时通过 Getter 加载，gets 额外返回 CAS 标识
+func cmdGet(c *conn, args []string) error {
+	if len(args) < 2 {
+		c.reply("ERROR")
+		return nil
+	}
+
+	type target struct {
+		name string
+		g    *cache.Group
+		key  string
+	}
+
+	// 先检查所有键，避免写入一半的回复
+	targets := make([]target, 0, len(args)-1)
+	for _, name := range args[1:] {
+		if !validKey(name) {
+			c.clientError("bad command line format")
+			return nil
+		}
+		g, key, err := c.resolve(name)
+		if err != nil {
+			c.serverError(err.Error())
+			return nil
+		}
+		targets = append(targets, target{name, g, key})
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	withCAS := args[0] == "gets"
+	for _, t := range targets {
+		atomic.AddInt64(&c.srv.stats.cmdGet, 1)
+		view, cas, err := c.get(ctx, t.g, t.key, withCAS)
+		if err != nil {
+			atomic.AddInt64(&c.srv.stats.getMisses, 1)
+			if !errors.Is(err, cache.ErrNotFound) {
+				logrus.Debugf("[KamaCache] memcached get %s failed: %v", t.name, err)
+			}
+			continue
+		}
+		atomic.AddInt64(&c.srv.stats.getHits, 1)
+
+		data, flags := c.srv.decodeView(view)
+		fmt.Fprintf(c.w, "VALUE %s %d %d", t.name, flags, data.Len())
+		if withCAS {
+			fmt.Fprintf(c.w, " %d", cas)
+		}
+		c.w.WriteString("\r\n")
+		data.WriteTo(c.w)
+		c.w.WriteString("\r\n")
+	}
+	c.reply("END")
+	return nil
+}
+
+// get 读取键，withCAS 为 true 时从所有者节点读取值的版本号作为 CAS 标识
+func (c *conn) get(ctx context.Context, g *cache.Group, key string, withCAS bool) (cache.ByteView, uint64, error) {
+	if withCAS {
+		return g.GetWithVersion(ctx, key)
+	}
+	view, err := g.Get(ctx, key)
+	return view, 0, err
+}
+
+// cmdStore set/add/replace/append/prepend <key> <flags> <exptime> <bytes> [noreply]
+// 以及 cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+func cmdStore(c *conn, args []string) error {
+	n := 5
+	if args[0] == "cas" {
+		n = 6
+	}
+	if len(args) != n && !noreply(args, n) {
+		c.clientError("bad command line format")
+		return nil
+	}
+
+	size, err := strconv.Atoi(args[4])
+	if err != nil || size < 0 {
+		c.clientError("bad command line format")
+		return nil
+	}
+	flags, flagsErr := strconv.ParseUint(args[2], 10, 32)
+	ttl, expired, ok := parseExptime(args[3])
+	var cas uint64
+	if args[0] == "cas" {
+		cas, err = strconv.ParseUint(args[5], 10, 64)
+	}
+
+	// 无论参数是否有效都要读掉数据块
+	if size > c.srv.opts.maxItemSize {
+		if _, err := c.r.Discard(size + 2); err != nil {
+			return err
+		}
+		c.serverError("object too large for cache")
+		return nil
+	}
+	data, terminated, readErr := readData(c.r, size)
+	if readErr != nil {
+		return readErr
+	}
+	if !terminated {
+		c.clientError("bad data chunk")
+		return nil
+	}
+	if flagsErr != nil || !ok || err != nil || !validKey(args[1]) {
+		c.clientError("bad command line format")
+		return nil
+	}
+
+	var mode storeMode
+	switch args[0] {
+	case "set":
+		mode = modeSet
+	case "add":
+		mode = modeAdd
+	case "replace":
+		mode = modeReplace
+	case "cas":
+		mode = modeCAS
+	default:
+		c.serverError(args[0] + " is not supported")
+		return nil
+	}
+
+	g, key, err := c.resolve(args[1])
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	result, err := c.store(ctx, g, key, data, uint32(flags), ttl, expired, mode, cas)
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+	if !noreply(args, n) {
+		c.reply(textReplies[result])
+	}
+	return nil
+}
+
+// conditions add、replace 和 cas 对应的条件写入
+var conditions = map[storeMode]cache.WriteCondition{
+	modeAdd:     cache.IfAbsent,
+	modeReplace: cache.IfPresent,
+	modeCAS:     cache.IfVersion,
+}
+
+// store 按模式写入值。add、replace 和 cas 由所有者节点原子地检查条件并写入，cas 比较的是所有者分配的版本号
+func (c *conn) store(ctx context.Context, g *cache.Group, key string, data []byte, flags uint32,
+	ttl time.Duration, expired bool, mode storeMode, cas uint64) (storeResult, error) {
+	atomic.AddInt64(&c.srv.stats.cmdSet, 1)
+
+	value := c.srv.encodeValue(data, flags)
+	if mode == modeSet {
+		// 过期时间已过的写入等同于删除
+		if expired {
+			return resultStored, g.Delete(ctx, key)
+		}
+		return resultStored, g.SetWithTTL(ctx, key, value, ttl)
+	}
+
+	_, err := g.SetIf(ctx, key, value, ttl, conditions[mode], cas)
+	switch {
+	case errors.Is(err, cache.ErrKeyExists):
+		return resultNotStored, nil
+	case errors.Is(err, cache.ErrNotFound):
+		if mode == modeCAS {
+			return resultNotFound, nil
+		}
+		return resultNotStored, nil
+	case errors.Is(err, cache.ErrVersionMismatch):
+		return resultExists, nil
+	case err != nil:
+		return 0, err
+	}
+
+	// 过期时间已过的条件写入在条件成立后删除
+	if expired {
+		return resultStored, g.Delete(ctx, key)
+	}
+	return resultStored, nil
+}
+
+// cmdDelete delete <key> [0] [noreply]，Group 不区分键是否存在，总是返回 DELETED
+func cmdDelete(c *conn, args []string) error {
+	if len(args) < 2 {
+		c.reply("ERROR")
+		return nil
+	}
+	quiet := len(args) > 2 && args[len(args)-1] == "noreply"
+	rest := args[2:]
+	if quiet {
+		rest = rest[:len(rest)-1]
+	}
+	if len(rest) > 1 || (len(rest) == 1 && rest[0] != "0") || !validKey(args[1]) {
+		c.clientError("bad command line format.  Usage: delete <key> [noreply]")
+		return nil
+	}
+
+	g, key, err := c.resolve(args[1])
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if err := g.Delete(ctx, key); err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+	if !quiet {
+		c.reply("DELETED")
+	}
+	return nil
+}
+
+// cmdIncr incr/decr <key> <value> [noreply]，在所有者节点上原子执行
+func cmdIncr(c *conn, args []string) error {
+	if (len(args) != 3 && !noreply(args, 3)) || !validKey(args[1]) {
+		c.reply("ERROR")
+		return nil
+	}
+	delta, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil || delta > math.MaxInt64 {
+		c.clientError("invalid numeric delta argument")
+		return nil
+	}
+
+	g, key, err := c.resolve(args[1])
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	// memcached 中不存在的键返回 NOT_FOUND，计数器不会小于 0，
+	// 存在性检查、截断和写入由所有者节点在同一次操作中完成
+	floor := int64(math.MinInt64)
+	d := int64(delta)
+	if args[0] == "decr" {
+		floor, d = 0, -d
+	}
+	value, err := g.IncrIfExists(ctx, key, d, floor)
+	if errors.Is(err, cache.ErrNotFound) {
+		if !noreply(args, 3) {
+			c.reply("NOT_FOUND")
+		}
+		return nil
+	}
+	if err != nil {
+		if errors.Is(err, cache.ErrNotInteger) {
+			c.clientError("cannot increment or decrement non-numeric value")
+			return nil
+		}
+		c.serverError(err.Error())
+		return nil
+	}
+	if !noreply(args, 3) {
+		c.reply(strconv.FormatInt(value, 10))
+	}
+	return nil
+}
+
+// cmdTouch touch <key> <exptime> [noreply]，重新写入当前值以更新过期时间
+func cmdTouch(c *conn, args []string) error {
+	if (len(args) != 3 && !noreply(args, 3)) || !validKey(args[1]) {
+		c.reply("ERROR")
+		return nil
+	}
+	ttl, expired, ok := parseExptime(args[2])
+	if !ok {
+		c.clientError("invalid exptime argument")
+		return nil
+	}
+
+	g, key, err := c.resolve(args[1])
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	atomic.AddInt64(&c.srv.stats.cmdTouch, 1)
+	found, err := c.touch(ctx, g, key, ttl, expired)
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+	if noreply(args, 3) {
+		return nil
+	}
+	if found {
+		c.reply("TOUCHED")
+	} else {
+		c.reply("NOT_FOUND")
+	}
+	return nil
+}
+
+// touch 更新键的过期时间，键不存在时返回 false
+func (c *conn) touch(ctx context.Context, g *cache.Group, key string, ttl time.Duration, expired bool) (bool, error) {
+	view, err := g.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if expired {
+		return true, g.Delete(ctx, key)
+	}
+	return true, g.SetWithTTL(ctx, key, view.ByteSLice(), ttl)
+}
+
+// cmdStats stats，统计信息包括本服务器的计数和默认组的 Group.Stats，不支持子命令
+func cmdStats(c *conn, args []string) error {
+	if len(args) > 1 {
+		c.reply("END")
+		return nil
+	}
+
+	s := c.srv
+	s.mu.Lock()
+	currConns := len(s.conns)
+	s.mu.Unlock()
+
+	stat := func(name string, value any) {
+		fmt.Fprintf(c.w, "STAT %s %v\r\n", name, value)
+	}
+	stat("pid", os.Getpid())
+	stat("uptime", int64(time.Since(s.started).Seconds()))
+	stat("time", time.Now().Unix())
+	stat("version", version)
+	stat("curr_connections", currConns)
+	stat("total_connections", atomic.LoadInt64(&s.stats.totalConns))
+	stat("cmd_get", atomic.LoadInt64(&s.stats.cmdGet))
+	stat("cmd_set", atomic.LoadInt64(&s.stats.cmdSet))
+	stat("cmd_touch", atomic.LoadInt64(&s.stats.cmdTouch))
+	stat("get_hits", atomic.LoadInt64(&s.stats.getHits))
+	stat("get_misses", atomic.LoadInt64(&s.stats.getMisses))
+
+	if g := s.opts.node.GetGroup(s.group); g != nil {
+		gs := g.Stats()
+		keys := make([]string, 0, len(gs))
+		for k := range gs {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			stat("group_"+k, gs[k])
+		}
+	}
+	c.reply("END")
+	return nil
+}
+
+func cmdVersion(c *conn, args []string) error {
+	c.reply("VERSION " + version)
+	return nil
+}
+
+func cmdVerbosity(c *conn, args []string) error {
+	if !noreply(args, len(args)-1) {
+		c.reply("OK")
+	}
+	return nil
+}
+
+func cmdQuit(c *conn, args []string) error {
+	c.quit = true
+	return nil
+}
+
+// metaFlags 解析 meta 命令的标志，每个标志为单个字符加可选的参数
+type metaFlags map[byte]string
+
+func parseMetaFlags(tokens []string) metaFlags {
+	flags := make(metaFlags, len(tokens))
+	for _, t := range tokens {
+		flags[t[0]] = t[1:]
+	}
+	return flags
+}
+
+func (f metaFlags) has(flag byte) bool {
+	_, ok := f[flag]
+	return ok
+}
+
+// metaReply 写入 meta 命令的回复，ret 为需要回显的标志
+func (c *conn) metaReply(code string, ret []string) {
+	if len(ret) > 0 {
+		code += " " + strings.Join(ret, " ")
+	}
+	c.reply(code)
+}
+
+// echoFlags 返回需要原样回显的 O（opaque）和 k（键）标志
+func echoFlags(key string, tokens []string) []string {
+	var ret []string
+	for _, t := range tokens {
+		switch t[0] {
+		case 'O':
+			ret = append(ret, t)
+		case 'k':
+			ret = append(ret, "k"+key)
+		}
+	}
+	return ret
+}
+
+// cmdMetaGet mg <key> <flags>*，支持 v、k、s、f、c、t、T、O、q 标志
+func cmdMetaGet(c *conn, args []string) error {
+	if len(args) < 2 || !validKey(args[1]) {
+		c.clientError("bad command line format")
+		return nil
+	}
+	name, tokens := args[1], args[2:]
+	flags := parseMetaFlags(tokens)
+
+	g, key, err := c.resolve(name)
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	atomic.AddInt64(&c.srv.stats.cmdGet, 1)
+	withCAS := flags.has('c')
+	view, cas, err := c.get(ctx, g, key, withCAS)
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotFound) {
+			c.serverError(err.Error())
+			return nil
+		}
+		atomic.AddInt64(&c.srv.stats.getMisses, 1)
+		if !flags.has('q') {
+			c.metaReply("EN", nil)
+		}
+		return nil
+	}
+	atomic.AddInt64(&c.srv.stats.getHits, 1)
+
+	if t, ok := flags['T']; ok {
+		ttl, expired, ok := parseExptime(t)
+		if !ok {
+			c.clientError("bad token in command line format")
+			return nil
+		}
+		atomic.AddInt64(&c.srv.stats.cmdTouch, 1)
+		switch {
+		case expired:
+			err = g.Delete(ctx, key)
+		case withCAS:
+			// 重新写入后版本号改变，返回新值的版本号
+			cas, err = g.SetIf(ctx, key, view.ByteSLice(), ttl, cache.IfPresent, 0)
+		default:
+			err = g.SetWithTTL(ctx, key, view.ByteSLice(), ttl)
+		}
+		if err != nil {
+			c.serverError(err.Error())
+			return nil
+		}
+	}
+
+	data, itemFlags := c.srv.decodeView(view)
+	var ret []string
+	for _, t := range tokens {
+		switch t[0] {
+		case 'k':
+			ret = append(ret, "k"+name)
+		case 'O':
+			ret = append(ret, t)
+		case 's':
+			ret = append(ret, "s"+strconv.Itoa(data.Len()))
+		case 'f':
+			ret = append(ret, "f"+strconv.FormatUint(uint64(itemFlags), 10))
+		case 'c':
+			ret = append(ret, "c"+strconv.FormatUint(cas, 10))
+		case 't':
+			// 剩余时间来自本节点的缓存，-1 表示永不过期
+			remaining := int64(-1)
+			if ttl, ok := g.TTL(key); ok && ttl > 0 {
+				remaining = int64((ttl + time.Second - 1) / time.Second)
+			}
+			ret = append(ret, "t"+strconv.FormatInt(remaining, 10))
+		}
+	}
+
+	if !flags.has('v') {
+		c.metaReply("HD", ret)
+		return nil
+	}
+	c.metaReply("VA "+strconv.Itoa(data.Len()), ret)
+	data.WriteTo(c.w)
+	c.w.WriteString("\r\n")
+	return nil
+}
+
+// cmdMetaSet ms <key> <datalen> <flags>*，支持 T、F、C、M（S/E/R）、O、k、q 标志
+func cmdMetaSet(c *conn, args []string) error {
+	if len(args) < 3 {
+		c.clientError("bad command line format")
+		return nil
+	}
+	size, err := strconv.Atoi(args[2])
+	if err != nil || size < 0 {
+		c.clientError("bad data chunk")
+		return nil
+	}
+
+	if size > c.srv.opts.maxItemSize {
+		if _, err := c.r.Discard(size + 2); err != nil {
+			return err
+		}
+		c.serverError("object too large for cache")
+		return nil
+	}
+	data, terminated, err := readData(c.r, size)
+	if err != nil {
+		return err
+	}
+	if !terminated {
+		c.clientError("bad data chunk")
+		return nil
+	}
+
+	name, tokens := args[1], args[3:]
+	if !validKey(name) {
+		c.clientError("bad command line format")
+		return nil
+	}
+	flags := parseMetaFlags(tokens)
+
+	mode := modeSet
+	if m, ok := flags['M']; ok {
+		switch strings.ToUpper(m) {
+		case "S":
+		case "E":
+			mode = modeAdd
+		case "R":
+			mode = modeReplace
+		case "A", "P":
+			c.clientError("append and prepend are not supported")
+			return nil
+		default:
+			c.clientError("invalid mode for ms")
+			return nil
+		}
+	}
+
+	var ttl time.Duration
+	var expired bool
+	if t, ok := flags['T']; ok {
+		var valid bool
+		if ttl, expired, valid = parseExptime(t); !valid {
+			c.clientError("bad token in command line format")
+			return nil
+		}
+	}
+	var itemFlags uint64
+	if f, ok := flags['F']; ok {
+		if itemFlags, err = strconv.ParseUint(f, 10, 32); err != nil {
+			c.clientError("bad token in command line format")
+			return nil
+		}
+	}
+	var cas uint64
+	if cs, ok := flags['C']; ok {
+		if cas, err = strconv.ParseUint(cs, 10, 64); err != nil {
+			c.clientError("bad token in command line format")
+			return nil
+		}
+		mode = modeCAS
+	}
+
+	g, key, err := c.resolve(name)
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	result, err := c.store(ctx, g, key, data, uint32(itemFlags), ttl, expired, mode, cas)
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+	if result == resultStored && flags.has('q') {
+		return nil
+	}
+	c.metaReply(metaReplies[result], echoFlags(name, tokens))
+	return nil
+}
+
+// cmdMetaDelete md <key> <flags>*，支持 O、k、q 标志
+func cmdMetaDelete(c *conn, args []string) error {
+	if len(args) < 2 || !validKey(args[1]) {
+		c.clientError("bad command line format")
+		return nil
+	}
+	name, tokens := args[1], args[2:]
+
+	g, key, err := c.resolve(name)
+	if err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if err := g.Delete(ctx, key); err != nil {
+		c.serverError(err.Error())
+		return nil
+	}
+	if !parseMetaFlags(tokens).has('q') {
+		c.metaReply("HD", echoFlags(name, tokens))
+	}
+	return nil
+}
+
+// cmdMetaNoop mn，配合 q 标志使用，表示之前的流水线命令已处理完毕
+func cmdMetaNoop(c *conn, args []string) error {
+	c.reply("MN")
+	return nil
+}
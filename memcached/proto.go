@@ -0,0 +1,134 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	cache "github.com/SuperJinggg/mycache-go"
+)
+
+const (
+	maxLineLength = 64 * 1024 // 命令行的最大长度，multi-get 可能包含很多键
+	maxKeyLength  = 250       // memcached 协议规定的键的最大长度
+	relativeLimit = 30 * 24 * 60 * 60
+)
+
+// errLineTooLong 命令行超过最大长度
+var errLineTooLong = errors.New("line too long")
+
+// flagsMagic 保存标志位时值的头部，后面跟 4 字节的大端标志位
+var flagsMagic = []byte("\x00mcf")
+
+// readLine 读取一行并去掉结尾的 \r\n
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxLineLength {
+			return "", errLineTooLong
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// readData 读取 n 字节的数据块及结尾的 \r\n，数据块长度不符时丢弃该行剩余的内容
+func readData(r *bufio.Reader, n int) ([]byte, bool, error) {
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, false, err
+	}
+	if buf[n] == '\r' && buf[n+1] == '\n' {
+		return buf[:n], true, nil
+	}
+	if buf[n+1] != '\n' {
+		if _, err := readLine(r); err != nil {
+			return nil, false, err
+		}
+	}
+	return nil, false, nil
+}
+
+// validKey 检查键的长度并确保不包含控制字符
+func validKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// parseExptime 按 memcached 的规则解析过期时间：0 表示使用组的默认过期时间，
+// 负数表示立即过期，超过 30 天时视为 Unix 时间戳
+func parseExptime(s string) (ttl time.Duration, expired bool, ok bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false, false
+	}
+	switch {
+	case n < 0:
+		return 0, true, true
+	case n == 0:
+		return 0, false, true
+	case n > relativeLimit:
+		ttl = time.Until(time.Unix(n, 0))
+		return ttl, ttl <= 0, true
+	default:
+		return time.Duration(n) * time.Second, false, true
+	}
+}
+
+// encodeValue 按需在值前加上标志位，标志位为 0 时保持原值以便其他协议读取。
+// Group 不接受空值，空值总是加上头部保存
+func (s *Server) encodeValue(data []byte, flags uint32) []byte {
+	if !s.opts.storeFlags {
+		if len(data) > 0 {
+			return data
+		}
+		flags = 0
+	} else if flags == 0 && len(data) > 0 {
+		return data
+	}
+	b := make([]byte, len(flagsMagic)+4+len(data))
+	copy(b, flagsMagic)
+	binary.BigEndian.PutUint32(b[len(flagsMagic):], flags)
+	copy(b[len(flagsMagic)+4:], data)
+	return b
+}
+
+// hasHeader 判断值是否带有 encodeValue 加上的头部，不保存标志位时只有空值带有头部
+func (s *Server) hasHeader(n int, header []byte) bool {
+	size := len(flagsMagic) + 4
+	if n < size || (!s.opts.storeFlags && n != size) {
+		return false
+	}
+	return bytes.HasPrefix(header, flagsMagic)
+}
+
+// decodeView 拆分出标志位和客户端写入的数据，直接在视图上拆分，只复制头部而不复制数据
+func (s *Server) decodeView(v cache.ByteView) (cache.ByteView, uint32) {
+	n := len(flagsMagic) + 4
+	if v.Len() < n {
+		return v, 0
+	}
+	header := make([]byte, n)
+	v.Copy(header)
+	if !s.hasHeader(v.Len(), header) {
+		return v, 0
+	}
+	return v.Slice(n, v.Len()), binary.BigEndian.Uint32(header[len(flagsMagic):])
+}
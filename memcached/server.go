@@ -0,0 +1,272 @@
+// Package memcached 实现 memcached 文本协议和 meta 协议前端，现有的 memcached 客户端可以直接访问 mycache 集群
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cache "github.com/SuperJinggg/mycache-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Server memcached 协议服务器，命令会转换为默认 Group 的操作，由 Group 负责路由到所有者节点
+type Server struct {
+	addr  string
+	group string
+	opts  options
+
+	mu      sync.Mutex
+	ln      net.Listener
+	conns   map[*conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+	started time.Time
+
+	stats serverStats
+}
+
+// serverStats stats 命令输出的计数
+type serverStats struct {
+	totalConns int64
+	cmdGet     int64
+	cmdSet     int64
+	cmdTouch   int64
+	getHits    int64
+	getMisses  int64
+}
+
+// options 服务器配置
+type options struct {
+	keyPrefixSep   string        // 通过 "组名<分隔符>键" 选择组，为空表示不启用
+	storeFlags     bool          // 是否保存客户端标志位
+	commandTimeout time.Duration // 单条命令的超时时间
+	maxItemSize    int           // 单个值的最大长度
+	idleTimeout    time.Duration // 连接空闲超时时间，0表示不超时
+	node           *cache.Node   // 提供组的节点
+}
+
+// Option 定义服务器的配置选项
+type Option func(*options)
+
+// WithKeyPrefix 通过键前缀选择组，例如分隔符为 ":" 时 "users:42" 访问 users 组的 42，
+// 前缀不是已存在的组时使用默认组
+func WithKeyPrefix(sep string) Option {
+	return func(o *options) {
+		o.keyPrefixSep = sep
+	}
+}
+
+// WithFlags 保存客户端设置的标志位（许多客户端用它标记序列化和压缩方式）
+// 标志位非零的值会加上 8 字节的头部，这些键不能再被其他协议直接读取
+func WithFlags() Option {
+	return func(o *options) {
+		o.storeFlags = true
+	}
+}
+
+// WithCommandTimeout 设置单条命令的超时时间
+func WithCommandTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.commandTimeout = d
+	}
+}
+
+// WithMaxItemSize 设置单个值的最大长度
+func WithMaxItemSize(n int) Option {
+	return func(o *options) {
+		o.maxItemSize = n
+	}
+}
+
+// WithIdleTimeout 设置连接空闲超时时间
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithNode 访问 node 上的组，默认为 cache.DefaultNode
+func WithNode(node *cache.Node) Option {
+	return func(o *options) {
+		o.node = node
+	}
+}
+
+// NewServer 创建 memcached 协议服务器，group 为默认组
+func NewServer(addr, group string, opts ...Option) *Server {
+	o := options{
+		commandTimeout: 5 * time.Second,
+		maxItemSize:    1 << 20, // 1MB，与 memcached 默认值一致
+		node:           cache.DefaultNode(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Server{
+		addr:  addr,
+		group: group,
+		opts:  o,
+		conns: make(map[*conn]struct{}),
+	}
+}
+
+// ListenAndServe 监听地址并处理连接，Close 后返回 nil
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	return s.Serve(ln)
+}
+
+// Serve 在已有的监听器上处理连接，Close 后返回 nil
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return nil
+	}
+	s.ln = ln
+	s.started = time.Now()
+	s.mu.Unlock()
+
+	logrus.Infof("[KamaCache] memcached server listening at %s", ln.Addr())
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		c := &conn{
+			srv: s,
+			nc:  nc,
+			r:   bufio.NewReader(nc),
+			w:   bufio.NewWriter(nc),
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			nc.Close()
+			return nil
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		atomic.AddInt64(&s.stats.totalConns, 1)
+
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Addr 返回实际监听的地址，未开始监听时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close 停止监听并关闭所有连接
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// conn 一个客户端连接
+type conn struct {
+	srv  *Server
+	nc   net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	quit bool
+}
+
+// serve 循环读取并执行命令，缓冲区中没有更多命令时才写回，以支持流水线和 multi-get
+func (c *conn) serve() {
+	defer c.nc.Close()
+
+	for !c.quit {
+		if c.srv.opts.idleTimeout > 0 {
+			c.nc.SetReadDeadline(time.Now().Add(c.srv.opts.idleTimeout))
+		}
+
+		line, err := readLine(c.r)
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				c.clientError("line too long")
+				c.w.Flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logrus.Debugf("[KamaCache] memcached connection %s closed: %v", c.nc.RemoteAddr(), err)
+			}
+			return
+		}
+		if line == "" {
+			continue
+		}
+
+		if err := c.dispatch(line); err != nil {
+			// 数据块读取失败，连接已无法继续解析
+			c.w.Flush()
+			return
+		}
+
+		if c.r.Buffered() == 0 || c.quit {
+			if err := c.w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// context 为单条命令创建上下文
+func (c *conn) context() (context.Context, context.CancelFunc) {
+	if c.srv.opts.commandTimeout > 0 {
+		return context.WithTimeout(context.Background(), c.srv.opts.commandTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
@@ -0,0 +1,171 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cache "github.com/SuperJinggg/mycache-go"
+)
+
+// testClient 发送原始命令并读取指定行数的回复
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// do 发送命令并读取 lines 行回复，多行回复以 | 连接
+func (c *testClient) do(cmd string, lines int) string {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(cmd)); err != nil {
+		c.t.Fatalf("发送命令失败: %v", err)
+	}
+	got := make([]string, lines)
+	for i := range got {
+		line, err := readLine(c.r)
+		if err != nil {
+			c.t.Fatalf("读取回复失败: %v", err)
+		}
+		got[i] = line
+	}
+	return strings.Join(got, "|")
+}
+
+// startServer 启动测试服务器并返回已连接的客户端
+func startServer(t *testing.T, group string, opts ...Option) *testClient {
+	t.Helper()
+	srv := NewServer("127.0.0.1:0", group, opts...)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func newTestGroup(t *testing.T, name string) *cache.Group {
+	t.Helper()
+	g := cache.NewGroup(name, 1<<20, cache.GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if strings.HasPrefix(key, "db-") {
+			return []byte("loaded-" + key), nil
+		}
+		return nil, cache.ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+// 测试文本协议命令
+func TestTextCommands(t *testing.T) {
+	newTestGroup(t, "mc-text")
+	c := startServer(t, "mc-text", WithFlags())
+
+	cases := []struct {
+		cmd   string
+		lines int
+		want  string
+	}{
+		{"get missing\r\n", 1, "END"},
+		{"get db-1\r\n", 3, "VALUE db-1 0 11|loaded-db-1|END"},
+		{"set k1 42 0 2\r\nv1\r\n", 1, "STORED"},
+		{"get k1 missing db-2\r\n", 5, "VALUE k1 42 2|v1|VALUE db-2 0 11|loaded-db-2|END"},
+		{"add k1 0 0 1\r\nx\r\n", 1, "NOT_STORED"},
+		{"replace nope 0 0 1\r\nx\r\n", 1, "NOT_STORED"},
+		{"replace k1 0 0 2\r\nv2\r\n", 1, "STORED"},
+		{"set n 0 0 2 noreply\r\n10\r\nincr n 5\r\n", 1, "15"},
+		{"decr n 100\r\n", 1, "0"},
+		{"incr missing 1\r\n", 1, "NOT_FOUND"},
+		{"incr k1 1\r\n", 1, "CLIENT_ERROR cannot increment or decrement non-numeric value"},
+		{"set empty 0 0 0\r\n\r\n", 1, "STORED"},
+		{"get empty\r\n", 3, "VALUE empty 0 0||END"},
+		{"touch k1 100\r\n", 1, "TOUCHED"},
+		{"touch nope 100\r\n", 1, "NOT_FOUND"},
+		{"delete k1\r\n", 1, "DELETED"},
+		{"get k1\r\n", 1, "END"},
+		{"set big 0 0 2\r\ntoolong\r\n", 1, "CLIENT_ERROR bad data chunk"},
+		{"append k1 0 0 1\r\nx\r\n", 1, "SERVER_ERROR append is not supported"},
+		{"bogus\r\n", 1, "ERROR"},
+		{"version\r\n", 1, "VERSION " + version},
+	}
+	for _, tc := range cases {
+		if got := c.do(tc.cmd, tc.lines); got != tc.want {
+			t.Fatalf("%q 应返回 %q，实际为 %q", tc.cmd, tc.want, got)
+		}
+	}
+
+	t.Run("gets 和 cas", func(t *testing.T) {
+		c.do("set c 0 0 1\r\na\r\n", 1)
+		fields := strings.Fields(strings.Split(c.do("gets c\r\n", 3), "|")[0])
+		if len(fields) != 5 {
+			t.Fatalf("gets 应返回 CAS 标识，实际为 %v", fields)
+		}
+		if got := c.do("cas c 0 0 1 1\r\nb\r\n", 1); got != "EXISTS" {
+			t.Fatalf("CAS 标识不匹配时应返回 EXISTS，实际为 %q", got)
+		}
+		if got := c.do("cas c 0 0 1 "+fields[4]+"\r\nb\r\n", 1); got != "STORED" {
+			t.Fatalf("CAS 标识匹配时应写入成功，实际为 %q", got)
+		}
+	})
+}
+
+// 测试 meta 协议命令
+func TestMetaCommands(t *testing.T) {
+	newTestGroup(t, "mc-meta")
+	c := startServer(t, "mc-meta", WithFlags())
+
+	cases := []struct {
+		cmd   string
+		lines int
+		want  string
+	}{
+		{"ms k1 2 T100 F7\r\nv1\r\n", 1, "HD"},
+		{"mg k1 v f t k Oabc\r\n", 2, "VA 2 f7 t100 kk1 Oabc|v1"},
+		{"mg k1 s\r\n", 1, "HD s2"},
+		{"ms e 0 F3\r\n\r\n", 1, "HD"},
+		{"mg e v f\r\n", 2, "VA 0 f3|"},
+		{"mg missing v\r\n", 1, "EN"},
+		{"mg missing v q\r\nmn\r\n", 1, "MN"},
+		{"ms k1 1 ME\r\nx\r\n", 1, "NS"},
+		{"ms k2 1 MR\r\nx\r\n", 1, "NS"},
+		{"ms k2 1 q\r\nx\r\nmn\r\n", 1, "MN"},
+		{"ms k2 1 C1\r\ny\r\n", 1, "EX"},
+		{"md k2 O9\r\n", 1, "HD O9"},
+		{"mg k2 v\r\n", 1, "EN"},
+		{"ms k3 1 MA\r\nx\r\n", 1, "CLIENT_ERROR append and prepend are not supported"},
+	}
+	for _, tc := range cases {
+		if got := c.do(tc.cmd, tc.lines); got != tc.want {
+			t.Fatalf("%q 应返回 %q，实际为 %q", tc.cmd, tc.want, got)
+		}
+	}
+}
+
+// 测试过期时间的解析
+func TestParseExptime(t *testing.T) {
+	if ttl, expired, ok := parseExptime("60"); !ok || expired || ttl != time.Minute {
+		t.Fatalf("相对时间解析错误: %v %v %v", ttl, expired, ok)
+	}
+	if _, expired, ok := parseExptime("-1"); !ok || !expired {
+		t.Fatal("负数应视为立即过期")
+	}
+	abs := time.Now().Add(time.Hour).Unix()
+	if ttl, expired, ok := parseExptime(strconv.FormatInt(abs, 10)); !ok || expired || ttl <= 59*time.Minute {
+		t.Fatalf("绝对时间解析错误: %v %v %v", ttl, expired, ok)
+	}
+	if _, _, ok := parseExptime("abc"); ok {
+		t.Fatal("非数字应解析失败")
+	}
+}
@@ -0,0 +1,253 @@
+package kamacache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SuperJinggg/mycache-go/store"
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryQuota 缓存组在内存管理器中的配额
+type MemoryQuota struct {
+	Min    int64   // 保证分配的最小字节数
+	Max    int64   // 最多分配的字节数，0表示不超过总预算
+	Weight float64 // 分配剩余内存时的权重，不大于0时为1
+}
+
+// MemoryManagerOption 内存管理器的配置选项
+type MemoryManagerOption func(*MemoryManager)
+
+// WithRebalanceInterval 设置重新分配内存的间隔，默认为10秒
+func WithRebalanceInterval(d time.Duration) MemoryManagerOption {
+	return func(m *MemoryManager) {
+		if d > 0 {
+			m.interval = d
+		}
+	}
+}
+
+// WithMemoryManager 由内存管理器按 quota 分配本地缓存的最大内存，NewGroup 的 cacheBytes 不再生效。
+// 内置的 store.LRU 和 store.LRU2 都可以调整大小，未实现 store.Resizer 的存储保持 cacheBytes 不变并占用预算。
+// 最小配额之和超过预算时 NewGroup 会 panic，CreateGroup 返回错误
+func WithMemoryManager(m *MemoryManager, quota MemoryQuota) GroupOption {
+	return func(g *Group) {
+		g.memManager = m
+		g.memQuota = quota
+	}
+}
+
+// MemoryManager 在多个缓存组之间分配进程级的内存预算。
+// 每个组先获得配额中的最小值，剩余内存定期按权重和容量压力重新分配，
+// 因容量不足被淘汰且随后未命中越多的组，增加内存带来的命中率提升越大，分得越多
+type MemoryManager struct {
+	budget   int64
+	interval time.Duration
+
+	mu      sync.Mutex
+	members map[*Cache]*memoryMember
+
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// memoryMember 一个注册到内存管理器的缓存
+type memoryMember struct {
+	name      string
+	cache     *Cache
+	quota     MemoryQuota
+	resizable bool  // 底层存储是否支持调整大小
+	limit     int64 // 当前分配的字节数
+
+	lastMisses    int64 // 上次分配时的未命中次数
+	lastEvictions int64 // 上次分配时的容量淘汰次数
+}
+
+// NewMemoryManager 创建总预算为 budget 字节的内存管理器
+func NewMemoryManager(budget int64, opts ...MemoryManagerOption) *MemoryManager {
+	if budget <= 0 {
+		panic("memory budget must be positive")
+	}
+
+	m := &MemoryManager{
+		budget:   budget,
+		interval: 10 * time.Second,
+		members:  make(map[*Cache]*memoryMember),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	go m.loop()
+	return m
+}
+
+// Register 将缓存注册到内存管理器并立即重新分配，最小配额之和超过总预算时返回错误
+func (m *MemoryManager) Register(name string, c *Cache, quota MemoryQuota) error {
+	if quota.Weight <= 0 {
+		quota.Weight = 1
+	}
+	if quota.Max > 0 && quota.Max < quota.Min {
+		return fmt.Errorf("memory quota of %s: max %d is less than min %d", name, quota.Max, quota.Min)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 先检查预算，失败时不修改缓存
+	var others int64
+	for other, o := range m.members {
+		if other != c {
+			others += o.quota.Min
+		}
+	}
+	if reserved := others + quota.Min; reserved > m.budget {
+		return fmt.Errorf("memory quota of %s: minimum guarantees %d exceed budget %d", name, reserved, m.budget)
+	}
+
+	member := &memoryMember{name: name, cache: c, quota: quota}
+	member.resizable = c.SetMaxBytes(max(quota.Min, 1))
+	if !member.resizable {
+		// 不支持调整大小的存储固定占用其配置的大小
+		fixed := c.MaxBytes()
+		member.quota.Min, member.quota.Max = fixed, fixed
+		logrus.Warnf("[KamaCache] cache of group %s does not support resizing, reserving its fixed %d bytes", name, fixed)
+		if reserved := others + fixed; reserved > m.budget {
+			return fmt.Errorf("memory quota of %s: fixed size %d with other minimum guarantees exceeds budget %d", name, reserved, m.budget)
+		}
+	}
+
+	member.lastMisses = atomic.LoadInt64(&c.misses)
+	member.lastEvictions = c.Evictions(store.EvictionCapacity)
+	m.members[c] = member
+	m.rebalanceLocked()
+	return nil
+}
+
+// Unregister 将缓存从内存管理器中移除，释放的内存在下次分配时交给其他组
+func (m *MemoryManager) Unregister(c *Cache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.members, c)
+}
+
+// Rebalance 立即重新分配内存
+func (m *MemoryManager) Rebalance() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rebalanceLocked()
+}
+
+// Allocations 返回每个组当前分配的字节数
+func (m *MemoryManager) Allocations() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	allocs := make(map[string]int64, len(m.members))
+	for _, member := range m.members {
+		allocs[member.name] = member.limit
+	}
+	return allocs
+}
+
+// Budget 返回总预算
+func (m *MemoryManager) Budget() int64 {
+	return m.budget
+}
+
+// Close 停止定期重新分配，已分配的大小保持不变
+func (m *MemoryManager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.stopCh)
+		<-m.doneCh
+	})
+}
+
+// loop 定期重新分配内存
+func (m *MemoryManager) loop() {
+	defer close(m.doneCh)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Rebalance()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// rebalanceLocked 重新计算每个组的大小并调整存储，先缩小再扩大，避免总量短暂超过预算
+func (m *MemoryManager) rebalanceLocked() {
+	if len(m.members) == 0 {
+		return
+	}
+
+	// 按名称排序，使分配结果与 map 的遍历顺序无关
+	members := make([]*memoryMember, 0, len(m.members))
+	for _, member := range m.members {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].name < members[j].name })
+
+	targets := make([]int64, len(members))
+	gains := make([]float64, len(members))
+	remaining := m.budget
+	for i, member := range members {
+		targets[i] = member.quota.Min
+		remaining -= member.quota.Min
+
+		// 容量淘汰说明缓存已满，未命中说明被淘汰的数据还有人访问，两者同时存在时增加内存才能提高命中率
+		misses := atomic.LoadInt64(&member.cache.misses)
+		evictions := member.cache.Evictions(store.EvictionCapacity)
+		pressure := min(misses-member.lastMisses, evictions-member.lastEvictions)
+		member.lastMisses, member.lastEvictions = misses, evictions
+		gains[i] = member.quota.Weight * float64(1+max(pressure, 0))
+	}
+
+	// 剩余内存分成若干份，每次交给边际收益最高的组，收益随已分配的大小递减
+	chunk := max(remaining/100, 1)
+	for remaining > 0 {
+		best := -1
+		var bestScore float64
+		for i, member := range members {
+			if !member.resizable || (member.quota.Max > 0 && targets[i] >= member.quota.Max) {
+				continue
+			}
+			score := gains[i] / float64(targets[i]+chunk)
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		grant := min(chunk, remaining)
+		if limit := members[best].quota.Max; limit > 0 {
+			grant = min(grant, limit-targets[best])
+		}
+		targets[best] += grant
+		remaining -= grant
+	}
+
+	for _, shrink := range []bool{true, false} {
+		for i, member := range members {
+			if member.resizable && (targets[i] < member.limit) == shrink && targets[i] != member.limit {
+				member.cache.SetMaxBytes(max(targets[i], 1))
+			}
+		}
+	}
+	for i, member := range members {
+		member.limit = targets[i]
+	}
+}
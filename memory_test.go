@@ -0,0 +1,133 @@
+package kamacache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SuperJinggg/mycache-go/store"
+)
+
+// 测试内存管理器在缓存组之间分配内存
+func TestMemoryManager(t *testing.T) {
+	m := NewMemoryManager(10000, WithRebalanceInterval(time.Hour))
+	t.Cleanup(m.Close)
+
+	getter := GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return bytes.Repeat([]byte("v"), 100), nil
+	})
+	newGroup := func(name string, quota MemoryQuota) *Group {
+		opts := DefaultCacheOptions()
+		opts.CacheType = store.LRU
+		g := NewGroup(name, 1<<20, getter, WithCacheOptions(opts), WithMemoryManager(m, quota))
+		t.Cleanup(func() { g.Close() })
+		return g
+	}
+	hot := newGroup("memory-hot-test", MemoryQuota{Min: 1000})
+	cold := newGroup("memory-cold-test", MemoryQuota{Min: 1000, Max: 4000})
+
+	t.Run("按配额分配初始内存", func(t *testing.T) {
+		allocs := m.Allocations()
+		if allocs["memory-cold-test"] != 4000 || allocs["memory-hot-test"] != 6000 {
+			t.Fatalf("初始分配不正确: %v", allocs)
+		}
+		if got := hot.mainCache.MaxBytes(); got != 6000 {
+			t.Errorf("缓存的最大内存应为 6000，实际为 %d", got)
+		}
+	})
+
+	t.Run("容量压力大的组分得更多内存", func(t *testing.T) {
+		m.Rebalance()
+		ctx := context.Background()
+		for round := 0; round < 2; round++ {
+			for i := 0; i < 100; i++ {
+				hot.Get(ctx, fmt.Sprintf("key-%d", i))
+			}
+		}
+		cold.Get(ctx, "key")
+
+		m.Rebalance()
+		allocs := m.Allocations()
+		if allocs["memory-hot-test"] < 8000 || allocs["memory-cold-test"] < 1000 {
+			t.Fatalf("重新分配后的结果不正确: %v", allocs)
+		}
+		if allocs["memory-hot-test"]+allocs["memory-cold-test"] > m.Budget() {
+			t.Errorf("分配的总量超过预算: %v", allocs)
+		}
+		if got := cold.mainCache.MaxBytes(); got != allocs["memory-cold-test"] {
+			t.Errorf("缩小后缓存的最大内存应为 %d，实际为 %d", allocs["memory-cold-test"], got)
+		}
+		if used := cold.mainCache.Bytes(); used > cold.mainCache.MaxBytes() {
+			t.Errorf("缩小后占用的内存 %d 超过上限", used)
+		}
+	})
+
+	t.Run("最小配额超过预算", func(t *testing.T) {
+		before := m.Allocations()
+		g, err := CreateGroup("memory-overcommit-test", 1<<20, getter, WithMemoryManager(m, MemoryQuota{Min: 9000}))
+		if err == nil || g != nil {
+			t.Fatal("最小配额之和超过预算时应返回错误")
+		}
+		if GetGroup("memory-overcommit-test") != nil {
+			t.Error("创建失败的组不应注册到节点")
+		}
+		if allocs := m.Allocations(); len(allocs) != len(before) {
+			t.Errorf("创建失败不应改变分配结果: %v", allocs)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("NewGroup 在最小配额之和超过预算时应该 panic")
+			}
+		}()
+		newGroup("memory-overcommit-test", MemoryQuota{Min: 9000})
+	})
+
+	t.Run("替换同名的组时释放旧组的配额", func(t *testing.T) {
+		node := NewNode()
+		t.Cleanup(func() { node.Close() })
+		node.NewGroup("memory-replace-test", 1<<20, getter, WithMemoryManager(m, MemoryQuota{Min: 100}))
+		node.NewGroup("memory-replace-test", 1<<20, getter, WithMemoryManager(m, MemoryQuota{Min: 100}))
+
+		count := 0
+		m.mu.Lock()
+		for _, member := range m.members {
+			if member.name == "memory-replace-test" {
+				count++
+			}
+		}
+		m.mu.Unlock()
+		if count != 1 {
+			t.Fatalf("被替换的组应已注销，实际注册了 %d 个同名的组", count)
+		}
+	})
+
+	t.Run("默认的 LRU2 存储可以调整大小", func(t *testing.T) {
+		g := NewGroup("memory-lru2-test", 1<<20, getter, WithMemoryManager(m, MemoryQuota{Min: 500, Max: 500}))
+		t.Cleanup(func() { g.Close() })
+
+		ctx := context.Background()
+		for i := 0; i < 20; i++ {
+			g.Get(ctx, fmt.Sprintf("key-%d", i))
+		}
+		if got := g.mainCache.MaxBytes(); got != 500 {
+			t.Errorf("LRU2 缓存的最大内存应为 500，实际为 %d", got)
+		}
+		if used := g.mainCache.Bytes(); used > 500 || used == 0 {
+			t.Errorf("LRU2 缓存占用的内存 %d 应不超过上限", used)
+		}
+		if g.mainCache.Evictions(store.EvictionCapacity) == 0 {
+			t.Error("超过上限时应按容量淘汰")
+		}
+	})
+
+	t.Run("关闭的组释放内存", func(t *testing.T) {
+		cold.Close()
+		m.Rebalance()
+		if allocs := m.Allocations(); allocs["memory-hot-test"] != 10000 {
+			t.Errorf("关闭其他组后应分得全部内存: %v", allocs)
+		}
+	})
+}
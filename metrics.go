@@ -0,0 +1,182 @@
+package kamacache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SuperJinggg/mycache-go/metrics"
+	"github.com/SuperJinggg/mycache-go/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// WithMetricsPath 在 HTTP 网关的 path 上提供 Prometheus 格式的指标，不经过认证
+func WithMetricsPath(path string) ServerOption {
+	return func(o *ServerOptions) {
+		o.MetricsPath = path
+	}
+}
+
+// MetricsCollector 返回采集默认节点上所有缓存组、对等节点 RPC 和哈希环指标的 Collector，
+// 已注册到 metrics.Default，使用自定义的 Registry 时可以再次注册
+func MetricsCollector() metrics.Collector {
+	return defaultNode.MetricsCollector()
+}
+
+// MetricsCollector 返回采集节点上所有缓存组、对等节点 RPC 和哈希环指标的 Collector，
+// 已注册到节点服务器的指标路径。对等节点 RPC 的指标按缓存组使用的节点选择器统计
+func (n *Node) MetricsCollector() metrics.Collector {
+	return metrics.CollectorFunc(n.collectMetrics)
+}
+
+// collectMetrics 输出节点的所有指标
+func (n *Node) collectMetrics(s metrics.Sink) {
+	pickers := make(map[*ClientPicker]bool)
+	for _, g := range n.snapshotGroups() {
+		collectGroupMetrics(s, g)
+		if p, ok := g.peers.(*ClientPicker); ok {
+			pickers[p] = true
+		}
+	}
+
+	for p := range pickers {
+		p.mu.RLock()
+		shares := p.consHash.GetStats()
+		p.mu.RUnlock()
+		for node, share := range shares {
+			s.Gauge("kamacache_ring_request_share", "Share of requests routed to each node by the consistent hash ring.",
+				share, metrics.Label{Name: "self", Value: p.selfAddr}, metrics.Label{Name: "node", Value: node})
+		}
+		p.metrics.collect(s)
+	}
+}
+
+// collectGroupMetrics 输出一个缓存组的指标
+func collectGroupMetrics(s metrics.Sink, g *Group) {
+	group := metrics.Label{Name: "group", Value: g.name}
+	counter := func(name, help string, v *int64) {
+		s.Counter(name, help, float64(atomic.LoadInt64(v)), group)
+	}
+
+	counter("kamacache_local_hits_total", "Gets served from the local cache.", &g.stats.localHits)
+	counter("kamacache_local_misses_total", "Gets not found in the local cache.", &g.stats.localMisses)
+	counter("kamacache_peer_hits_total", "Values fetched from peers.", &g.stats.peerHits)
+	counter("kamacache_peer_misses_total", "Failed fetches from peers.", &g.stats.peerMisses)
+	counter("kamacache_loader_hits_total", "Values loaded from the getter.", &g.stats.loaderHits)
+	counter("kamacache_loader_errors_total", "Loads that returned an error.", &g.stats.loaderErrors)
+	counter("kamacache_loader_rejected_total", "Loads rejected by concurrency limit, rate limit or circuit breaker.", &g.stats.loaderRejected)
+	counter("kamacache_stale_hits_total", "Stale values served after a failed load.", &g.stats.staleHits)
+	s.Histogram("kamacache_load_duration_seconds", "Latency of cache misses, including peer fetches and getter calls.",
+		g.loadLatency.Snapshot(), group)
+
+	for _, reason := range store.EvictionReasons {
+		s.Counter("kamacache_evictions_total", "Entries removed from the local cache by reason.",
+			float64(g.mainCache.Evictions(reason)), group, metrics.Label{Name: "reason", Value: reason.String()})
+	}
+	s.Gauge("kamacache_bytes", "Bytes used by the local cache.", float64(g.mainCache.Bytes()), group)
+	s.Gauge("kamacache_max_bytes", "Maximum bytes the local cache may use.", float64(g.mainCache.MaxBytes()), group)
+	s.Gauge("kamacache_items", "Entries in the local cache.", float64(g.mainCache.Len()), group)
+
+	if w := g.writeBehind; w != nil {
+		s.Gauge("kamacache_write_queue_depth", "Writes waiting to be flushed to the backing store.", float64(w.depth()), group)
+		counter("kamacache_write_flush_failures_total", "Writes dropped after exhausting retries.", &w.failures)
+	}
+}
+
+// rpcKey 按对等节点和方法区分 RPC 指标
+type rpcKey struct {
+	peer   string
+	method string
+}
+
+// rpcStats 一个对等节点上一个方法的指标
+type rpcStats struct {
+	latency *metrics.Histogram
+	mu      sync.Mutex
+	codes   map[string]int64 // 按 gRPC 状态码统计的调用次数
+}
+
+// rpcMetrics 对等节点 RPC 指标
+type rpcMetrics struct {
+	mu    sync.RWMutex
+	calls map[rpcKey]*rpcStats
+}
+
+// newRPCMetrics 创建空的 RPC 指标
+func newRPCMetrics() *rpcMetrics {
+	return &rpcMetrics{calls: make(map[rpcKey]*rpcStats)}
+}
+
+// observe 记录一次 RPC
+func (m *rpcMetrics) observe(peer, method string, elapsed time.Duration, err error) {
+	key := rpcKey{peer: peer, method: method}
+
+	m.mu.RLock()
+	st, ok := m.calls[key]
+	m.mu.RUnlock()
+	if !ok {
+		m.mu.Lock()
+		if st, ok = m.calls[key]; !ok {
+			st = &rpcStats{latency: metrics.NewHistogram(nil), codes: make(map[string]int64)}
+			m.calls[key] = st
+		}
+		m.mu.Unlock()
+	}
+
+	st.latency.Observe(elapsed.Seconds())
+	st.mu.Lock()
+	st.codes[status.Code(err).String()]++
+	st.mu.Unlock()
+}
+
+// collect 输出 RPC 指标
+func (m *rpcMetrics) collect(s metrics.Sink) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for key, st := range m.calls {
+		peer := metrics.Label{Name: "peer", Value: key.peer}
+		method := metrics.Label{Name: "method", Value: key.method}
+		s.Histogram("kamacache_peer_rpc_duration_seconds", "Latency of RPCs to peers.", st.latency.Snapshot(), peer, method)
+
+		st.mu.Lock()
+		for code, n := range st.codes {
+			s.Counter("kamacache_peer_rpc_total", "RPCs to peers by gRPC status code.", float64(n),
+				peer, method, metrics.Label{Name: "code", Value: code})
+		}
+		st.mu.Unlock()
+	}
+}
+
+// peerMetricsInterceptor 记录客户端 RPC 的耗时和状态码，客户端没有设置指标时不记录
+func peerMetricsInterceptor(c *Client) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if c.metrics != nil {
+			c.metrics.observe(c.addr, method, time.Since(start), err)
+		}
+		return err
+	}
+}
+
+// peerMetricsStreamInterceptor 记录客户端流式 RPC 从建立到结束的耗时和状态码，客户端没有设置指标时不记录
+func peerMetricsStreamInterceptor(c *Client) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if c.metrics == nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			c.metrics.observe(c.addr, method, time.Since(start), err)
+			return nil, err
+		}
+		return observeClientStream(ctx, cs, desc.ServerStreams, func(err error) {
+			c.metrics.observe(c.addr, method, time.Since(start), err)
+		}), nil
+	}
+}
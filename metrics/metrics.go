@@ -0,0 +1,127 @@
+// Package metrics 提供不依赖第三方库的指标采集，内置 Prometheus 文本格式输出，
+// 也可以通过 Sink 接口适配 client_golang 等指标库
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Label 指标的标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sink 接收指标的适配接口，Collector 在采集时向其输出当前的指标值
+type Sink interface {
+	Counter(name, help string, value float64, labels ...Label)
+	Gauge(name, help string, value float64, labels ...Label)
+	Histogram(name, help string, h HistogramSnapshot, labels ...Label)
+}
+
+// Collector 在采集时向 Sink 输出指标
+type Collector interface {
+	Collect(s Sink)
+}
+
+// CollectorFunc 函数类型实现 Collector 接口
+type CollectorFunc func(s Sink)
+
+// Collect 实现 Collector 接口
+func (f CollectorFunc) Collect(s Sink) {
+	f(s)
+}
+
+// Registry 保存一组 Collector
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// Default 默认的 Registry，kamacache 包在初始化时向其注册缓存的指标
+var Default = NewRegistry()
+
+// NewRegistry 创建空的 Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register 注册 Collector
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Collect 依次调用所有 Collector
+func (r *Registry) Collect(s Sink) {
+	r.mu.RLock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	for _, c := range collectors {
+		c.Collect(s)
+	}
+}
+
+// DefBuckets 默认的延迟分桶，单位为秒
+var DefBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram 固定分桶的直方图，可以并发调用 Observe
+type Histogram struct {
+	buckets []float64 // 各分桶的上界，升序
+	counts  []uint64  // 落在各分桶的次数，最后一个为超过所有上界的次数
+	sum     uint64    // 所有观测值之和，按 float64 的位存储
+	count   uint64
+}
+
+// HistogramSnapshot 直方图在某一时刻的值
+type HistogramSnapshot struct {
+	Buckets []float64 // 各分桶的上界
+	Counts  []uint64  // 不大于各上界的累计次数
+	Sum     float64
+	Count   uint64
+}
+
+// NewHistogram 创建直方图，buckets 为空时使用 DefBuckets
+func NewHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &Histogram{buckets: b, counts: make([]uint64, len(b)+1)}
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	for {
+		old := atomic.LoadUint64(&h.sum)
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sum, old, next) {
+			return
+		}
+	}
+}
+
+// Snapshot 返回直方图当前的值
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  make([]uint64, len(h.buckets)),
+		Sum:     math.Float64frombits(atomic.LoadUint64(&h.sum)),
+		Count:   atomic.LoadUint64(&h.count),
+	}
+	var cumulative uint64
+	for i := range h.buckets {
+		cumulative += atomic.LoadUint64(&h.counts[i])
+		s.Counts[i] = cumulative
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// 测试 Prometheus 文本格式输出
+func TestWriteText(t *testing.T) {
+	h := NewHistogram([]float64{0.1, 1})
+	for _, v := range []float64{0.05, 0.5, 5} {
+		h.Observe(v)
+	}
+
+	r := NewRegistry()
+	r.Register(CollectorFunc(func(s Sink) {
+		for _, g := range []string{"a", "b"} {
+			s.Counter("hits_total", "Hits.", 3, Label{Name: "group", Value: g})
+			s.Gauge("items", "Items.", 2, Label{Name: "group", Value: g})
+		}
+		s.Histogram("latency_seconds", "Latency.", h.Snapshot(), Label{Name: "group", Value: `x"y`})
+	}))
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, r); err != nil {
+		t.Fatalf("输出失败: %v", err)
+	}
+	out := buf.String()
+
+	want := []string{
+		"# TYPE hits_total counter\nhits_total{group=\"a\"} 3\nhits_total{group=\"b\"} 3\n",
+		"# TYPE items gauge\n",
+		`latency_seconds_bucket{group="x\"y",le="0.1"} 1`,
+		`latency_seconds_bucket{group="x\"y",le="1"} 2`,
+		`latency_seconds_bucket{group="x\"y",le="+Inf"} 3`,
+		`latency_seconds_sum{group="x\"y"} 5.55`,
+		`latency_seconds_count{group="x\"y"} 3`,
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Fatalf("输出中缺少 %q:\n%s", w, out)
+		}
+	}
+	if strings.Count(out, "# HELP hits_total") != 1 {
+		t.Fatal("同名指标应只输出一次 HELP")
+	}
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// textContentType Prometheus 文本格式的 Content-Type
+const textContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// family 同名的一组指标，文本格式要求同名指标连续输出且只有一个 HELP 和 TYPE
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []string
+}
+
+// textSink 将指标按名称分组后以 Prometheus 文本格式输出
+type textSink struct {
+	families map[string]*family
+	order    []*family
+}
+
+func newTextSink() *textSink {
+	return &textSink{families: make(map[string]*family)}
+}
+
+// family 返回名称对应的指标组，不存在时创建
+func (s *textSink) family(name, help, typ string) *family {
+	f, ok := s.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		s.families[name] = f
+		s.order = append(s.order, f)
+	}
+	return f
+}
+
+func (s *textSink) Counter(name, help string, value float64, labels ...Label) {
+	f := s.family(name, help, "counter")
+	f.samples = append(f.samples, name+formatLabels(labels)+" "+formatFloat(value))
+}
+
+func (s *textSink) Gauge(name, help string, value float64, labels ...Label) {
+	f := s.family(name, help, "gauge")
+	f.samples = append(f.samples, name+formatLabels(labels)+" "+formatFloat(value))
+}
+
+func (s *textSink) Histogram(name, help string, h HistogramSnapshot, labels ...Label) {
+	f := s.family(name, help, "histogram")
+	for i, upper := range h.Buckets {
+		le := append(labels[:len(labels):len(labels)], Label{Name: "le", Value: formatFloat(upper)})
+		f.samples = append(f.samples, name+"_bucket"+formatLabels(le)+" "+strconv.FormatUint(h.Counts[i], 10))
+	}
+	inf := append(labels[:len(labels):len(labels)], Label{Name: "le", Value: "+Inf"})
+	f.samples = append(f.samples,
+		name+"_bucket"+formatLabels(inf)+" "+strconv.FormatUint(h.Count, 10),
+		name+"_sum"+formatLabels(labels)+" "+formatFloat(h.Sum),
+		name+"_count"+formatLabels(labels)+" "+strconv.FormatUint(h.Count, 10),
+	)
+}
+
+// writeTo 输出所有指标
+func (s *textSink) writeTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range s.order {
+		bw.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		bw.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		for _, sample := range f.samples {
+			bw.WriteString(sample + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteText 以 Prometheus 文本格式输出 r 中的所有指标
+func WriteText(w io.Writer, r *Registry) error {
+	s := newTextSink()
+	r.Collect(s)
+	return s.writeTo(w)
+}
+
+// Handler 返回以 Prometheus 文本格式输出 r 中指标的 HTTP 处理器
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", textContentType)
+		WriteText(w, r)
+	})
+}
+
+// HandleHTTP 在 mux 的 path 上挂载 r 的指标，r 为 nil 时使用 Default
+func HandleHTTP(mux *http.ServeMux, path string, r *Registry) {
+	if r == nil {
+		r = Default
+	}
+	mux.Handle("GET "+path, Handler(r))
+}
+
+// formatLabels 格式化标签，没有标签时返回空字符串
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.Name + `="` + escapeLabel(l.Value) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatFloat 按 Prometheus 的要求格式化浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
@@ -0,0 +1,117 @@
+package kamacache
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperJinggg/mycache-go/metrics"
+	pb "github.com/SuperJinggg/mycache-go/pb"
+)
+
+// 测试缓存组的指标导出
+func TestGroupMetrics(t *testing.T) {
+	g := NewGroup("metrics-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("value"), nil
+	}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	g.Get(ctx, "k")
+	g.Get(ctx, "k")
+
+	r := metrics.NewRegistry()
+	r.Register(MetricsCollector())
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf, r); err != nil {
+		t.Fatalf("输出失败: %v", err)
+	}
+	out := buf.String()
+
+	for _, w := range []string{
+		`kamacache_local_hits_total{group="metrics-test"} 1`,
+		`kamacache_loader_hits_total{group="metrics-test"} 1`,
+		`kamacache_load_duration_seconds_count{group="metrics-test"} 1`,
+		`kamacache_items{group="metrics-test"} 1`,
+	} {
+		if !strings.Contains(out, w) {
+			t.Fatalf("输出中缺少 %q", w)
+		}
+	}
+}
+
+// 测试对等节点 RPC 指标统计普通和流式 RPC
+func TestPeerRPCMetrics(t *testing.T) {
+	srv, err := NewServer("127.0.0.1:0", "rpc-metrics-test")
+	if err != nil {
+		t.Fatalf("创建服务器失败: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go srv.grpcServer.Serve(ln)
+	t.Cleanup(srv.grpcServer.Stop)
+
+	p := newTestPicker(t, "127.0.0.1:1", ln.Addr().String())
+	client := p.clients[ln.Addr().String()]
+	client.streamThreshold = 16
+
+	g := NewGroup("rpc-metrics-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	if err := client.Set(ctx, g.name, "big", bytes.Repeat([]byte("x"), 64), 0); err != nil {
+		t.Fatalf("流式写入失败: %v", err)
+	}
+	if _, err := client.Get(ctx, g.name, "big"); err != nil {
+		t.Fatalf("流式读取失败: %v", err)
+	}
+
+	// 调用方放弃读取的流在上下文结束时统计
+	abandonCtx, cancel := context.WithCancel(ctx)
+	if _, err := client.grpcCli.GetStream(abandonCtx, &pb.Request{Group: g.name, Key: "big"}); err != nil {
+		t.Fatalf("建立流失败: %v", err)
+	}
+	cancel()
+
+	collect := func() string {
+		r := metrics.NewRegistry()
+		r.Register(metrics.CollectorFunc(p.metrics.collect))
+		var buf bytes.Buffer
+		if err := metrics.WriteText(&buf, r); err != nil {
+			t.Fatalf("输出失败: %v", err)
+		}
+		return buf.String()
+	}
+
+	want := []string{
+		`method="/pb.MyCache/Get"`,
+		`method="/pb.MyCache/SetStream"`,
+		`kamacache_peer_rpc_total{peer="` + ln.Addr().String() + `",method="/pb.MyCache/GetStream",code="OK"} 1`,
+		`kamacache_peer_rpc_total{peer="` + ln.Addr().String() + `",method="/pb.MyCache/GetStream",code="Canceled"} 1`,
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		out := collect()
+		missing := ""
+		for _, w := range want {
+			if !strings.Contains(out, w) {
+				missing = w
+				break
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("输出中缺少 %q:\n%s", missing, out)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
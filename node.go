@@ -0,0 +1,213 @@
+package kamacache
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/SuperJinggg/mycache-go/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Node 一个缓存节点，持有该节点的缓存组、节点选择器和服务器。
+// 同一进程中的多个 Node 互不影响，可以在一个进程内运行多个节点，
+// 包级的 NewGroup、GetGroup、ListGroups、DestroyGroup、NewServer 使用 DefaultNode
+type Node struct {
+	mu     sync.RWMutex
+	groups map[string]*Group
+	picker PeerPicker
+	server *Server
+
+	registry *metrics.Registry // 服务器指标路径使用的 Registry
+}
+
+// defaultNode 包级函数使用的节点，指标注册到 metrics.Default
+var defaultNode = newNode(metrics.Default)
+
+// DefaultNode 返回包级函数使用的节点
+func DefaultNode() *Node {
+	return defaultNode
+}
+
+// NewNode 创建一个新的节点，它的指标不注册到 metrics.Default，可以通过 MetricsCollector 自行注册
+func NewNode() *Node {
+	return newNode(metrics.NewRegistry())
+}
+
+// newNode 创建节点并将其指标注册到 registry
+func newNode(registry *metrics.Registry) *Node {
+	n := &Node{
+		groups:   make(map[string]*Group),
+		registry: registry,
+	}
+	registry.Register(n.MetricsCollector())
+	return n
+}
+
+// NewGroup 在节点上创建缓存组，同名的组会被替换并关闭。
+// 节点已通过 NewClientPicker 创建节点选择器时，组默认使用它，WithPeers 可以覆盖。
+// 无法满足配置时 panic，需要处理错误时使用 CreateGroup
+func (n *Node) NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
+	g, err := n.CreateGroup(name, cacheBytes, getter, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return g
+}
+
+// CreateGroup 与 NewGroup 相同，但无法满足配置时返回错误，此时节点上同名的组不会被替换
+func (n *Node) CreateGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) (*Group, error) {
+	n.mu.RLock()
+	picker := n.picker
+	n.mu.RUnlock()
+	if picker != nil {
+		opts = append([]GroupOption{WithPeers(picker)}, opts...)
+	}
+
+	g, err := newGroup(name, cacheBytes, getter, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group %s: %w", name, err)
+	}
+	g.node = n
+
+	n.mu.Lock()
+	old, exists := n.groups[name]
+	n.groups[name] = g
+	n.mu.Unlock()
+
+	// 关闭被替换的组，释放它的内存配额、计数器批处理和 write-behind 协程
+	if exists {
+		logrus.Warnf("Group with name %s already exists, will be replaced", name)
+		old.Close()
+	}
+	logrus.Infof("Created cache group [%s] with cacheBytes=%d, expiration=%v", name, cacheBytes, g.expiration)
+
+	return g, nil
+}
+
+// GetGroup 获取节点上指定名称的组
+func (n *Node) GetGroup(name string) *Group {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.groups[name]
+}
+
+// ListGroups 返回节点上所有缓存组的名称
+func (n *Node) ListGroups() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	names := make([]string, 0, len(n.groups))
+	for name := range n.groups {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// DestroyGroup 销毁节点上指定名称的缓存组
+func (n *Node) DestroyGroup(name string) bool {
+	n.mu.Lock()
+	g, exists := n.groups[name]
+	delete(n.groups, name)
+	n.mu.Unlock()
+
+	if !exists {
+		return false
+	}
+
+	g.Close()
+	logrus.Infof("[KamaCache] destroyed cache group [%s]", name)
+	return true
+}
+
+// DestroyAllGroups 销毁节点上所有缓存组
+func (n *Node) DestroyAllGroups() {
+	n.mu.Lock()
+	list := n.groups
+	n.groups = make(map[string]*Group)
+	n.mu.Unlock()
+
+	for name, g := range list {
+		g.Close()
+		logrus.Infof("[KamaCache] destroyed cache group [%s]", name)
+	}
+}
+
+// removeGroup 组关闭时将其从节点上移除，组已被同名的组替换时什么也不做
+func (n *Node) removeGroup(g *Group) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.groups[g.name] == g {
+		delete(n.groups, g.name)
+	}
+}
+
+// snapshotGroups 返回节点上所有的组
+func (n *Node) snapshotGroups() []*Group {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	list := make([]*Group, 0, len(n.groups))
+	for _, g := range n.groups {
+		list = append(list, g)
+	}
+	return list
+}
+
+// NewClientPicker 为节点创建节点选择器，之后在节点上创建的组默认使用它
+func (n *Node) NewClientPicker(addr string, opts ...PickerOption) (*ClientPicker, error) {
+	picker, err := NewClientPicker(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	n.picker = picker
+	n.mu.Unlock()
+	return picker, nil
+}
+
+// NewServer 为节点创建服务器，服务器只访问节点上的组
+func (n *Node) NewServer(addr, svcName string, opts ...ServerOption) (*Server, error) {
+	srv, err := newServer(n, addr, svcName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	n.server = srv
+	n.mu.Unlock()
+	return srv, nil
+}
+
+// Picker 返回节点的节点选择器，未创建时返回 nil
+func (n *Node) Picker() PeerPicker {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.picker
+}
+
+// Server 返回节点的服务器，未创建时返回 nil
+func (n *Node) Server() *Server {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.server
+}
+
+// Close 依次停止服务器、销毁所有组并关闭节点选择器
+func (n *Node) Close() error {
+	n.mu.Lock()
+	srv, picker := n.server, n.picker
+	n.server, n.picker = nil, nil
+	n.mu.Unlock()
+
+	if srv != nil {
+		srv.Stop()
+	}
+	n.DestroyAllGroups()
+	if picker != nil {
+		return picker.Close()
+	}
+	return nil
+}
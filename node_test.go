@@ -0,0 +1,83 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// 测试同一进程中的多个节点互不影响
+func TestNodeIsolation(t *testing.T) {
+	ctx := context.Background()
+	newNode := func(value string) (*Node, *Client) {
+		node := NewNode()
+		node.NewGroup("node-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+			return []byte(value), nil
+		}))
+
+		srv, err := node.NewServer("127.0.0.1:0", "node-test")
+		if err != nil {
+			t.Fatalf("创建服务器失败: %v", err)
+		}
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("监听失败: %v", err)
+		}
+		go srv.grpcServer.Serve(ln)
+		t.Cleanup(func() { node.Close() })
+
+		client, err := NewClient(ln.Addr().String(), "node-test", nil)
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return node, client
+	}
+	nodeA, clientA := newNode("a")
+	nodeB, clientB := newNode("b")
+
+	t.Run("同名的组属于各自的节点", func(t *testing.T) {
+		if GetGroup("node-test") != nil {
+			t.Error("默认节点上不应该有其他节点的组")
+		}
+		if nodeA.GetGroup("node-test") == nodeB.GetGroup("node-test") {
+			t.Error("两个节点的组不应该相同")
+		}
+		if got, err := clientA.Get(ctx, "node-test", "k"); err != nil || string(got) != "a" {
+			t.Errorf("节点A应返回 a，实际为 %q, %v", got, err)
+		}
+		if got, err := clientB.Get(ctx, "node-test", "k"); err != nil || string(got) != "b" {
+			t.Errorf("节点B应返回 b，实际为 %q, %v", got, err)
+		}
+	})
+
+	t.Run("关闭被替换的组不影响新组", func(t *testing.T) {
+		old := nodeA.GetGroup("node-test")
+		replaced := nodeA.NewGroup("node-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+			return []byte("a2"), nil
+		}))
+		if _, err := old.Get(ctx, "k"); !errors.Is(err, ErrGroupClosed) {
+			t.Fatalf("被替换的组应已关闭，实际为 %v", err)
+		}
+		old.Close()
+		if nodeA.GetGroup("node-test") != replaced {
+			t.Fatal("关闭旧组后新组应该仍然存在")
+		}
+		if got, err := clientA.Get(ctx, "node-test", "k2"); err != nil || string(got) != "a2" {
+			t.Errorf("节点A应返回 a2，实际为 %q, %v", got, err)
+		}
+	})
+
+	t.Run("销毁组", func(t *testing.T) {
+		if !nodeB.DestroyGroup("node-test") {
+			t.Fatal("销毁已存在的组应返回 true")
+		}
+		if len(nodeB.ListGroups()) != 0 || nodeA.GetGroup("node-test") == nil {
+			t.Error("只应销毁节点B上的组")
+		}
+		if _, err := clientB.Get(ctx, "node-test", "k"); err == nil {
+			t.Error("组被销毁后应返回错误")
+		}
+	})
+}
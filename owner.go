@@ -0,0 +1,113 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOwnerUnavailable key 的所有者节点不可用
+var ErrOwnerUnavailable = errors.New("owner of the key is unavailable")
+
+// OwnerLoadPolicy 严格所有者加载模式下，所有者不可用时非所有者节点的处理策略
+type OwnerLoadPolicy int
+
+const (
+	OwnerLoadDisabled OwnerLoadPolicy = iota // 关闭严格模式，所有者不可用时在本地加载
+	OwnerLoadWait                            // 等待所有者恢复或环上所有者变更，直到加载超时
+	OwnerLoadFailFast                        // 立即返回 ErrOwnerUnavailable
+)
+
+const (
+	ownerRetryInitialBackoff = 20 * time.Millisecond
+	ownerRetryMaxBackoff     = 500 * time.Millisecond
+	ownerRetryMaxWait        = 30 * time.Second // 没有设置加载超时时等待所有者的最长时间
+)
+
+// peerMetadataKey 节点选择器创建的客户端发出的请求携带的元数据，收到的节点认为自己是所有者，直接处理而不再转发
+const peerMetadataKey = "kamacache-peer"
+
+// WithStrictOwnerLoad 开启严格所有者加载模式，只有 key 的所有者节点会调用 Getter，
+// 保证整个集群中每个 key 同一时刻最多只有一次数据源加载
+func WithStrictOwnerLoad(policy OwnerLoadPolicy) GroupOption {
+	return func(g *Group) {
+		g.ownerLoad = policy
+	}
+}
+
+// loadFromOwner 在向所有者获取失败后按策略处理，不会在非所有者节点上调用 Getter
+// 每次重试都会重新选择所有者，节点下线后 key 会由环上新的所有者（可能是本节点）负责加载
+func (g *Group) loadFromOwner(ctx context.Context, key string, lastErr error) (ByteView, error) {
+	if g.ownerLoad == OwnerLoadFailFast || !isOwnerUnavailable(lastErr) {
+		return ByteView{}, fmt.Errorf("%w: %v", ErrOwnerUnavailable, lastErr)
+	}
+
+	// 共享的加载不继承调用方的截止时间，没有设置加载超时时也不能无限等待
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ownerRetryMaxWait)
+		defer cancel()
+	}
+
+	backoff := ownerRetryInitialBackoff
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ByteView{}, fmt.Errorf("%w: %v", ErrOwnerUnavailable, ctx.Err())
+		case <-timer.C:
+		}
+
+		peer, err := g.pickOwner(key)
+		if err != nil {
+			backoff = min(backoff*2, ownerRetryMaxBackoff)
+			continue
+		}
+		if peer == nil {
+			// 所有者已变更为本节点
+			return g.loadFromGetter(ctx, key)
+		}
+
+		value, err := g.getFromPeer(ctx, peer, key)
+		if err == nil {
+			atomic.AddInt64(&g.stats.peerHits, 1)
+			return value, nil
+		}
+
+		atomic.AddInt64(&g.stats.peerMisses, 1)
+		if !isOwnerUnavailable(err) {
+			return ByteView{}, err
+		}
+
+		backoff = min(backoff*2, ownerRetryMaxBackoff)
+	}
+}
+
+// pickOwner 选择 key 的所有者，不在其他节点上故障转移，peer 为 nil 表示由本节点处理。
+// 节点选择器未实现 OwnerPicker 时使用 PickPeer 的结果
+func (g *Group) pickOwner(key string) (Peer, error) {
+	if g.peers == nil {
+		return nil, nil
+	}
+	if op, ok := g.peers.(OwnerPicker); ok {
+		peer, self, err := op.PickOwner(key)
+		if err != nil || self {
+			return nil, err
+		}
+		return peer, nil
+	}
+	peer, ok, self := g.peers.PickPeer(key)
+	if !ok || self {
+		return nil, nil
+	}
+	return peer, nil
+}
+
+// isOwnerUnavailable 判断错误是否由所有者节点不可达引起，数据源返回的错误不需要重试。
+// 与 isTransientError 相同，所有者返回的业务错误（例如加载器繁忙）即使状态码为 Unavailable 也不重试
+func isOwnerUnavailable(err error) bool {
+	return errors.Is(err, ErrOwnerUnavailable) || isTransientError(err)
+}
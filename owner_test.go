@@ -0,0 +1,203 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// scriptedPeer 依次返回预设错误的节点，错误用完后返回节点名称作为值
+type scriptedPeer struct {
+	*fakePeer
+	name  string
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func newScriptedPeer(name string, errs ...error) *scriptedPeer {
+	return &scriptedPeer{fakePeer: newFakePeer(), name: name, errs: errs}
+}
+
+func (p *scriptedPeer) Get(ctx context.Context, group, key string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+	return []byte(p.name), nil
+}
+
+// ownerStep PickOwner 的一次结果，peer 和 err 都为 nil 表示所有者是本节点
+type ownerStep struct {
+	peer *scriptedPeer
+	err  error
+}
+
+// scriptedPicker 每次 PickOwner 依次返回预设的所有者，用完后一直返回最后一个，模拟成员变化
+type scriptedPicker struct {
+	mu    sync.Mutex
+	steps []ownerStep
+}
+
+func (p *scriptedPicker) PickOwner(key string) (Peer, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	step := p.steps[0]
+	if len(p.steps) > 1 {
+		p.steps = p.steps[1:]
+	}
+	switch {
+	case step.err != nil:
+		return nil, false, step.err
+	case step.peer == nil:
+		return nil, true, nil
+	}
+	return step.peer, false, nil
+}
+
+func (p *scriptedPicker) PickPeer(key string) (Peer, bool, bool) {
+	peer, self, err := p.PickOwner(key)
+	return peer, err == nil, self
+}
+
+func (p *scriptedPicker) Close() error { return nil }
+
+// 测试严格所有者加载模式下每次重试都重新选择所有者
+func TestLoadFromOwner(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "owner down")
+	a, b := newScriptedPeer("a", unavailable), newScriptedPeer("b")
+
+	tests := []struct {
+		name      string
+		policy    OwnerLoadPolicy
+		steps     []ownerStep
+		want      string
+		wantErr   error
+		wantLoads int32
+	}{
+		{"所有者恢复后从所有者获取", OwnerLoadWait, []ownerStep{{peer: newScriptedPeer("a", unavailable, unavailable)}}, "a", nil, 0},
+		{"所有者变更为其他节点", OwnerLoadWait, []ownerStep{{peer: a}, {peer: b}}, "b", nil, 0},
+		{"所有者变更为本节点时在本地加载", OwnerLoadWait, []ownerStep{{peer: newScriptedPeer("a", unavailable)}, {}}, "local", nil, 1},
+		{"选择所有者失败时重试", OwnerLoadWait, []ownerStep{{err: ErrOwnerUnavailable}, {err: ErrOwnerUnavailable}, {peer: newScriptedPeer("a")}}, "a", nil, 0},
+		{"所有者一直不可用时等到加载超时", OwnerLoadWait, []ownerStep{{err: ErrOwnerUnavailable}}, "", ErrOwnerUnavailable, 0},
+		{"快速失败", OwnerLoadFailFast, []ownerStep{{peer: newScriptedPeer("a", unavailable)}}, "", ErrOwnerUnavailable, 0},
+		{"其他错误不重试也不在本地加载", OwnerLoadWait, []ownerStep{{peer: newScriptedPeer("a", status.Error(codes.Internal, "boom"))}, {peer: b}}, "", ErrOwnerUnavailable, 0},
+		{"所有者返回的业务错误不重试", OwnerLoadWait, []ownerStep{{peer: newScriptedPeer("a", toStatusError(ErrLoaderBusy))}, {peer: b}}, "", ErrOwnerUnavailable, 0},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loads int32
+			g := NewGroup(fmt.Sprintf("load-from-owner-test-%d", i), 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+				atomic.AddInt32(&loads, 1)
+				return []byte("local"), nil
+			}), WithStrictOwnerLoad(tt.policy), WithLoaderTimeout(200*time.Millisecond), WithPeers(&scriptedPicker{steps: tt.steps}))
+			t.Cleanup(func() { g.Close() })
+
+			view, err := g.Get(context.Background(), "k")
+			if !errors.Is(err, tt.wantErr) || (err == nil && view.String() != tt.want) {
+				t.Fatalf("应返回 %q, %v，实际为 %q, %v", tt.want, tt.wantErr, view.String(), err)
+			}
+			if got := atomic.LoadInt32(&loads); got != tt.wantLoads {
+				t.Fatalf("本地加载次数应为 %d，实际为 %d", tt.wantLoads, got)
+			}
+		})
+	}
+
+	if a.calls != 1 || b.calls != 1 {
+		t.Errorf("所有者变更后应只向新所有者获取一次，其他错误不应重试: a=%d, b=%d", a.calls, b.calls)
+	}
+}
+
+// 测试直接访问非所有者节点的 Get 和 Incr 由所有者处理，只有节点选择器发出的请求才在收到的节点上直接处理
+func TestServerRoutesClientsToOwner(t *testing.T) {
+	ctx := context.Background()
+	type member struct {
+		addr  string
+		loads int32
+		group *Group
+	}
+
+	listeners := make([]net.Listener, 2)
+	members := make([]*member, 2)
+	for i := range members {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("监听失败: %v", err)
+		}
+		listeners[i] = ln
+		members[i] = &member{addr: ln.Addr().String()}
+	}
+
+	var picker *ClientPicker
+	for i, m := range members {
+		node := NewNode()
+		p := newTestPicker(t, m.addr, members[1-i].addr)
+		if i == 0 {
+			picker = p
+		}
+		getter := GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+			atomic.AddInt32(&m.loads, 1)
+			return []byte(m.addr), nil
+		})
+		m.group = node.NewGroup("route-owner-test", 1<<20, getter, WithPeers(p), WithStrictOwnerLoad(OwnerLoadFailFast))
+
+		srv, err := node.NewServer(m.addr, "route-owner-test")
+		if err != nil {
+			t.Fatalf("创建服务器失败: %v", err)
+		}
+		go srv.grpcServer.Serve(listeners[i])
+		t.Cleanup(func() { node.Close() })
+	}
+
+	local, owner := members[0], members[1]
+	key := keyOwnedBy(t, picker, owner.addr)
+
+	client, err := NewClient(local.addr, "route-owner-test", nil)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	t.Run("Get 由所有者加载", func(t *testing.T) {
+		got, err := client.Get(ctx, "route-owner-test", key)
+		if err != nil || string(got) != owner.addr {
+			t.Fatalf("应返回所有者加载的值，实际为 %q, %v", got, err)
+		}
+		if atomic.LoadInt32(&local.loads) != 0 || atomic.LoadInt32(&owner.loads) != 1 {
+			t.Fatalf("只有所有者应调用 Getter，实际为 %d, %d", local.loads, owner.loads)
+		}
+	})
+
+	t.Run("Incr 在所有者上执行", func(t *testing.T) {
+		counter := ""
+		for i := 0; counter == ""; i++ {
+			if c := fmt.Sprintf("counter-%d", i); picker.consHash.Get(c) == owner.addr {
+				counter = c
+			}
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := client.Incr(ctx, "route-owner-test", counter, 1, 0); err != nil {
+				t.Fatalf("自增失败: %v", err)
+			}
+		}
+		if _, _, ok := local.group.mainCache.peek(counter); ok {
+			t.Fatal("非所有者节点不应保存计数器")
+		}
+		if v, err := owner.group.Incr(ctx, counter, 0, 0); err != nil || v != 2 {
+			t.Fatalf("所有者的计数器应为 2，实际为 %d, %v", v, err)
+		}
+	})
+}
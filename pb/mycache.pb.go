@@ -21,13 +21,71 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type LeaseStatus int32
+
+const (
+	LeaseStatus_LEASE_HIT     LeaseStatus = 0
+	LeaseStatus_LEASE_GRANTED LeaseStatus = 1
+	LeaseStatus_LEASE_WAIT    LeaseStatus = 2
+	LeaseStatus_LEASE_STALE   LeaseStatus = 3
+)
+
+// Enum value maps for LeaseStatus.
+var (
+	LeaseStatus_name = map[int32]string{
+		0: "LEASE_HIT",
+		1: "LEASE_GRANTED",
+		2: "LEASE_WAIT",
+		3: "LEASE_STALE",
+	}
+	LeaseStatus_value = map[string]int32{
+		"LEASE_HIT":     0,
+		"LEASE_GRANTED": 1,
+		"LEASE_WAIT":    2,
+		"LEASE_STALE":   3,
+	}
+)
+
+func (x LeaseStatus) Enum() *LeaseStatus {
+	p := new(LeaseStatus)
+	*p = x
+	return p
+}
+
+func (x LeaseStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LeaseStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_mycache_proto_enumTypes[0].Descriptor()
+}
+
+func (LeaseStatus) Type() protoreflect.EnumType {
+	return &file_mycache_proto_enumTypes[0]
+}
+
+func (x LeaseStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LeaseStatus.Descriptor instead.
+func (LeaseStatus) EnumDescriptor() ([]byte, []int) {
+	return file_mycache_proto_rawDescGZIP(), []int{0}
+}
+
 type Request struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
-	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
-	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Group      string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key        string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value      []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	LeaseToken uint64                 `protobuf:"varint,4,opt,name=lease_token,json=leaseToken,proto3" json:"lease_token,omitempty"`
+	TtlMs      int64                  `protobuf:"varint,5,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	// 值超过该长度时 Get 不返回值，而是要求调用方改用 GetStream，0 表示不限制
+	StreamThreshold int64 `protobuf:"varint,6,opt,name=stream_threshold,json=streamThreshold,proto3" json:"stream_threshold,omitempty"`
+	// 调用方支持的压缩算法，服务端可以直接返回压缩后的值
+	AcceptEncoding []string `protobuf:"bytes,7,rep,name=accept_encoding,json=acceptEncoding,proto3" json:"accept_encoding,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Request) Reset() {
@@ -81,9 +139,41 @@ func (x *Request) GetValue() []byte {
 	return nil
 }
 
+func (x *Request) GetLeaseToken() uint64 {
+	if x != nil {
+		return x.LeaseToken
+	}
+	return 0
+}
+
+func (x *Request) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+func (x *Request) GetStreamThreshold() int64 {
+	if x != nil {
+		return x.StreamThreshold
+	}
+	return 0
+}
+
+func (x *Request) GetAcceptEncoding() []string {
+	if x != nil {
+		return x.AcceptEncoding
+	}
+	return nil
+}
+
 type ResponseForGet struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// 值过大，需要通过 GetStream 获取
+	Stream bool `protobuf:"varint,2,opt,name=stream,proto3" json:"stream,omitempty"`
+	// value 使用的压缩算法，为空表示未压缩
+	Encoding      string `protobuf:"bytes,3,opt,name=encoding,proto3" json:"encoding,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -125,6 +215,105 @@ func (x *ResponseForGet) GetValue() []byte {
 	return nil
 }
 
+func (x *ResponseForGet) GetStream() bool {
+	if x != nil {
+		return x.Stream
+	}
+	return false
+}
+
+func (x *ResponseForGet) GetEncoding() string {
+	if x != nil {
+		return x.Encoding
+	}
+	return ""
+}
+
+// Chunk 流式传输中的一个分片，首个分片携带组、键和值的总长度
+type Chunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	TtlMs         int64                  `protobuf:"varint,4,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	TotalSize     int64                  `protobuf:"varint,5,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	Encoding      string                 `protobuf:"bytes,6,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Chunk) Reset() {
+	*x = Chunk{}
+	mi := &file_mycache_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chunk) ProtoMessage() {}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message {
+	mi := &file_mycache_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return file_mycache_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Chunk) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Chunk) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Chunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Chunk) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+func (x *Chunk) GetTotalSize() int64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+func (x *Chunk) GetEncoding() string {
+	if x != nil {
+		return x.Encoding
+	}
+	return ""
+}
+
 type ResponseForDelete struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Value         bool                   `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
@@ -134,7 +323,7 @@ type ResponseForDelete struct {
 
 func (x *ResponseForDelete) Reset() {
 	*x = ResponseForDelete{}
-	mi := &file_mycache_proto_msgTypes[2]
+	mi := &file_mycache_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -146,7 +335,7 @@ func (x *ResponseForDelete) String() string {
 func (*ResponseForDelete) ProtoMessage() {}
 
 func (x *ResponseForDelete) ProtoReflect() protoreflect.Message {
-	mi := &file_mycache_proto_msgTypes[2]
+	mi := &file_mycache_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -159,7 +348,7 @@ func (x *ResponseForDelete) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseForDelete.ProtoReflect.Descriptor instead.
 func (*ResponseForDelete) Descriptor() ([]byte, []int) {
-	return file_mycache_proto_rawDescGZIP(), []int{2}
+	return file_mycache_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *ResponseForDelete) GetValue() bool {
@@ -169,23 +358,231 @@ func (x *ResponseForDelete) GetValue() bool {
 	return false
 }
 
+type ResponseForLease struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        LeaseStatus            `protobuf:"varint,1,opt,name=status,proto3,enum=pb.LeaseStatus" json:"status,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Token         uint64                 `protobuf:"varint,3,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseForLease) Reset() {
+	*x = ResponseForLease{}
+	mi := &file_mycache_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseForLease) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseForLease) ProtoMessage() {}
+
+func (x *ResponseForLease) ProtoReflect() protoreflect.Message {
+	mi := &file_mycache_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseForLease.ProtoReflect.Descriptor instead.
+func (*ResponseForLease) Descriptor() ([]byte, []int) {
+	return file_mycache_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ResponseForLease) GetStatus() LeaseStatus {
+	if x != nil {
+		return x.Status
+	}
+	return LeaseStatus_LEASE_HIT
+}
+
+func (x *ResponseForLease) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *ResponseForLease) GetToken() uint64 {
+	if x != nil {
+		return x.Token
+	}
+	return 0
+}
+
+type IncrRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Delta         int64                  `protobuf:"varint,3,opt,name=delta,proto3" json:"delta,omitempty"`
+	TtlMs         int64                  `protobuf:"varint,4,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrRequest) Reset() {
+	*x = IncrRequest{}
+	mi := &file_mycache_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrRequest) ProtoMessage() {}
+
+func (x *IncrRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mycache_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrRequest.ProtoReflect.Descriptor instead.
+func (*IncrRequest) Descriptor() ([]byte, []int) {
+	return file_mycache_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IncrRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *IncrRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrRequest) GetDelta() int64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+func (x *IncrRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type ResponseForIncr struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         int64                  `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseForIncr) Reset() {
+	*x = ResponseForIncr{}
+	mi := &file_mycache_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseForIncr) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseForIncr) ProtoMessage() {}
+
+func (x *ResponseForIncr) ProtoReflect() protoreflect.Message {
+	mi := &file_mycache_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseForIncr.ProtoReflect.Descriptor instead.
+func (*ResponseForIncr) Descriptor() ([]byte, []int) {
+	return file_mycache_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ResponseForIncr) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
 var File_mycache_proto protoreflect.FileDescriptor
 
 const file_mycache_proto_rawDesc = "" +
 	"\n" +
-	"\rmycache.proto\x12\x02pb\"G\n" +
+	"\rmycache.proto\x12\x02pb\"\xd3\x01\n" +
 	"\aRequest\x12\x14\n" +
 	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
 	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x03 \x01(\fR\x05value\"&\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x1f\n" +
+	"\vlease_token\x18\x04 \x01(\x04R\n" +
+	"leaseToken\x12\x15\n" +
+	"\x06ttl_ms\x18\x05 \x01(\x03R\x05ttlMs\x12)\n" +
+	"\x10stream_threshold\x18\x06 \x01(\x03R\x0fstreamThreshold\x12'\n" +
+	"\x0faccept_encoding\x18\a \x03(\tR\x0eacceptEncoding\"Z\n" +
 	"\x0eResponseForGet\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\fR\x05value\")\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x16\n" +
+	"\x06stream\x18\x02 \x01(\bR\x06stream\x12\x1a\n" +
+	"\bencoding\x18\x03 \x01(\tR\bencoding\"\x95\x01\n" +
+	"\x05Chunk\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x15\n" +
+	"\x06ttl_ms\x18\x04 \x01(\x03R\x05ttlMs\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x05 \x01(\x03R\ttotalSize\x12\x1a\n" +
+	"\bencoding\x18\x06 \x01(\tR\bencoding\")\n" +
 	"\x11ResponseForDelete\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\bR\x05value2\x87\x01\n" +
+	"\x05value\x18\x01 \x01(\bR\x05value\"g\n" +
+	"\x10ResponseForLease\x12'\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x0f.pb.LeaseStatusR\x06status\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\x04R\x05token\"b\n" +
+	"\vIncrRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05delta\x18\x03 \x01(\x03R\x05delta\x12\x15\n" +
+	"\x06ttl_ms\x18\x04 \x01(\x03R\x05ttlMs\"'\n" +
+	"\x0fResponseForIncr\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x03R\x05value*P\n" +
+	"\vLeaseStatus\x12\r\n" +
+	"\tLEASE_HIT\x10\x00\x12\x11\n" +
+	"\rLEASE_GRANTED\x10\x01\x12\x0e\n" +
+	"\n" +
+	"LEASE_WAIT\x10\x02\x12\x0f\n" +
+	"\vLEASE_STALE\x10\x032\xb6\x02\n" +
 	"\aMyCache\x12&\n" +
 	"\x03Get\x12\v.pb.Request\x1a\x12.pb.ResponseForGet\x12&\n" +
 	"\x03Set\x12\v.pb.Request\x1a\x12.pb.ResponseForGet\x12,\n" +
-	"\x06Delete\x12\v.pb.Request\x1a\x15.pb.ResponseForDeleteB\x04Z\x02./b\x06proto3"
+	"\x06Delete\x12\v.pb.Request\x1a\x15.pb.ResponseForDelete\x12,\n" +
+	"\x04Incr\x12\x0f.pb.IncrRequest\x1a\x13.pb.ResponseForIncr\x12*\n" +
+	"\x05Lease\x12\v.pb.Request\x1a\x14.pb.ResponseForLease\x12%\n" +
+	"\tGetStream\x12\v.pb.Request\x1a\t.pb.Chunk0\x01\x12,\n" +
+	"\tSetStream\x12\t.pb.Chunk\x1a\x12.pb.ResponseForGet(\x01B\x04Z\x02./b\x06proto3"
 
 var (
 	file_mycache_proto_rawDescOnce sync.Once
@@ -199,24 +596,39 @@ func file_mycache_proto_rawDescGZIP() []byte {
 	return file_mycache_proto_rawDescData
 }
 
-var file_mycache_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_mycache_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_mycache_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_mycache_proto_goTypes = []any{
-	(*Request)(nil),           // 0: pb.Request
-	(*ResponseForGet)(nil),    // 1: pb.ResponseForGet
-	(*ResponseForDelete)(nil), // 2: pb.ResponseForDelete
+	(LeaseStatus)(0),          // 0: pb.LeaseStatus
+	(*Request)(nil),           // 1: pb.Request
+	(*ResponseForGet)(nil),    // 2: pb.ResponseForGet
+	(*Chunk)(nil),             // 3: pb.Chunk
+	(*ResponseForDelete)(nil), // 4: pb.ResponseForDelete
+	(*ResponseForLease)(nil),  // 5: pb.ResponseForLease
+	(*IncrRequest)(nil),       // 6: pb.IncrRequest
+	(*ResponseForIncr)(nil),   // 7: pb.ResponseForIncr
 }
 var file_mycache_proto_depIdxs = []int32{
-	0, // 0: pb.MyCache.Get:input_type -> pb.Request
-	0, // 1: pb.MyCache.Set:input_type -> pb.Request
-	0, // 2: pb.MyCache.Delete:input_type -> pb.Request
-	1, // 3: pb.MyCache.Get:output_type -> pb.ResponseForGet
-	1, // 4: pb.MyCache.Set:output_type -> pb.ResponseForGet
-	2, // 5: pb.MyCache.Delete:output_type -> pb.ResponseForDelete
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: pb.ResponseForLease.status:type_name -> pb.LeaseStatus
+	1, // 1: pb.MyCache.Get:input_type -> pb.Request
+	1, // 2: pb.MyCache.Set:input_type -> pb.Request
+	1, // 3: pb.MyCache.Delete:input_type -> pb.Request
+	6, // 4: pb.MyCache.Incr:input_type -> pb.IncrRequest
+	1, // 5: pb.MyCache.Lease:input_type -> pb.Request
+	1, // 6: pb.MyCache.GetStream:input_type -> pb.Request
+	3, // 7: pb.MyCache.SetStream:input_type -> pb.Chunk
+	2, // 8: pb.MyCache.Get:output_type -> pb.ResponseForGet
+	2, // 9: pb.MyCache.Set:output_type -> pb.ResponseForGet
+	4, // 10: pb.MyCache.Delete:output_type -> pb.ResponseForDelete
+	7, // 11: pb.MyCache.Incr:output_type -> pb.ResponseForIncr
+	5, // 12: pb.MyCache.Lease:output_type -> pb.ResponseForLease
+	3, // 13: pb.MyCache.GetStream:output_type -> pb.Chunk
+	2, // 14: pb.MyCache.SetStream:output_type -> pb.ResponseForGet
+	8, // [8:15] is the sub-list for method output_type
+	1, // [1:8] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_mycache_proto_init() }
@@ -229,13 +641,14 @@ func file_mycache_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mycache_proto_rawDesc), len(file_mycache_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      1,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_mycache_proto_goTypes,
 		DependencyIndexes: file_mycache_proto_depIdxs,
+		EnumInfos:         file_mycache_proto_enumTypes,
 		MessageInfos:      file_mycache_proto_msgTypes,
 	}.Build()
 	File_mycache_proto = out.File
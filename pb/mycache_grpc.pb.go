@@ -19,9 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MyCache_Get_FullMethodName    = "/pb.MyCache/Get"
-	MyCache_Set_FullMethodName    = "/pb.MyCache/Set"
-	MyCache_Delete_FullMethodName = "/pb.MyCache/Delete"
+	MyCache_Get_FullMethodName       = "/pb.MyCache/Get"
+	MyCache_Set_FullMethodName       = "/pb.MyCache/Set"
+	MyCache_Delete_FullMethodName    = "/pb.MyCache/Delete"
+	MyCache_Incr_FullMethodName      = "/pb.MyCache/Incr"
+	MyCache_Lease_FullMethodName     = "/pb.MyCache/Lease"
+	MyCache_GetStream_FullMethodName = "/pb.MyCache/GetStream"
+	MyCache_SetStream_FullMethodName = "/pb.MyCache/SetStream"
 )
 
 // MyCacheClient is the client API for MyCache service.
@@ -31,6 +35,10 @@ type MyCacheClient interface {
 	Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ResponseForGet, error)
 	Set(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ResponseForGet, error)
 	Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ResponseForDelete, error)
+	Incr(ctx context.Context, in *IncrRequest, opts ...grpc.CallOption) (*ResponseForIncr, error)
+	Lease(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ResponseForLease, error)
+	GetStream(ctx context.Context, in *Request, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error)
+	SetStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Chunk, ResponseForGet], error)
 }
 
 type myCacheClient struct {
@@ -71,6 +79,58 @@ func (c *myCacheClient) Delete(ctx context.Context, in *Request, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *myCacheClient) Incr(ctx context.Context, in *IncrRequest, opts ...grpc.CallOption) (*ResponseForIncr, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResponseForIncr)
+	err := c.cc.Invoke(ctx, MyCache_Incr_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *myCacheClient) Lease(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ResponseForLease, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResponseForLease)
+	err := c.cc.Invoke(ctx, MyCache_Lease_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *myCacheClient) GetStream(ctx context.Context, in *Request, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MyCache_ServiceDesc.Streams[0], MyCache_GetStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Request, Chunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MyCache_GetStreamClient = grpc.ServerStreamingClient[Chunk]
+
+func (c *myCacheClient) SetStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Chunk, ResponseForGet], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MyCache_ServiceDesc.Streams[1], MyCache_SetStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Chunk, ResponseForGet]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MyCache_SetStreamClient = grpc.ClientStreamingClient[Chunk, ResponseForGet]
+
 // MyCacheServer is the server API for MyCache service.
 // All implementations must embed UnimplementedMyCacheServer
 // for forward compatibility.
@@ -78,6 +138,10 @@ type MyCacheServer interface {
 	Get(context.Context, *Request) (*ResponseForGet, error)
 	Set(context.Context, *Request) (*ResponseForGet, error)
 	Delete(context.Context, *Request) (*ResponseForDelete, error)
+	Incr(context.Context, *IncrRequest) (*ResponseForIncr, error)
+	Lease(context.Context, *Request) (*ResponseForLease, error)
+	GetStream(*Request, grpc.ServerStreamingServer[Chunk]) error
+	SetStream(grpc.ClientStreamingServer[Chunk, ResponseForGet]) error
 	mustEmbedUnimplementedMyCacheServer()
 }
 
@@ -97,6 +161,18 @@ func (UnimplementedMyCacheServer) Set(context.Context, *Request) (*ResponseForGe
 func (UnimplementedMyCacheServer) Delete(context.Context, *Request) (*ResponseForDelete, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedMyCacheServer) Incr(context.Context, *IncrRequest) (*ResponseForIncr, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Incr not implemented")
+}
+func (UnimplementedMyCacheServer) Lease(context.Context, *Request) (*ResponseForLease, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lease not implemented")
+}
+func (UnimplementedMyCacheServer) GetStream(*Request, grpc.ServerStreamingServer[Chunk]) error {
+	return status.Errorf(codes.Unimplemented, "method GetStream not implemented")
+}
+func (UnimplementedMyCacheServer) SetStream(grpc.ClientStreamingServer[Chunk, ResponseForGet]) error {
+	return status.Errorf(codes.Unimplemented, "method SetStream not implemented")
+}
 func (UnimplementedMyCacheServer) mustEmbedUnimplementedMyCacheServer() {}
 func (UnimplementedMyCacheServer) testEmbeddedByValue()                 {}
 
@@ -172,6 +248,60 @@ func _MyCache_Delete_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MyCache_Incr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MyCacheServer).Incr(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MyCache_Incr_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MyCacheServer).Incr(ctx, req.(*IncrRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MyCache_Lease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MyCacheServer).Lease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MyCache_Lease_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MyCacheServer).Lease(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MyCache_GetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Request)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MyCacheServer).GetStream(m, &grpc.GenericServerStream[Request, Chunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MyCache_GetStreamServer = grpc.ServerStreamingServer[Chunk]
+
+func _MyCache_SetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MyCacheServer).SetStream(&grpc.GenericServerStream[Chunk, ResponseForGet]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MyCache_SetStreamServer = grpc.ClientStreamingServer[Chunk, ResponseForGet]
+
 // MyCache_ServiceDesc is the grpc.ServiceDesc for MyCache service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,7 +321,26 @@ var MyCache_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _MyCache_Delete_Handler,
 		},
+		{
+			MethodName: "Incr",
+			Handler:    _MyCache_Incr_Handler,
+		},
+		{
+			MethodName: "Lease",
+			Handler:    _MyCache_Lease_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetStream",
+			Handler:       _MyCache_GetStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SetStream",
+			Handler:       _MyCache_SetStream_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "mycache.proto",
 }
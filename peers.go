@@ -22,11 +22,21 @@ type PeerPicker interface {
 	Close() error
 }
 
+// OwnerPicker 可选接口，返回 key 的所有者而不进行故障转移。
+// 不幂等或要求唯一所有者的操作（Set、Delete、Incr、租约和严格所有者加载）只能由所有者处理，
+// 所有者不可用时返回 ErrOwnerUnavailable，self 为 true 表示由本节点处理
+type OwnerPicker interface {
+	PickOwner(key string) (peer Peer, self bool, err error)
+}
+
 // Peer 定义了缓存节点的接口
 type Peer interface {
-	Get(group string, key string) ([]byte, error)
-	Set(ctx context.Context, group string, key string, value []byte) error
-	Delete(group string, key string) (bool, error)
+	Get(ctx context.Context, group string, key string) ([]byte, error)
+	Set(ctx context.Context, group string, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, group string, key string) (bool, error)
+	Incr(ctx context.Context, group string, key string, delta int64, ttl time.Duration) (int64, error)
+	Lease(ctx context.Context, group string, key string) (LeaseResult, error)
+	SetWithLease(ctx context.Context, group string, key string, value []byte, token uint64) error
 	Close() error
 }
 
@@ -40,6 +50,11 @@ type ClientPicker struct {
 	etcdCli  *clientv3.Client
 	ctx      context.Context
 	cancel   context.CancelFunc
+
+	clientOpts []ClientOption      // 创建Client时使用的选项
+	hedge      *HedgeOptions       // 对冲请求配置，nil表示不启用
+	health     *HealthCheckOptions // 健康检查配置，nil表示不启用
+	metrics    *rpcMetrics         // 选择器创建的所有客户端的 RPC 指标
 }
 
 // PickerOption 定义配置选项
@@ -52,6 +67,13 @@ func WithServiceName(name string) PickerOption {
 	}
 }
 
+// WithClientOptions 设置创建节点Client时使用的选项
+func WithClientOptions(opts ...ClientOption) PickerOption {
+	return func(p *ClientPicker) {
+		p.clientOpts = append(p.clientOpts, opts...)
+	}
+}
+
 // PrintPeers 打印当前已发现的节点（仅用于调试）
 func (p *ClientPicker) PrintPeers() {
 	p.mu.RLock()
@@ -65,20 +87,31 @@ func (p *ClientPicker) PrintPeers() {
 
 // NewClientPicker 创建新的ClientPicker实例
 func NewClientPicker(addr string, opts ...PickerOption) (*ClientPicker, error) {
+	// 与注册到etcd中的地址保持一致，否则无法识别自身
+	selfAddr, err := registry.ResolveAddr(addr)
+	if err != nil {
+		logrus.Warnf("failed to resolve self address %s: %v", addr, err)
+		selfAddr = addr
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	picker := &ClientPicker{
-		selfAddr: addr,
+		selfAddr: selfAddr,
 		svcName:  defaultSvcName,
 		clients:  make(map[string]*Client),
 		consHash: consistenthash.New(),
 		ctx:      ctx,
 		cancel:   cancel,
+		metrics:  newRPCMetrics(),
 	}
 
 	for _, opt := range opts {
 		opt(picker)
 	}
 
+	// 自身也参与哈希环，所有节点对 key 的所有者达成一致
+	picker.consHash.Add(picker.selfAddr)
+
 	cli, err := clientv3.New(clientv3.Config{
 		Endpoints:   registry.DefaultConfig.Endpoints,
 		DialTimeout: registry.DefaultConfig.DialTimeout,
@@ -96,6 +129,10 @@ func NewClientPicker(addr string, opts ...PickerOption) (*ClientPicker, error) {
 		return nil, err
 	}
 
+	if picker.health != nil && picker.health.Interval > 0 {
+		go picker.runHealthChecks()
+	}
+
 	return picker, nil
 }
 
@@ -133,8 +170,12 @@ func (p *ClientPicker) handleWatchEvents(events []*clientv3.Event) {
 	defer p.mu.Unlock()
 
 	for _, event := range events {
+		// 删除事件不携带值，地址只能从 key 中解析
 		addr := string(event.Kv.Value)
-		if addr == p.selfAddr {
+		if event.Type == clientv3.EventTypeDelete {
+			addr = parseAddrFromKey(string(event.Kv.Key), p.svcName)
+		}
+		if addr == "" || addr == p.selfAddr {
 			continue
 		}
 
@@ -179,7 +220,12 @@ func (p *ClientPicker) fetchAllServices() error {
 
 // set 添加服务实例
 func (p *ClientPicker) set(addr string) {
-	if client, err := NewClient(addr, p.svcName, p.etcdCli); err == nil {
+	if client, err := NewClient(addr, p.svcName, p.etcdCli, p.clientOpts...); err == nil {
+		if p.health != nil {
+			client.health = newPeerHealth(addr, *p.health)
+		}
+		client.metrics = p.metrics
+		client.peer = true
 		p.consHash.Add(addr)
 		p.clients[addr] = client
 		logrus.Infof("Successfully created client for %s", addr)
@@ -194,17 +240,82 @@ func (p *ClientPicker) remove(addr string) {
 	delete(p.clients, addr)
 }
 
-// PickPeer 选择peer节点
+// PickOwner 实现 OwnerPicker 接口，所有者熔断或不健康时返回 ErrOwnerUnavailable
+func (p *ClientPicker) PickOwner(key string) (Peer, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	addr := p.consHash.Get(key)
+	if addr == "" || addr == p.selfAddr {
+		return nil, true, nil
+	}
+
+	client, ok := p.clients[addr]
+	if !ok || !client.Available() {
+		return nil, false, fmt.Errorf("%w: %s", ErrOwnerUnavailable, addr)
+	}
+	return client, false, nil
+}
+
+// PickPeer 选择peer节点，所有者熔断时依次选择哈希环上的后继节点。
+// 故障转移只适用于幂等的 Get，其他操作使用 PickOwner
 func (p *ClientPicker) PickPeer(key string) (Peer, bool, bool) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	if addr := p.consHash.Get(key); addr != "" {
-		if client, ok := p.clients[addr]; ok {
-			return client, true, addr == p.selfAddr
+	addr := p.consHash.Get(key)
+	if addr == "" {
+		return nil, false, false
+	}
+	if addr == p.selfAddr {
+		return nil, true, true
+	}
+
+	primary, ok := p.clients[addr]
+	if !ok {
+		return nil, false, false
+	}
+	if primary.Available() && p.hedge == nil {
+		return primary, true, false
+	}
+
+	// 沿哈希环查找可用的后继节点，遇到自身时由本地处理
+	var candidates []*Client
+	for _, next := range p.consHash.GetN(key, len(p.clients)+1)[1:] {
+		if next == p.selfAddr {
+			break
 		}
+		if c, ok := p.clients[next]; ok && c.Available() {
+			candidates = append(candidates, c)
+			if len(candidates) == 2 {
+				break
+			}
+		}
+	}
+
+	if !primary.Available() {
+		if len(candidates) == 0 {
+			// 没有可用的远程后继节点，由本地处理
+			return nil, true, true
+		}
+		logrus.Debugf("[KamaCache] peer %s is unavailable, using %s", addr, candidates[0].addr)
+		primary, candidates = candidates[0], candidates[1:]
+	}
+
+	if p.hedge != nil && len(candidates) > 0 {
+		return &hedgedPeer{Client: primary, secondary: candidates[0], opts: *p.hedge}, true, false
+	}
+	return primary, true, false
+}
+
+// Leave 将自身从哈希环上移除，之后所有 key 都由其他节点负责，用于下线前移交数据
+func (p *ClientPicker) Leave() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.consHash.Remove(p.selfAddr); err == nil {
+		logrus.Infof("[KamaCache] %s left the hash ring", p.selfAddr)
 	}
-	return nil, false, false
 }
 
 // Close 关闭所有资源
@@ -0,0 +1,273 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SuperJinggg/mycache-go/consistenthash"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// newTestPicker 创建不连接 etcd 的节点选择器，self 和 peers 都加入哈希环
+func newTestPicker(t *testing.T, self string, peers ...string) *ClientPicker {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ClientPicker{
+		selfAddr: self,
+		svcName:  "picker-test",
+		consHash: consistenthash.New(),
+		clients:  make(map[string]*Client),
+		ctx:      ctx,
+		cancel:   cancel,
+		metrics:  newRPCMetrics(),
+	}
+	p.consHash.Add(self)
+	for _, addr := range peers {
+		p.set(addr)
+	}
+	t.Cleanup(func() {
+		cancel()
+		for _, c := range p.clients {
+			c.Close()
+		}
+	})
+	return p
+}
+
+// keyOwnedBy 返回哈希环上由 addr 负责的一个 key
+func keyOwnedBy(t *testing.T, p *ClientPicker, addr string) string {
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if p.consHash.Get(key) == addr {
+			return key
+		}
+	}
+	t.Fatalf("找不到由 %s 负责的 key", addr)
+	return ""
+}
+
+// tripBreaker 使节点的熔断器打开
+func tripBreaker(c *Client) {
+	for i := 0; i < DefaultBreakerOptions().FailureThreshold; i++ {
+		c.breaker.onFailure()
+	}
+}
+
+// 测试注销的节点从哈希环和客户端中移除，删除事件不携带值
+func TestPickerWatchEvents(t *testing.T) {
+	p := newTestPicker(t, "127.0.0.1:1")
+	addr := "127.0.0.1:2"
+	key := []byte("/services/" + p.svcName + "/" + addr)
+
+	p.handleWatchEvents([]*clientv3.Event{{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: key, Value: []byte(addr)}}})
+	owned := keyOwnedBy(t, p, addr)
+	if peer, self, err := p.PickOwner(owned); err != nil || self || peer.(*Client).addr != addr {
+		t.Fatalf("注册后应选择新节点，实际为 %v, %v, %v", peer, self, err)
+	}
+
+	p.handleWatchEvents([]*clientv3.Event{{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: key}}})
+	if _, ok := p.clients[addr]; ok {
+		t.Fatal("注销后应关闭并移除客户端")
+	}
+	if peer, self, err := p.PickOwner(owned); err != nil || !self || peer != nil {
+		t.Fatalf("注销后 key 应由本节点负责，实际为 %v, %v, %v", peer, self, err)
+	}
+}
+
+// 测试所有者不可用时 Get 故障转移而只能由所有者处理的操作返回 ErrOwnerUnavailable
+func TestPickerFailover(t *testing.T) {
+	p := newTestPicker(t, "127.0.0.1:1", "127.0.0.1:2", "127.0.0.1:3")
+	owner := "127.0.0.1:2"
+	key := keyOwnedBy(t, p, owner)
+
+	t.Run("所有者可用", func(t *testing.T) {
+		peer, ok, self := p.PickPeer(key)
+		if !ok || self || peer.(*Client).addr != owner {
+			t.Fatalf("应选择所有者 %s", owner)
+		}
+		if peer, self, err := p.PickOwner(key); err != nil || self || peer.(*Client).addr != owner {
+			t.Fatalf("PickOwner 应返回所有者: %v", err)
+		}
+	})
+
+	tripBreaker(p.clients[owner])
+
+	t.Run("Get 转移到后继节点", func(t *testing.T) {
+		peer, ok, self := p.PickPeer(key)
+		if !ok {
+			t.Fatal("应选择后继节点或本节点")
+		}
+		if !self && peer.(*Client).addr == owner {
+			t.Fatal("不应选择熔断的所有者")
+		}
+	})
+
+	t.Run("只能由所有者处理的操作不转移", func(t *testing.T) {
+		if _, _, err := p.PickOwner(key); !errors.Is(err, ErrOwnerUnavailable) {
+			t.Fatalf("应返回 ErrOwnerUnavailable，实际为 %v", err)
+		}
+
+		g := NewGroup("picker-failover-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+			return nil, ErrNotFound
+		}), WithPeers(p))
+		t.Cleanup(func() { g.Close() })
+
+		if _, err := g.Incr(context.Background(), key, 1, 0); !errors.Is(err, ErrOwnerUnavailable) {
+			t.Errorf("Incr 应返回 ErrOwnerUnavailable，实际为 %v", err)
+		}
+		if n, err := g.Handoff(context.Background()); n != 0 || err != nil {
+			t.Errorf("本地没有数据时移交应成功: %d, %v", n, err)
+		}
+	})
+}
+
+// 测试开启对冲请求时选择主节点和备用节点
+func TestPickerHedging(t *testing.T) {
+	p := newTestPicker(t, "127.0.0.1:1", "127.0.0.1:2", "127.0.0.1:3", "127.0.0.1:4")
+	p.hedge = &HedgeOptions{Delay: 10 * time.Millisecond}
+	owner := "127.0.0.1:2"
+	key := keyOwnedBy(t, p, owner)
+
+	peer, ok, self := p.PickPeer(key)
+	if !ok || self {
+		t.Fatal("应选择远程节点")
+	}
+	hedged, isHedged := peer.(*hedgedPeer)
+	if !isHedged {
+		// 环上所有者之后紧接着本节点时没有备用节点
+		if next := p.consHash.GetN(key, 2); len(next) < 2 || next[1] != p.selfAddr {
+			t.Fatalf("存在远程后继节点时应返回对冲节点，实际为 %T", peer)
+		}
+		return
+	}
+	if hedged.Client.addr != owner || hedged.secondary.addr == owner {
+		t.Errorf("主节点应为所有者，备用节点应为其他节点: %s, %s", hedged.Client.addr, hedged.secondary.addr)
+	}
+	if hp, _, err := p.PickOwner(key); err != nil || hp.(*Client).addr != owner {
+		t.Errorf("PickOwner 不应返回对冲节点: %T, %v", hp, err)
+	}
+}
+
+// 测试对冲延迟在没有耗时估计时不触发
+func TestHedgeDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    HedgeOptions
+		samples int
+		want    time.Duration
+		wantOK  bool
+	}{
+		{"固定延迟", HedgeOptions{Delay: 5 * time.Millisecond}, 0, 5 * time.Millisecond, true},
+		{"样本不足时不按延迟对冲", HedgeOptions{}, 5, 0, false},
+		{"样本不足时下限也不生效", HedgeOptions{MinDelay: time.Millisecond}, 0, 0, false},
+		{"使用 p95", HedgeOptions{}, 20, 19 * time.Millisecond, true},
+		{"不低于下限", HedgeOptions{MinDelay: 50 * time.Millisecond}, 20, 50 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &hedgedPeer{Client: &Client{latency: newLatencyWindow()}, opts: tt.opts}
+			for i := 1; i <= tt.samples; i++ {
+				h.Client.latency.record(time.Duration(i) * time.Millisecond)
+			}
+			if got, ok := h.delay(); got != tt.want || ok != tt.wantOK {
+				t.Errorf("对冲延迟应为 %v, %v，实际为 %v, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+// 测试熔断器的状态转换
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     func(b *circuitBreaker)
+		wantAllow bool
+		wantOpen  bool
+	}{
+		{"未达到阈值", func(b *circuitBreaker) { b.onFailure() }, true, false},
+		{"达到阈值后打开", func(b *circuitBreaker) { b.onFailure(); b.onFailure() }, false, true},
+		{"成功后重新计数", func(b *circuitBreaker) { b.onFailure(); b.onSuccess(); b.onFailure() }, true, false},
+		{"超时后放行试探请求", func(b *circuitBreaker) {
+			b.onFailure()
+			b.onFailure()
+			time.Sleep(30 * time.Millisecond)
+		}, true, true},
+		{"试探失败后重新打开", func(b *circuitBreaker) {
+			b.onFailure()
+			b.onFailure()
+			time.Sleep(30 * time.Millisecond)
+			b.allow()
+			b.onFailure()
+		}, false, true},
+		{"试探成功后关闭", func(b *circuitBreaker) {
+			b.onFailure()
+			b.onFailure()
+			time.Sleep(30 * time.Millisecond)
+			b.allow()
+			b.onSuccess()
+		}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(BreakerOptions{FailureThreshold: 2, OpenTimeout: 20 * time.Millisecond})
+			tt.steps(b)
+			if got := b.allow(); got != tt.wantAllow {
+				t.Errorf("allow 应为 %v，实际为 %v", tt.wantAllow, got)
+			}
+			if got := b.isOpen(); got != tt.wantOpen {
+				t.Errorf("isOpen 应为 %v，实际为 %v", tt.wantOpen, got)
+			}
+		})
+	}
+}
+
+// 测试按失败比例熔断
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes string // s 表示成功，f 表示失败，i 表示不计入统计
+		wantOpen bool
+	}{
+		{"请求数不足时不熔断", "fff", false},
+		{"失败比例达到阈值后熔断", "sfsf", true},
+		{"失败比例未达到阈值", "ssfsfs", false},
+		{"成功不会清空失败次数", "ffsf", true},
+		{"不计入统计的结果", "fiifif", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(BreakerOptions{FailureRate: 0.5, MinRequests: 4, Window: time.Minute, OpenTimeout: time.Minute})
+			for _, o := range tt.outcomes {
+				switch o {
+				case 's':
+					b.onSuccess()
+				case 'f':
+					b.onFailure()
+				case 'i':
+					b.onIgnored()
+				}
+			}
+			if got := b.isOpen(); got != tt.wantOpen {
+				t.Errorf("isOpen 应为 %v，实际为 %v", tt.wantOpen, got)
+			}
+		})
+	}
+
+	t.Run("窗口结束后重新统计", func(t *testing.T) {
+		b := newCircuitBreaker(BreakerOptions{FailureRate: 0.5, MinRequests: 2, Window: 20 * time.Millisecond})
+		b.onFailure()
+		time.Sleep(30 * time.Millisecond)
+		b.onSuccess()
+		b.onSuccess()
+		b.onFailure()
+		if b.isOpen() {
+			t.Error("上一个窗口的失败不应计入")
+		}
+	})
+}
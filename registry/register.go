@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,72 +23,131 @@ var DefaultConfig = &Config{
 	DialTimeout: 5 * time.Second,
 }
 
-// Register 注册服务到etcd
-func Register(svcName, addr string, stopCh <-chan error) error {
-	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   DefaultConfig.Endpoints,
-		DialTimeout: DefaultConfig.DialTimeout,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create etcd client: %v", err)
+// Registration 表示一次服务注册，持有租约并在后台续期
+type Registration struct {
+	cli     *clientv3.Client
+	ownsCli bool // 是否由本注册创建 etcd 客户端，注销时需要关闭
+	leaseID clientv3.LeaseID
+	key     string
+	cancel  context.CancelFunc // 停止续期
+	once    sync.Once
+}
+
+// NewRegistration 使用给定的 etcd 客户端注册服务，cli 为 nil 时使用 DefaultConfig 创建客户端
+func NewRegistration(cli *clientv3.Client, svcName, addr string) (*Registration, error) {
+	ownsCli := false
+	if cli == nil {
+		var err error
+		cli, err = clientv3.New(clientv3.Config{
+			Endpoints:   DefaultConfig.Endpoints,
+			DialTimeout: DefaultConfig.DialTimeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create etcd client: %v", err)
+		}
+		ownsCli = true
+	}
+	closeCli := func() {
+		if ownsCli {
+			cli.Close()
+		}
 	}
 
-	localIP, err := getLocalIP()
+	addr, err := ResolveAddr(addr)
 	if err != nil {
-		cli.Close()
-		return fmt.Errorf("failed to get local IP: %v", err)
-	}
-	if addr[0] == ':' {
-		addr = fmt.Sprintf("%s%s", localIP, addr)
+		closeCli()
+		return nil, err
 	}
 
 	// 创建租约
 	lease, err := cli.Grant(context.Background(), 10) // 增加租约时间到10秒
 	if err != nil {
-		cli.Close()
-		return fmt.Errorf("failed to create lease: %v", err)
+		closeCli()
+		return nil, fmt.Errorf("failed to create lease: %v", err)
 	}
 
 	// 注册服务，使用完整的key路径
 	key := fmt.Sprintf("/services/%s/%s", svcName, addr)
 	_, err = cli.Put(context.Background(), key, addr, clientv3.WithLease(lease.ID))
 	if err != nil {
-		cli.Close()
-		return fmt.Errorf("failed to put key-value to etcd: %v", err)
+		closeCli()
+		return nil, fmt.Errorf("failed to put key-value to etcd: %v", err)
 	}
 
 	// 保持租约
-	keepAliveCh, err := cli.KeepAlive(context.Background(), lease.ID)
+	ctx, cancel := context.WithCancel(context.Background())
+	keepAliveCh, err := cli.KeepAlive(ctx, lease.ID)
 	if err != nil {
-		cli.Close()
-		return fmt.Errorf("failed to keep lease alive: %v", err)
+		cancel()
+		closeCli()
+		return nil, fmt.Errorf("failed to keep lease alive: %v", err)
 	}
 
-	// 处理租约续期和服务注销
+	// 处理租约续期
 	go func() {
-		defer cli.Close()
-		for {
-			select {
-			case <-stopCh:
-				// 服务注销，撤销租约
-				ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-				cli.Revoke(ctx, lease.ID)
-				cancel()
-				return
-			case resp, ok := <-keepAliveCh:
-				if !ok {
-					logrus.Warn("keep alive channel closed")
-					return
-				}
-				logrus.Debugf("successfully renewed lease: %d", resp.ID)
-			}
+		for resp := range keepAliveCh {
+			logrus.Debugf("successfully renewed lease: %d", resp.ID)
+		}
+		if ctx.Err() == nil {
+			logrus.Warn("keep alive channel closed")
 		}
 	}()
 
 	logrus.Infof("Service registered: %s at %s", svcName, addr)
+	return &Registration{
+		cli:     cli,
+		ownsCli: ownsCli,
+		leaseID: lease.ID,
+		key:     key,
+		cancel:  cancel,
+	}, nil
+}
+
+// Deregister 撤销租约并删除注册信息，可以重复调用
+func (r *Registration) Deregister(ctx context.Context) error {
+	var err error
+	r.once.Do(func() {
+		r.cancel()
+		if _, rerr := r.cli.Revoke(ctx, r.leaseID); rerr != nil {
+			err = fmt.Errorf("failed to revoke lease: %v", rerr)
+		}
+		if r.ownsCli {
+			r.cli.Close()
+		}
+		logrus.Infof("Service deregistered: %s", r.key)
+	})
+	return err
+}
+
+// Register 注册服务到etcd，stopCh 关闭或收到值时注销服务
+func Register(svcName, addr string, stopCh <-chan error) error {
+	reg, err := NewRegistration(nil, svcName, addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		reg.Deregister(ctx)
+	}()
 	return nil
 }
 
+// ResolveAddr 将 ":port" 形式的地址补全为本机IP，与注册到etcd中的地址保持一致
+func ResolveAddr(addr string) (string, error) {
+	if addr == "" || addr[0] != ':' {
+		return addr, nil
+	}
+
+	localIP, err := getLocalIP()
+	if err != nil {
+		return "", fmt.Errorf("failed to get local IP: %v", err)
+	}
+	return fmt.Sprintf("%s%s", localIP, addr), nil
+}
+
 func getLocalIP() (string, error) {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
@@ -0,0 +1,771 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cache "github.com/SuperJinggg/mycache-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// command 命令定义
+type command struct {
+	arity   int  // 参数个数（含命令名），负数表示至少 -arity 个
+	noAuth  bool // 认证前是否允许执行
+	handler func(c *conn, args [][]byte)
+}
+
+// commands 支持的命令
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"PING":    {-1, true, cmdPing},
+		"ECHO":    {2, false, cmdEcho},
+		"QUIT":    {1, true, cmdQuit},
+		"HELLO":   {-1, true, cmdHello},
+		"AUTH":    {-2, true, cmdAuth},
+		"SELECT":  {2, false, cmdSelect},
+		"CLIENT":  {-2, false, cmdClient},
+		"COMMAND": {-1, false, cmdCommand},
+		"GET":     {2, false, cmdGet},
+		"SET":     {-3, false, cmdSet},
+		"SETEX":   {4, false, cmdSetEx},
+		"PSETEX":  {4, false, cmdSetEx},
+		"DEL":     {-2, false, cmdDel},
+		"UNLINK":  {-2, false, cmdDel},
+		"MGET":    {-2, false, cmdMGet},
+		"MSET":    {-3, false, cmdMSet},
+		"EXISTS":  {-2, false, cmdExists},
+		"TTL":     {2, false, cmdTTL},
+		"PTTL":    {2, false, cmdTTL},
+		"INCR":    {2, false, cmdIncr},
+		"DECR":    {2, false, cmdIncr},
+		"INCRBY":  {3, false, cmdIncr},
+		"DECRBY":  {3, false, cmdIncr},
+		"SCAN":    {-2, false, cmdScan},
+		"KEYS":    {2, false, cmdKeys},
+		"DBSIZE":  {1, false, cmdDBSize},
+		"INFO":    {-1, false, cmdInfo},
+	}
+}
+
+// dispatch 执行一条命令
+func (c *conn) dispatch(args [][]byte) {
+	name := strings.ToUpper(string(args[0]))
+	cmd, ok := commands[name]
+	if !ok {
+		c.w.writeError(fmt.Sprintf("unknown command '%s'", args[0]))
+		return
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		c.w.writeError(fmt.Sprintf("wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return
+	}
+	if c.srv.opts.authenticator != nil && !c.authenticated && !cmd.noAuth {
+		c.w.writeError("NOAUTH Authentication required.")
+		return
+	}
+	cmd.handler(c, args)
+}
+
+// resolve 根据键前缀或当前选择的组确定组和组内的键
+func (c *conn) resolve(key string) (*cache.Group, string, error) {
+	if sep := c.srv.opts.keyPrefixSep; sep != "" {
+		if prefix, rest, ok := strings.Cut(key, sep); ok {
+			if g := c.srv.opts.node.GetGroup(prefix); g != nil {
+				return g, rest, nil
+			}
+		}
+	}
+
+	g := c.currentGroup()
+	if g == nil {
+		return nil, "", errors.New("no group selected, use SELECT or a group key prefix")
+	}
+	return g, key, nil
+}
+
+// currentGroup 返回当前选择的组
+func (c *conn) currentGroup() *cache.Group {
+	if c.db >= len(c.srv.opts.databases) {
+		return nil
+	}
+	return c.srv.opts.node.GetGroup(c.srv.opts.databases[c.db])
+}
+
+// allow 检查当前身份对组的操作权限，无权限时写入错误
+func (c *conn) allow(g *cache.Group, op cache.Operation) bool {
+	authz := c.srv.opts.authorizer
+	if c.srv.opts.authenticator == nil || authz == nil || authz.Authorize(c.identity, g.Name(), op) {
+		return true
+	}
+	c.w.writeError(fmt.Sprintf("NOPERM %s is not allowed to %s on group %s", c.identity, op, g.Name()))
+	return false
+}
+
+// resolveFor 确定组并检查权限，失败时写入错误
+func (c *conn) resolveFor(key []byte, op cache.Operation) (*cache.Group, string, bool) {
+	g, k, err := c.resolve(string(key))
+	if err != nil {
+		c.w.writeError(err.Error())
+		return nil, "", false
+	}
+	if !c.allow(g, op) {
+		return nil, "", false
+	}
+	return g, k, true
+}
+
+// writeErr 将缓存错误转换为 Redis 错误
+func (c *conn) writeErr(err error) {
+	switch {
+	case errors.Is(err, cache.ErrNotInteger):
+		c.w.writeError("value is not an integer or out of range")
+	case errors.Is(err, cache.ErrPermissionDenied):
+		c.w.writeError("NOPERM " + err.Error())
+	case errors.Is(err, cache.ErrUnauthenticated):
+		c.w.writeError("NOAUTH " + err.Error())
+	default:
+		c.w.writeError(err.Error())
+	}
+}
+
+func cmdPing(c *conn, args [][]byte) {
+	if len(args) > 2 {
+		c.w.writeError("wrong number of arguments for 'ping' command")
+		return
+	}
+	if len(args) == 2 {
+		c.w.writeBulk(args[1])
+		return
+	}
+	c.w.writeSimple("PONG")
+}
+
+func cmdEcho(c *conn, args [][]byte) {
+	c.w.writeBulk(args[1])
+}
+
+func cmdQuit(c *conn, args [][]byte) {
+	c.w.writeSimple("OK")
+	c.quit = true
+}
+
+// cmdHello HELLO [protover [AUTH username password] [SETNAME clientname]]
+func cmdHello(c *conn, args [][]byte) {
+	proto := c.w.proto
+	if len(args) > 1 {
+		v, err := strconv.Atoi(string(args[1]))
+		if err != nil {
+			c.w.writeError("Protocol version is not an integer or out of range")
+			return
+		}
+		if v != 2 && v != 3 {
+			c.w.writeError("NOPROTO unsupported protocol version")
+			return
+		}
+		proto = v
+	}
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "AUTH":
+			if i+2 >= len(args) {
+				c.w.writeError("syntax error")
+				return
+			}
+			if !c.authenticate(args[i+2]) {
+				return
+			}
+			i += 2
+		case "SETNAME":
+			if i+1 >= len(args) {
+				c.w.writeError("syntax error")
+				return
+			}
+			c.name = string(args[i+1])
+			i++
+		default:
+			c.w.writeError("syntax error")
+			return
+		}
+	}
+
+	if c.srv.opts.authenticator != nil && !c.authenticated {
+		c.w.writeError("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+		return
+	}
+
+	c.w.proto = proto
+	c.w.writeMapLen(7)
+	c.w.writeBulkString("server")
+	c.w.writeBulkString("mycache")
+	c.w.writeBulkString("version")
+	c.w.writeBulkString("7.0.0")
+	c.w.writeBulkString("proto")
+	c.w.writeInt(int64(proto))
+	c.w.writeBulkString("id")
+	c.w.writeInt(c.id)
+	c.w.writeBulkString("mode")
+	c.w.writeBulkString("cluster")
+	c.w.writeBulkString("role")
+	c.w.writeBulkString("master")
+	c.w.writeBulkString("modules")
+	c.w.writeArrayLen(0)
+}
+
+// cmdAuth AUTH [username] password
+func cmdAuth(c *conn, args [][]byte) {
+	if len(args) > 3 {
+		c.w.writeError("syntax error")
+		return
+	}
+	if c.srv.opts.authenticator == nil {
+		c.w.writeError("AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+		return
+	}
+	if c.authenticate(args[len(args)-1]) {
+		c.w.writeSimple("OK")
+	}
+}
+
+// authenticate 将密码作为 Bearer 令牌认证，失败时写入错误
+func (c *conn) authenticate(password []byte) bool {
+	if c.srv.opts.authenticator == nil {
+		c.authenticated = true
+		return true
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+string(password)))
+	identity, err := c.srv.opts.authenticator.Authenticate(ctx)
+	if err != nil {
+		c.w.writeError("WRONGPASS invalid username-password pair or user is disabled.")
+		return false
+	}
+	c.identity, c.authenticated = identity, true
+	return true
+}
+
+func cmdSelect(c *conn, args [][]byte) {
+	db, err := strconv.Atoi(string(args[1]))
+	if err != nil {
+		c.w.writeError("value is not an integer or out of range")
+		return
+	}
+	if db < 0 || db >= len(c.srv.opts.databases) {
+		c.w.writeError("DB index is out of range")
+		return
+	}
+	c.db = db
+	c.w.writeSimple("OK")
+}
+
+// cmdClient 只实现客户端库连接时常用的子命令
+func cmdClient(c *conn, args [][]byte) {
+	switch strings.ToUpper(string(args[1])) {
+	case "ID":
+		c.w.writeInt(c.id)
+	case "GETNAME":
+		if c.name == "" {
+			c.w.writeNull()
+			return
+		}
+		c.w.writeBulkString(c.name)
+	case "SETNAME":
+		if len(args) != 3 {
+			c.w.writeError("wrong number of arguments for 'client|setname' command")
+			return
+		}
+		c.name = string(args[2])
+		c.w.writeSimple("OK")
+	default:
+		c.w.writeSimple("OK")
+	}
+}
+
+// cmdCommand 客户端库用于获取命令信息，返回空列表
+func cmdCommand(c *conn, args [][]byte) {
+	if len(args) > 1 && strings.ToUpper(string(args[1])) == "COUNT" {
+		c.w.writeInt(int64(len(commands)))
+		return
+	}
+	c.w.writeArrayLen(0)
+}
+
+// cmdGet 读取值，未命中时通过 Getter 加载
+func cmdGet(c *conn, args [][]byte) {
+	g, key, ok := c.resolveFor(args[1], cache.OpGet)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	view, err := g.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			c.w.writeNull()
+			return
+		}
+		c.writeErr(err)
+		return
+	}
+	c.w.writeBulkView(view)
+}
+
+// cmdSet SET key value [EX seconds | PX milliseconds]
+func cmdSet(c *conn, args [][]byte) {
+	var ttl time.Duration
+	for i := 3; i < len(args); i++ {
+		opt := strings.ToUpper(string(args[i]))
+		switch opt {
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				c.w.writeError("syntax error")
+				return
+			}
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				c.w.writeError("invalid expire time in 'set' command")
+				return
+			}
+			if opt == "EX" {
+				ttl = time.Duration(n) * time.Second
+			} else {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+			i++
+		default:
+			// NX、XX、GET 等依赖原子的存在性判断，分布式缓存中无法保证
+			c.w.writeError("syntax error")
+			return
+		}
+	}
+
+	g, key, ok := c.resolveFor(args[1], cache.OpSet)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if err := g.SetWithTTL(ctx, key, encodeValue(args[2]), ttl); err != nil {
+		c.writeErr(err)
+		return
+	}
+	c.w.writeSimple("OK")
+}
+
+// cmdSetEx SETEX key seconds value / PSETEX key milliseconds value
+func cmdSetEx(c *conn, args [][]byte) {
+	unit := "EX"
+	if strings.ToUpper(string(args[0])) == "PSETEX" {
+		unit = "PX"
+	}
+	cmdSet(c, [][]byte{args[0], args[1], args[3], []byte(unit), args[2]})
+}
+
+// cmdDel 删除键，返回删除成功的数量（删除不存在的键也计入）
+func cmdDel(c *conn, args [][]byte) {
+	type target struct {
+		g   *cache.Group
+		key string
+	}
+
+	// 先检查所有键的权限，任意一个键没有权限时不删除任何键
+	targets := make([]target, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		g, key, ok := c.resolveFor(arg, cache.OpDelete)
+		if !ok {
+			return
+		}
+		targets = append(targets, target{g, key})
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	var n int64
+	for _, t := range targets {
+		if err := t.g.Delete(ctx, t.key); err != nil {
+			c.writeErr(err)
+			return
+		}
+		n++
+	}
+	c.w.writeInt(n)
+}
+
+func cmdMGet(c *conn, args [][]byte) {
+	type target struct {
+		g   *cache.Group
+		key string
+	}
+
+	// 先检查所有键的权限，避免写入一半的数组
+	targets := make([]target, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		g, key, ok := c.resolveFor(arg, cache.OpGet)
+		if !ok {
+			return
+		}
+		targets = append(targets, target{g, key})
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	c.w.writeArrayLen(len(targets))
+	for _, t := range targets {
+		view, err := t.g.Get(ctx, t.key)
+		if err != nil {
+			c.w.writeNull()
+			continue
+		}
+		c.w.writeBulkView(view)
+	}
+}
+
+func cmdMSet(c *conn, args [][]byte) {
+	if len(args)%2 != 1 {
+		c.w.writeError("wrong number of arguments for 'mset' command")
+		return
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	// 先检查所有键的权限，任意一个键没有权限时不写入任何键
+	type entry struct {
+		g     *cache.Group
+		key   string
+		value []byte
+	}
+	entries := make([]entry, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		g, key, ok := c.resolveFor(args[i], cache.OpSet)
+		if !ok {
+			return
+		}
+		entries = append(entries, entry{g: g, key: key, value: encodeValue(args[i+1])})
+	}
+
+	for _, e := range entries {
+		if err := e.g.Set(ctx, e.key, e.value); err != nil {
+			c.writeErr(err)
+			return
+		}
+	}
+	c.w.writeSimple("OK")
+}
+
+// cmdExists 返回存在的键数量，本地未缓存的键会通过所有者节点或 Getter 加载
+func cmdExists(c *conn, args [][]byte) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	var n int64
+	for _, arg := range args[1:] {
+		g, key, ok := c.resolveFor(arg, cache.OpGet)
+		if !ok {
+			return
+		}
+		if _, err := g.Get(ctx, key); err == nil {
+			n++
+		}
+	}
+	c.w.writeInt(n)
+}
+
+// cmdTTL 返回本节点缓存中的剩余过期时间，-1 表示永不过期，-2 表示本节点未缓存
+func cmdTTL(c *conn, args [][]byte) {
+	g, key, ok := c.resolveFor(args[1], cache.OpGet)
+	if !ok {
+		return
+	}
+
+	ttl, found := g.TTL(key)
+	switch {
+	case !found:
+		c.w.writeInt(-2)
+	case ttl == 0:
+		c.w.writeInt(-1)
+	case strings.ToUpper(string(args[0])) == "PTTL":
+		c.w.writeInt(ttl.Milliseconds())
+	default:
+		c.w.writeInt(int64((ttl + time.Second - 1) / time.Second))
+	}
+}
+
+// cmdIncr INCR/DECR/INCRBY/DECRBY，在所有者节点上原子执行
+func cmdIncr(c *conn, args [][]byte) {
+	name := strings.ToUpper(string(args[0]))
+	delta := int64(1)
+	if len(args) == 3 {
+		n, err := strconv.ParseInt(string(args[2]), 10, 64)
+		if err != nil {
+			c.w.writeError("value is not an integer or out of range")
+			return
+		}
+		delta = n
+	}
+	if name == "DECR" || name == "DECRBY" {
+		delta = -delta
+	}
+
+	g, key, ok := c.resolveFor(args[1], cache.OpSet)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	value, err := g.Incr(ctx, key, delta, 0)
+	if err != nil {
+		c.writeErr(err)
+		return
+	}
+	c.w.writeInt(value)
+}
+
+// localKeys 返回当前组在本节点缓存的键，按字典序排列
+func (c *conn) localKeys(pattern string) ([]string, bool) {
+	g := c.currentGroup()
+	if g == nil {
+		c.w.writeError("no group selected, use SELECT")
+		return nil, false
+	}
+	if !c.allow(g, cache.OpGet) {
+		return nil, false
+	}
+
+	var keys []string
+	g.Range(func(key string, _ cache.ByteView) bool {
+		if pattern == "" || matchGlob(pattern, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	sort.Strings(keys)
+	return keys, true
+}
+
+// cmdScan SCAN cursor [MATCH pattern] [COUNT count]，遍历当前组在本节点缓存的键。
+// 游标为 0 时生成排序后的键快照，之后的游标为快照中的位置，避免每次调用都遍历和排序所有键。
+// 快照之后写入的键不会返回，同一连接上交错的遍历会共用快照，可能导致键被跳过或重复返回
+func cmdScan(c *conn, args [][]byte) {
+	cursor, err := strconv.Atoi(string(args[1]))
+	if err != nil || cursor < 0 {
+		c.w.writeError("invalid cursor")
+		return
+	}
+
+	pattern, count := "", 10
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			c.w.writeError("syntax error")
+			return
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			count, err = strconv.Atoi(string(args[i+1]))
+			if err != nil || count <= 0 {
+				c.w.writeError("value is not an integer or out of range")
+				return
+			}
+		case "TYPE":
+			// 只有字符串类型
+			if strings.ToLower(string(args[i+1])) != "string" {
+				pattern = "\x00"
+			}
+		default:
+			c.w.writeError("syntax error")
+			return
+		}
+	}
+
+	if cursor == 0 || c.scanKeys == nil || c.scanDB != c.db {
+		keys, ok := c.localKeys("")
+		if !ok {
+			return
+		}
+		c.scanKeys, c.scanDB = keys, c.db
+	} else if !c.allow(c.currentGroup(), cache.OpGet) {
+		// 快照生成后可能通过 AUTH 切换了身份
+		return
+	}
+	keys := c.scanKeys
+
+	end := min(cursor+count, len(keys))
+	var page []string
+	for i := min(cursor, len(keys)); i < end; i++ {
+		if pattern == "" || matchGlob(pattern, keys[i]) {
+			page = append(page, keys[i])
+		}
+	}
+
+	next := end
+	if next >= len(keys) {
+		next = 0
+		c.scanKeys = nil
+	}
+	c.w.writeArrayLen(2)
+	c.w.writeBulkString(strconv.Itoa(next))
+	c.w.writeArrayLen(len(page))
+	for _, k := range page {
+		c.w.writeBulkString(k)
+	}
+}
+
+func cmdKeys(c *conn, args [][]byte) {
+	keys, ok := c.localKeys(string(args[1]))
+	if !ok {
+		return
+	}
+	c.w.writeArrayLen(len(keys))
+	for _, k := range keys {
+		c.w.writeBulkString(k)
+	}
+}
+
+// cmdDBSize 返回当前组在本节点缓存的键数量
+func cmdDBSize(c *conn, args [][]byte) {
+	keys, ok := c.localKeys("")
+	if !ok {
+		return
+	}
+	c.w.writeInt(int64(len(keys)))
+}
+
+// cmdInfo INFO [section]，统计信息来自各组的 Group.Stats
+func cmdInfo(c *conn, args [][]byte) {
+	section := "all"
+	if len(args) > 1 {
+		section = strings.ToLower(string(args[1]))
+	}
+	want := func(name string) bool {
+		return section == "all" || section == "default" || section == "everything" || section == name
+	}
+
+	var b strings.Builder
+	if want("server") {
+		b.WriteString("# Server\r\n")
+		b.WriteString("redis_version:7.0.0\r\n")
+		b.WriteString("mycache_mode:cluster\r\n")
+		fmt.Fprintf(&b, "uptime_in_seconds:%d\r\n", int64(time.Since(c.srv.started).Seconds()))
+		b.WriteString("\r\n")
+	}
+	if want("clients") {
+		c.srv.mu.Lock()
+		n := len(c.srv.conns)
+		c.srv.mu.Unlock()
+		fmt.Fprintf(&b, "# Clients\r\nconnected_clients:%d\r\n\r\n", n)
+	}
+	if want("stats") || want("keyspace") {
+		var stats, keyspace strings.Builder
+		for i, name := range c.srv.opts.databases {
+			g := c.srv.opts.node.GetGroup(name)
+			if g == nil {
+				continue
+			}
+			s := g.Stats()
+			keys := make([]string, 0, len(s))
+			for k := range s {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&stats, "%s_%s:%v\r\n", name, k, s[k])
+			}
+			fmt.Fprintf(&keyspace, "db%d:keys=%v,group=%s\r\n", i, s["cache_size"], name)
+		}
+		if want("stats") {
+			b.WriteString("# Stats\r\n" + stats.String() + "\r\n")
+		}
+		if want("keyspace") {
+			b.WriteString("# Keyspace\r\n" + keyspace.String() + "\r\n")
+		}
+	}
+	c.w.writeBulkString(b.String())
+}
+
+// matchGlob 按 Redis 的 glob 规则匹配，支持 *、?、[abc]、[^a-z] 和反斜杠转义
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// 没有闭合的方括号按普通字符处理
+				if s[0] != '[' {
+					return false
+				}
+				break
+			}
+			class := pattern[1 : end+1]
+			negate := len(class) > 0 && class[0] == '^'
+			if negate {
+				class = class[1:]
+			}
+			matched := false
+			for i := 0; i < len(class); i++ {
+				if i+2 < len(class) && class[i+1] == '-' {
+					if class[i] <= s[0] && s[0] <= class[i+2] {
+						matched = true
+					}
+					i += 2
+				} else if class[i] == s[0] {
+					matched = true
+				}
+			}
+			if matched == negate {
+				return false
+			}
+			pattern = pattern[end+1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
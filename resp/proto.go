@@ -0,0 +1,178 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	cache "github.com/SuperJinggg/mycache-go"
+)
+
+// errProtocol 客户端发送的数据不符合 RESP 协议
+var errProtocol = errors.New("protocol error")
+
+// maxInlineSize 内联命令和协议头的最大长度，与 Redis 一致
+const maxInlineSize = 64 << 10
+
+// readLine 读取一行并去掉结尾的 \r\n，超过 maxInlineSize 时返回协议错误，避免没有换行的输入无限占用内存
+func readLine(r *bufio.Reader) (string, error) {
+	var buf []byte
+	for {
+		part, err := r.ReadSlice('\n')
+		if len(buf)+len(part) > maxInlineSize {
+			return "", fmt.Errorf("%w: too big inline request", errProtocol)
+		}
+		buf = append(buf, part...)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
+
+// readCommand 读取一条命令，支持多条批量字符串组成的数组和内联命令（例如 telnet 输入）
+func readCommand(r *bufio.Reader, maxBulk int) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, nil
+	}
+
+	// 内联命令
+	if line[0] != '*' {
+		fields := strings.Fields(line)
+		args := make([][]byte, len(fields))
+		for i, f := range fields {
+			args[i] = []byte(f)
+		}
+		return args, nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > 1024*1024 {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+	}
+
+	args := make([][]byte, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" || line[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got '%s'", errProtocol, line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulk {
+			return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk not terminated by CRLF", errProtocol)
+		}
+		args[i] = buf[:size]
+	}
+	return args, nil
+}
+
+// writer 按客户端协商的协议版本（RESP2 或 RESP3）写入回复
+type writer struct {
+	w     *bufio.Writer
+	proto int
+}
+
+func (w *writer) writeSimple(s string) {
+	w.w.WriteString("+" + s + "\r\n")
+}
+
+// errorCodes 回复中使用的 Redis 错误码
+var errorCodes = map[string]bool{
+	"ERR":       true,
+	"NOAUTH":    true,
+	"NOPERM":    true,
+	"WRONGPASS": true,
+	"NOPROTO":   true,
+}
+
+func (w *writer) writeError(msg string) {
+	// 没有以错误码开头的错误加上通用的 ERR
+	if code, _, _ := strings.Cut(msg, " "); !errorCodes[code] {
+		msg = "ERR " + msg
+	}
+	w.w.WriteString("-" + strings.ReplaceAll(msg, "\r\n", " ") + "\r\n")
+}
+
+func (w *writer) writeInt(n int64) {
+	w.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (w *writer) writeBulk(b []byte) {
+	w.w.WriteString("$" + strconv.Itoa(len(b)) + "\r\n")
+	w.w.Write(b)
+	w.w.WriteString("\r\n")
+}
+
+// emptyValue 空字符串在缓存中的表示。Group 不接受空值，
+// 与 memcached 协议保存空值的方式相同（标志位为 0 的头部），两种协议可以互相读取
+var emptyValue = []byte("\x00mcf\x00\x00\x00\x00")
+
+// encodeValue 将空字符串替换为 emptyValue
+func encodeValue(b []byte) []byte {
+	if len(b) == 0 {
+		return emptyValue
+	}
+	return b
+}
+
+// writeBulkView 写入缓存值，直接从视图写出而不复制
+func (w *writer) writeBulkView(v cache.ByteView) {
+	if v.Len() == len(emptyValue) && v.String() == string(emptyValue) {
+		w.writeBulk(nil)
+		return
+	}
+	w.w.WriteString("$" + strconv.Itoa(v.Len()) + "\r\n")
+	v.WriteTo(w.w)
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) writeBulkString(s string) {
+	w.writeBulk([]byte(s))
+}
+
+// writeNull 写入空值，RESP2 中为空的批量字符串
+func (w *writer) writeNull() {
+	if w.proto >= 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w *writer) writeArrayLen(n int) {
+	w.w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+// writeMapLen 写入映射的长度，RESP2 中映射以键值交替的数组表示
+func (w *writer) writeMapLen(n int) {
+	if w.proto >= 3 {
+		w.w.WriteString("%" + strconv.Itoa(n) + "\r\n")
+		return
+	}
+	w.writeArrayLen(n * 2)
+}
+
+func (w *writer) flush() error {
+	return w.w.Flush()
+}
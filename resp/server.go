@@ -0,0 +1,278 @@
+// Package resp 实现 Redis RESP2/RESP3 协议前端，现有的 Redis 客户端可以直接访问 mycache 集群
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cache "github.com/SuperJinggg/mycache-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Server RESP 协议服务器，命令会转换为对应 Group 的操作，由 Group 负责路由到所有者节点
+type Server struct {
+	addr string
+	opts options
+
+	mu      sync.Mutex
+	ln      net.Listener
+	conns   map[*conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+	nextID  int64
+	started time.Time
+}
+
+// options 服务器配置
+type options struct {
+	databases      []string            // SELECT 的编号对应的组名，第一个为默认组
+	keyPrefixSep   string              // 通过 "组名<分隔符>键" 选择组，为空表示不启用
+	authenticator  cache.Authenticator // 认证方式，nil表示不认证
+	authorizer     cache.Authorizer    // 授权方式
+	commandTimeout time.Duration       // 单条命令的超时时间
+	maxBulkSize    int                 // 单个参数的最大长度
+	idleTimeout    time.Duration       // 连接空闲超时时间，0表示不超时
+	node           *cache.Node         // 提供组的节点
+}
+
+// Option 定义服务器的配置选项
+type Option func(*options)
+
+// WithDatabases 设置 SELECT 的编号对应的组，SELECT 0 对应 groups[0]，也是连接的默认组
+func WithDatabases(groups ...string) Option {
+	return func(o *options) {
+		o.databases = groups
+	}
+}
+
+// WithKeyPrefix 通过键前缀选择组，例如分隔符为 ":" 时 "users:42" 访问 users 组的 42，
+// 前缀不是已存在的组时使用当前选择的组
+func WithKeyPrefix(sep string) Option {
+	return func(o *options) {
+		o.keyPrefixSep = sep
+	}
+}
+
+// WithAuth 开启认证和按组授权，AUTH 命令的密码作为 Bearer 令牌交给 authn 认证
+func WithAuth(authn cache.Authenticator, authz cache.Authorizer) Option {
+	return func(o *options) {
+		o.authenticator = authn
+		o.authorizer = authz
+	}
+}
+
+// WithCommandTimeout 设置单条命令的超时时间
+func WithCommandTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.commandTimeout = d
+	}
+}
+
+// WithMaxBulkSize 设置单个参数的最大长度
+func WithMaxBulkSize(n int) Option {
+	return func(o *options) {
+		o.maxBulkSize = n
+	}
+}
+
+// WithIdleTimeout 设置连接空闲超时时间
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithNode 访问 node 上的组，默认为 cache.DefaultNode
+func WithNode(node *cache.Node) Option {
+	return func(o *options) {
+		o.node = node
+	}
+}
+
+// NewServer 创建 RESP 协议服务器
+func NewServer(addr string, opts ...Option) *Server {
+	o := options{
+		commandTimeout: 5 * time.Second,
+		maxBulkSize:    64 << 20, // 64MB
+		node:           cache.DefaultNode(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Server{
+		addr:  addr,
+		opts:  o,
+		conns: make(map[*conn]struct{}),
+	}
+}
+
+// ListenAndServe 监听地址并处理连接，Close 后返回 nil
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+	return s.Serve(ln)
+}
+
+// Serve 在已有的监听器上处理连接，Close 后返回 nil
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return nil
+	}
+	s.ln = ln
+	s.started = time.Now()
+	s.mu.Unlock()
+
+	logrus.Infof("[KamaCache] resp server listening at %s", ln.Addr())
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		c := s.newConn(nc)
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			nc.Close()
+			return nil
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Addr 返回实际监听的地址，未开始监听时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close 停止监听并关闭所有连接
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// conn 一个客户端连接及其会话状态
+type conn struct {
+	srv *Server
+	nc  net.Conn
+	r   *bufio.Reader
+	w   *writer
+	id  int64
+
+	db            int    // 当前选择的组编号
+	identity      string // 认证后的身份
+	authenticated bool
+	name          string // CLIENT SETNAME 设置的名称
+	quit          bool
+
+	scanKeys []string // SCAN 遍历的键快照，游标为 0 时重新生成，遍历结束后释放
+	scanDB   int      // 生成快照时选择的组编号
+}
+
+// newConn 创建连接
+func (s *Server) newConn(nc net.Conn) *conn {
+	return &conn{
+		srv: s,
+		nc:  nc,
+		r:   bufio.NewReader(nc),
+		w:   &writer{w: bufio.NewWriter(nc), proto: 2},
+		id:  atomic.AddInt64(&s.nextID, 1),
+	}
+}
+
+// serve 循环读取并执行命令，缓冲区中没有更多命令时才写回，以支持流水线
+func (c *conn) serve() {
+	defer c.nc.Close()
+
+	for !c.quit {
+		if c.srv.opts.idleTimeout > 0 {
+			c.nc.SetReadDeadline(time.Now().Add(c.srv.opts.idleTimeout))
+		}
+
+		args, err := readCommand(c.r, c.srv.opts.maxBulkSize)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.w.writeError("Protocol error: " + err.Error())
+				c.w.flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logrus.Debugf("[KamaCache] resp connection %s closed: %v", c.nc.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		c.dispatch(args)
+
+		if c.r.Buffered() == 0 || c.quit {
+			if err := c.w.flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// context 为单条命令创建上下文
+func (c *conn) context() (context.Context, context.CancelFunc) {
+	if c.srv.opts.commandTimeout > 0 {
+		return context.WithTimeout(context.Background(), c.srv.opts.commandTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
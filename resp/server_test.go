@@ -0,0 +1,271 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	cache "github.com/SuperJinggg/mycache-go"
+)
+
+// testClient 发送命令并将回复解析为便于比较的字符串
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *testClient) do(args ...string) string {
+	c.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.t.Fatalf("发送命令失败: %v", err)
+	}
+	return c.readReply()
+}
+
+// readReply 读取一个回复，数组和映射以 [a b] 的形式表示，空值为 (nil)
+func (c *testClient) readReply() string {
+	c.t.Helper()
+	line, err := readLine(c.r)
+	if err != nil {
+		c.t.Fatalf("读取回复失败: %v", err)
+	}
+	switch line[0] {
+	case '+', '-', ':':
+		return line
+	case '_':
+		return "(nil)"
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return "(nil)"
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			c.t.Fatalf("读取回复失败: %v", err)
+		}
+		return string(buf[:n])
+	case '*', '%':
+		n, _ := strconv.Atoi(line[1:])
+		if line[0] == '%' {
+			n *= 2
+		}
+		items := make([]string, n)
+		for i := range items {
+			items[i] = c.readReply()
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	}
+	c.t.Fatalf("无法识别的回复: %q", line)
+	return ""
+}
+
+// startServer 启动测试服务器并返回已连接的客户端
+func startServer(t *testing.T, opts ...Option) *testClient {
+	t.Helper()
+	srv := NewServer("127.0.0.1:0", opts...)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func newTestGroup(t *testing.T, name string) *cache.Group {
+	t.Helper()
+	g := cache.NewGroup(name, 1<<20, cache.GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if strings.HasPrefix(key, "db-") {
+			return []byte("loaded-" + key), nil
+		}
+		return nil, cache.ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+// 测试常用命令到 Group 操作的映射
+func TestCommands(t *testing.T) {
+	newTestGroup(t, "resp-a")
+	newTestGroup(t, "resp-b")
+	c := startServer(t, WithDatabases("resp-a", "resp-b"), WithKeyPrefix(":"))
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"PING"}, "+PONG"},
+		{[]string{"GET", "missing"}, "(nil)"},
+		{[]string{"GET", "db-1"}, "loaded-db-1"},
+		{[]string{"SET", "k1", "v1", "EX", "100"}, "+OK"},
+		{[]string{"GET", "k1"}, "v1"},
+		{[]string{"TTL", "k1"}, ":100"},
+		{[]string{"SET", "k2", "v2"}, "+OK"},
+		{[]string{"TTL", "k2"}, ":-1"},
+		{[]string{"TTL", "nope"}, ":-2"},
+		{[]string{"SET", "k3", "v3", "NX"}, "-ERR syntax error"},
+		{[]string{"MSET", "m1", "a", "m2", "b"}, "+OK"},
+		{[]string{"MGET", "m1", "missing", "m2"}, "[a (nil) b]"},
+		{[]string{"EXISTS", "m1", "missing", "db-2"}, ":2"},
+		{[]string{"INCR", "counter"}, ":1"},
+		{[]string{"INCRBY", "counter", "10"}, ":11"},
+		{[]string{"DECR", "counter"}, ":10"},
+		{[]string{"INCR", "k1"}, "-ERR value is not an integer or out of range"},
+		{[]string{"DEL", "m1", "m2"}, ":2"},
+		{[]string{"GET", "m1"}, "(nil)"},
+		{[]string{"SCAN", "0", "MATCH", "k*", "COUNT", "100"}, "[0 [k1 k2]]"},
+		{[]string{"SCAN", "0", "MATCH", "k*", "COUNT", "4"}, "[4 [k1]]"},
+		{[]string{"SET", "k0", "new"}, "+OK"},
+		{[]string{"SCAN", "4", "MATCH", "k*", "COUNT", "100"}, "[0 [k2]]"},
+		{[]string{"DEL", "k0"}, ":1"},
+		{[]string{"SET", "empty", ""}, "+OK"},
+		{[]string{"GET", "empty"}, ""},
+		{[]string{"MSET", "e2", ""}, "+OK"},
+		{[]string{"MGET", "empty", "e2"}, "[ ]"},
+		{[]string{"DEL", "empty", "e2"}, ":2"},
+		{[]string{"KEYS", "db-?"}, "[db-1 db-2]"},
+		{[]string{"SET", "resp-b:x", "from-prefix"}, "+OK"},
+		{[]string{"SELECT", "1"}, "+OK"},
+		{[]string{"GET", "x"}, "from-prefix"},
+		{[]string{"SELECT", "5"}, "-ERR DB index is out of range"},
+		{[]string{"NOSUCH"}, "-ERR unknown command 'NOSUCH'"},
+		{[]string{"GET"}, "-ERR wrong number of arguments for 'get' command"},
+	}
+	for _, tc := range cases {
+		if got := c.do(tc.args...); got != tc.want {
+			t.Fatalf("%v 应返回 %q，实际为 %q", tc.args, tc.want, got)
+		}
+	}
+}
+
+// 测试 RESP3 协商与认证
+func TestHelloAndAuth(t *testing.T) {
+	newTestGroup(t, "resp-auth")
+	c := startServer(t,
+		WithDatabases("resp-auth"),
+		WithAuth(cache.StaticTokenAuthenticator{"secret": "reader"}, cache.NewACL(
+			cache.ACLRule{Identity: "reader", Group: "resp-auth", Ops: []cache.Operation{cache.OpGet}},
+		)),
+	)
+
+	t.Run("认证前拒绝命令", func(t *testing.T) {
+		if got := c.do("GET", "k"); got != "-NOAUTH Authentication required." {
+			t.Fatalf("未认证时应返回 NOAUTH，实际为 %q", got)
+		}
+		if got := c.do("AUTH", "wrong"); !strings.HasPrefix(got, "-WRONGPASS") {
+			t.Fatalf("密码错误时应返回 WRONGPASS，实际为 %q", got)
+		}
+	})
+
+	t.Run("HELLO 3 认证并切换协议", func(t *testing.T) {
+		got := c.do("HELLO", "3", "AUTH", "default", "secret")
+		if !strings.Contains(got, "proto :3") {
+			t.Fatalf("HELLO 应返回协议版本 3，实际为 %q", got)
+		}
+		if got := c.do("GET", "missing"); got != "(nil)" {
+			t.Fatalf("RESP3 空值应为 _，实际为 %q", got)
+		}
+	})
+
+	t.Run("按组授权", func(t *testing.T) {
+		if got := c.do("SET", "k", "v"); !strings.HasPrefix(got, "-NOPERM") {
+			t.Fatalf("只读身份写入应返回 NOPERM，实际为 %q", got)
+		}
+	})
+}
+
+// 测试 MSET 和 DEL 在执行前检查所有键的权限
+func TestMSetPermission(t *testing.T) {
+	newTestGroup(t, "resp-rw")
+	ro := newTestGroup(t, "resp-ro")
+	c := startServer(t,
+		WithDatabases("resp-rw", "resp-ro"),
+		WithKeyPrefix(":"),
+		WithAuth(cache.StaticTokenAuthenticator{"secret": "writer"}, cache.NewACL(
+			cache.ACLRule{Identity: "writer", Group: "resp-rw"},
+			cache.ACLRule{Identity: "writer", Group: "resp-ro", Ops: []cache.Operation{cache.OpGet}},
+		)),
+	)
+
+	if got := c.do("AUTH", "secret"); got != "+OK" {
+		t.Fatalf("认证失败: %q", got)
+	}
+	if got := c.do("MSET", "a", "1", "resp-ro:b", "2"); !strings.HasPrefix(got, "-NOPERM") {
+		t.Fatalf("包含无权限的键时应返回 NOPERM，实际为 %q", got)
+	}
+	if got := c.do("GET", "a"); got != "(nil)" {
+		t.Fatalf("权限检查失败时不应写入任何键，实际为 %q", got)
+	}
+	if _, ok := ro.TTL("b"); ok {
+		t.Fatal("无权限的组不应被写入")
+	}
+
+	c.do("SET", "a", "1")
+	if got := c.do("DEL", "a", "resp-ro:b"); !strings.HasPrefix(got, "-NOPERM") {
+		t.Fatalf("包含无权限的键时应返回 NOPERM，实际为 %q", got)
+	}
+	if got := c.do("GET", "a"); got != "1" {
+		t.Fatalf("权限检查失败时不应删除任何键，实际为 %q", got)
+	}
+}
+
+// 测试过长的内联命令被拒绝
+func TestInlineLimit(t *testing.T) {
+	newTestGroup(t, "resp-inline")
+	c := startServer(t, WithDatabases("resp-inline"))
+
+	if _, err := c.conn.Write([]byte("PING\r\n")); err != nil {
+		t.Fatalf("发送命令失败: %v", err)
+	}
+	if got := c.readReply(); got != "+PONG" {
+		t.Fatalf("内联命令应返回 PONG，实际为 %q", got)
+	}
+
+	if _, err := c.conn.Write([]byte(strings.Repeat("a", 2*maxInlineSize))); err != nil {
+		t.Fatalf("发送命令失败: %v", err)
+	}
+	if got := c.readReply(); !strings.HasPrefix(got, "-ERR Protocol error") {
+		t.Fatalf("过长的内联命令应返回协议错误，实际为 %q", got)
+	}
+}
+
+// 测试 glob 匹配
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:42", true},
+		{"user:*", "order:1", false},
+		{"h?llo", "hello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"a/*", "a/b/c", true},
+	}
+	for _, tc := range cases {
+		if got := matchGlob(tc.pattern, tc.s); got != tc.want {
+			t.Fatalf("matchGlob(%q, %q) 应为 %v", tc.pattern, tc.s, tc.want)
+		}
+	}
+}
@@ -2,21 +2,24 @@ package kamacache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"slices"
 	"sync"
 	"time"
 
-	"crypto/tls"
-
 	pb "github.com/SuperJinggg/mycache-go/pb"
 	"github.com/SuperJinggg/mycache-go/registry"
 	"github.com/sirupsen/logrus"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 )
 
 // Server 定义缓存服务器
@@ -24,21 +27,44 @@ type Server struct {
 	pb.UnimplementedMyCacheServer
 	addr       string           // 服务地址
 	svcName    string           // 服务名称
-	groups     *sync.Map        // 缓存组
+	node       *Node            // 提供缓存组的节点
 	grpcServer *grpc.Server     // gRPC服务器
+	health     *health.Server   // 健康检查服务
 	etcdCli    *clientv3.Client // etcd客户端
-	stopCh     chan error       // 停止信号
 	opts       *ServerOptions   // 服务器选项
+
+	tlsReloader *certReloader  // 服务端证书，未启用TLS时为nil
+	httpMux     *http.ServeMux // HTTP 网关路由
+	httpServer  *http.Server   // HTTP 网关，未配置 HTTPAddr 时为nil
+
+	mu           sync.Mutex
+	registration *registry.Registration // 服务注册信息
+	shutdown     bool                   // 是否已开始关闭
 }
 
+// defaultShutdownTimeout Stop 等待关闭完成的最长时间
+const defaultShutdownTimeout = 10 * time.Second
+
 // ServerOptions 服务器配置选项
 type ServerOptions struct {
-	EtcdEndpoints []string      // etcd端点
-	DialTimeout   time.Duration // 连接超时
-	MaxMsgSize    int           // 最大消息大小
-	TLS           bool          // 是否启用TLS
-	CertFile      string        // 证书文件
-	KeyFile       string        // 密钥文件
+	EtcdEndpoints  []string      // etcd端点
+	DialTimeout    time.Duration // 连接超时
+	MaxMsgSize     int           // 最大消息大小，更大的值通过流式RPC分片传输
+	MaxStreamSize  int64         // 流式RPC写入的值的最大长度
+	TLS            bool          // 是否启用TLS
+	CertFile       string        // 证书文件
+	KeyFile        string        // 密钥文件
+	ClientCAFile   string        // 校验客户端证书的CA文件，配置后启用双向TLS
+	TLSReload      time.Duration // 检查证书文件变化的间隔，0表示不重新加载
+	Authenticator  Authenticator // 认证方式，nil表示不认证
+	Authorizer     Authorizer    // 授权方式，nil表示认证通过即可访问所有组
+	PeerIdentities []string      // 开启认证时集群中节点的身份，只有这些调用方可以转发需要持久化的写入或同步副本
+	DrainDelay     time.Duration // 注销后等待其他节点感知的时间，期间仍正常处理请求
+	Handoff        bool          // 关闭时是否将本地缓存的数据移交给新的所有者
+	HTTPAddr       string        // HTTP 网关监听地址，为空时不启动
+	MetricsPath    string        // HTTP 网关上提供指标的路径，为空时不提供
+
+	TracerProvider trace.TracerProvider // 链路追踪，nil表示不开启
 }
 
 // DefaultServerOptions 默认配置
@@ -46,6 +72,7 @@ var DefaultServerOptions = &ServerOptions{
 	EtcdEndpoints: []string{"localhost:2379"},
 	DialTimeout:   5 * time.Second,
 	MaxMsgSize:    4 << 20, // 4MB
+	MaxStreamSize: defaultMaxStreamSize,
 }
 
 // ServerOption 定义选项函数类型
@@ -65,6 +92,15 @@ func WithDialTimeout(timeout time.Duration) ServerOption {
 	}
 }
 
+// WithMaxStreamSize 设置通过流式RPC写入的值的最大长度，超过时拒绝，不在接收前按声明的长度分配内存
+func WithMaxStreamSize(n int64) ServerOption {
+	return func(o *ServerOptions) {
+		if n > 0 {
+			o.MaxStreamSize = n
+		}
+	}
+}
+
 // WithTLS 设置TLS配置
 func WithTLS(certFile, keyFile string) ServerOption {
 	return func(o *ServerOptions) {
@@ -74,11 +110,61 @@ func WithTLS(certFile, keyFile string) ServerOption {
 	}
 }
 
-// NewServer 创建新的服务器实例
+// WithMutualTLS 要求客户端提供由 caFile 签发的证书，需要同时配置 WithTLS
+func WithMutualTLS(caFile string) ServerOption {
+	return func(o *ServerOptions) {
+		o.ClientCAFile = caFile
+	}
+}
+
+// WithTLSReload 设置检查证书文件变化的间隔，证书轮换后无需重启服务
+func WithTLSReload(interval time.Duration) ServerOption {
+	return func(o *ServerOptions) {
+		o.TLSReload = interval
+	}
+}
+
+// WithAuth 开启认证和按组授权，authz 为 nil 时认证通过即可访问所有组
+func WithAuth(authn Authenticator, authz Authorizer) ServerOption {
+	return func(o *ServerOptions) {
+		o.Authenticator = authn
+		o.Authorizer = authz
+	}
+}
+
+// WithPeerIdentities 设置集群中节点的身份，开启认证时只信任这些调用方转发的写入和同步的副本，
+// 其他调用方的写入一律按客户端写入处理
+func WithPeerIdentities(identities ...string) ServerOption {
+	return func(o *ServerOptions) {
+		o.PeerIdentities = identities
+	}
+}
+
+// WithDrainDelay 设置关闭时从注册中心注销后等待其他节点感知的时间
+func WithDrainDelay(d time.Duration) ServerOption {
+	return func(o *ServerOptions) {
+		o.DrainDelay = d
+	}
+}
+
+// WithHandoff 关闭时将本地缓存的数据移交给新的所有者，避免滚动重启后大量回源
+func WithHandoff() ServerOption {
+	return func(o *ServerOptions) {
+		o.Handoff = true
+	}
+}
+
+// NewServer 创建访问默认节点上缓存组的服务器实例
 func NewServer(addr, svcName string, opts ...ServerOption) (*Server, error) {
-	options := DefaultServerOptions
+	return newServer(defaultNode, addr, svcName, opts...)
+}
+
+// newServer 创建访问 node 上缓存组的服务器
+func newServer(node *Node, addr, svcName string, opts ...ServerOption) (*Server, error) {
+	// 复制默认配置，避免选项修改全局默认值
+	options := *DefaultServerOptions
 	for _, opt := range opts {
-		opt(options)
+		opt(&options)
 	}
 
 	// 创建etcd客户端
@@ -94,31 +180,55 @@ func NewServer(addr, svcName string, opts ...ServerOption) (*Server, error) {
 	var serverOpts []grpc.ServerOption
 	serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(options.MaxMsgSize))
 
+	var tlsReloader *certReloader
 	if options.TLS {
-		creds, err := loadTLSCredentials(options.CertFile, options.KeyFile)
+		tlsReloader, err = loadTLSReloader(&options)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load TLS credentials: %v", err)
 		}
+		// 配置了客户端CA时要求并校验客户端证书，gRPC 要求协商 HTTP/2
+		creds := credentials.NewTLS(tlsReloader.serverConfig(options.ClientCAFile != "", "h2"))
 		serverOpts = append(serverOpts, grpc.Creds(creds))
 	}
 
+	// 错误转换放在最外层，保证所有错误都以带详情的状态码返回
+	interceptors := []grpc.UnaryServerInterceptor{errorUnaryInterceptor}
+	if options.TracerProvider != nil {
+		interceptors = append(interceptors, tracingUnaryServerInterceptor(options.TracerProvider.Tracer(tracerName)))
+	}
+	if options.Authenticator != nil {
+		interceptors = append(interceptors, authUnaryInterceptor(options.Authenticator, options.Authorizer))
+	}
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(interceptors...))
+
+	streamInterceptors := []grpc.StreamServerInterceptor{errorStreamInterceptor}
+	if options.TracerProvider != nil {
+		streamInterceptors = append(streamInterceptors, tracingStreamServerInterceptor(options.TracerProvider.Tracer(tracerName)))
+	}
+	if options.Authenticator != nil {
+		streamInterceptors = append(streamInterceptors, authStreamInterceptor(options.Authenticator, options.Authorizer))
+	}
+	serverOpts = append(serverOpts, grpc.ChainStreamInterceptor(streamInterceptors...))
+
 	srv := &Server{
 		addr:       addr,
 		svcName:    svcName,
-		groups:     &sync.Map{},
+		node:       node,
 		grpcServer: grpc.NewServer(serverOpts...),
 		etcdCli:    etcdCli,
-		stopCh:     make(chan error),
-		opts:       options,
+		opts:       &options,
+
+		tlsReloader: tlsReloader,
 	}
+	srv.httpMux = srv.newHTTPMux()
 
 	// 注册服务
 	pb.RegisterMyCacheServer(srv.grpcServer, srv)
 
 	// 注册健康检查服务
-	healthServer := health.NewServer()
-	healthpb.RegisterHealthServer(srv.grpcServer, healthServer)
-	healthServer.SetServingStatus(svcName, healthpb.HealthCheckResponse_SERVING)
+	srv.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv.grpcServer, srv.health)
+	srv.health.SetServingStatus(svcName, healthpb.HealthCheckResponse_SERVING)
 
 	return srv, nil
 }
@@ -131,58 +241,229 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
+	// 启动 HTTP 网关
+	if s.opts.HTTPAddr != "" {
+		if err := s.startHTTP(); err != nil {
+			lis.Close()
+			return err
+		}
+	}
+
 	// 注册到etcd
-	stopCh := make(chan error)
 	go func() {
-		if err := registry.Register(s.svcName, s.addr, stopCh); err != nil {
+		reg, err := registry.NewRegistration(s.etcdCli, s.svcName, s.addr)
+		if err != nil {
 			logrus.Errorf("failed to register service: %v", err)
-			close(stopCh)
 			return
 		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.shutdown {
+			// 注册完成前已经开始关闭
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			reg.Deregister(ctx)
+			return
+		}
+		s.registration = reg
 	}()
 
 	logrus.Infof("Server starting at %s", s.addr)
 	return s.grpcServer.Serve(lis)
 }
 
-// Stop 停止服务器
-func (s *Server) Stop() {
-	close(s.stopCh)
-	s.grpcServer.GracefulStop()
+// Drain 将健康状态设置为 NOT_SERVING，其他节点的健康检查随后会停止向本节点路由请求，
+// 已有请求仍会正常处理
+func (s *Server) Drain() {
+	s.health.SetServingStatus(s.svcName, healthpb.HealthCheckResponse_NOT_SERVING)
+	logrus.Infof("[KamaCache] server %s is draining", s.addr)
+}
+
+// Shutdown 优雅关闭服务器：从注册中心注销、将健康状态设置为 NOT_SERVING、
+// 按需移交数据、等待其他节点感知并处理完进行中的请求，ctx 到期后强制关闭。
+// 其他节点在 DrainDelay 内仍可能发来请求，这些请求会被正常处理
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return nil
+	}
+	s.shutdown = true
+	reg := s.registration
+	s.mu.Unlock()
+
+	// 1. 从注册中心注销，其他节点通过 watch 将本节点移出哈希环
+	if reg != nil {
+		if err := reg.Deregister(ctx); err != nil {
+			logrus.Warnf("[KamaCache] failed to deregister %s: %v", s.addr, err)
+		}
+	}
+
+	// 2. 健康检查返回 NOT_SERVING
+	s.Drain()
+
+	// 3. 将数据移交给新的所有者
+	if s.opts.Handoff {
+		s.handoff(ctx)
+	}
+
+	// 4. 等待其他节点感知本节点下线
+	if s.opts.DrainDelay > 0 {
+		timer := time.NewTimer(s.opts.DrainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	// 5. 等待进行中的请求完成，超时后强制关闭
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	s.mu.Lock()
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	var err error
+	if httpServer != nil {
+		if herr := httpServer.Shutdown(ctx); herr != nil {
+			httpServer.Close()
+			err = herr
+		}
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		<-done
+		err = ctx.Err()
+	}
+
 	if s.etcdCli != nil {
 		s.etcdCli.Close()
 	}
+	logrus.Infof("[KamaCache] server %s stopped", s.addr)
+	return err
+}
+
+// handoff 将所有组的本地数据移交给新的所有者
+func (s *Server) handoff(ctx context.Context) {
+	for _, name := range s.node.ListGroups() {
+		group := s.node.GetGroup(name)
+		if group == nil {
+			continue
+		}
+
+		// 先离开哈希环，PickPeer 才会返回新的所有者
+		if leaver, ok := group.peers.(interface{ Leave() }); ok {
+			leaver.Leave()
+		}
+
+		moved, err := group.Handoff(ctx)
+		if err != nil {
+			logrus.Warnf("[KamaCache] handoff of group [%s] incomplete: %v", name, err)
+		}
+		logrus.Infof("[KamaCache] handed off %d keys of group [%s]", moved, name)
+	}
+}
+
+// Stop 停止服务器，最多等待 defaultShutdownTimeout
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+	defer cancel()
+	s.Shutdown(ctx)
 }
 
 // Get 实现Cache服务的Get方法
 func (s *Server) Get(ctx context.Context, req *pb.Request) (*pb.ResponseForGet, error) {
-	group := GetGroup(req.Group)
+	group := s.node.GetGroup(req.Group)
 	if group == nil {
-		return nil, fmt.Errorf("group %s not found", req.Group)
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, req.Group)
 	}
 
-	view, err := group.Get(ctx, req.Key)
+	// 其他节点认为本节点是所有者，由本节点负责加载，避免在节点间来回转发；其他调用方按所有者路由
+	ctx = s.peerContext(ctx)
+
+	// 请求方需要版本号时从本节点读取并在回复头部返回，值已解压且不使用流式传输
+	if _, requested, _ := requestedVersion(ctx); requested {
+		view, version, err := group.GetWithVersion(ctx, req.Key)
+		if err != nil {
+			return nil, err
+		}
+		if err := grpc.SetHeader(ctx, versionHeader(version)); err != nil {
+			return nil, err
+		}
+		return &pb.ResponseForGet{Value: view.b}, nil
+	}
+
+	// 请求方支持时直接发送压缩后的数据，因此不通过 Get 解压
+	view, err := group.get(ctx, req.Key)
 	if err != nil {
 		return nil, err
 	}
+	if group.localOnly(view.Len()) {
+		return nil, ErrValueTooLarge
+	}
 
-	return &pb.ResponseForGet{Value: view.ByteSLice()}, nil
+	value, encoding, err := view.encode(req.AcceptEncoding)
+	if err != nil {
+		group.dropCorrupt(req.Key, err)
+		return nil, err
+	}
+
+	// 超过请求方阈值的值由请求方通过 GetStream 获取
+	if req.StreamThreshold > 0 && int64(len(value)) > req.StreamThreshold {
+		return &pb.ResponseForGet{Stream: true}, nil
+	}
+
+	return &pb.ResponseForGet{Value: value, Encoding: encoding}, nil
 }
 
 // Set 实现Cache服务的Set方法
 func (s *Server) Set(ctx context.Context, req *pb.Request) (*pb.ResponseForGet, error) {
-	group := GetGroup(req.Group)
+	group := s.node.GetGroup(req.Group)
 	if group == nil {
-		return nil, fmt.Errorf("group %s not found", req.Group)
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, req.Group)
 	}
 
-	// 从 context 中获取标记，如果没有则创建新的 context
-	fromPeer := ctx.Value("from_peer")
-	if fromPeer == nil {
-		ctx = context.WithValue(ctx, "from_peer", true)
+	// 按调用方是否为可信节点区分转发来的写入、同步来的副本和客户端直接写入
+	ctx = s.writeContext(ctx)
+
+	// 携带租约令牌的回写需要校验租约
+	if req.LeaseToken != 0 {
+		if err := group.SetWithLease(ctx, req.Key, req.Value, req.LeaseToken); err != nil {
+			return nil, err
+		}
+		return &pb.ResponseForGet{Value: req.Value}, nil
+	}
+
+	// 携带条件的写入在本节点检查条件，新值的版本号在回复头部返回
+	cond, err := requestedCondition(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %w", conditionMetadataKey, err)
+	}
+	if cond != 0 {
+		expected, _, err := requestedVersion(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s metadata: %w", versionMetadataKey, err)
+		}
+		version, err := group.SetIf(ctx, req.Key, req.Value, time.Duration(req.TtlMs)*time.Millisecond, cond, expected)
+		if err != nil {
+			return nil, err
+		}
+		if err := grpc.SetHeader(ctx, versionHeader(version)); err != nil {
+			return nil, err
+		}
+		return &pb.ResponseForGet{}, nil
 	}
 
-	if err := group.Set(ctx, req.Key, req.Value); err != nil {
+	if err := group.SetWithTTL(ctx, req.Key, req.Value, time.Duration(req.TtlMs)*time.Millisecond); err != nil {
 		return nil, err
 	}
 
@@ -191,22 +472,120 @@ func (s *Server) Set(ctx context.Context, req *pb.Request) (*pb.ResponseForGet,
 
 // Delete 实现Cache服务的Delete方法
 func (s *Server) Delete(ctx context.Context, req *pb.Request) (*pb.ResponseForDelete, error) {
-	group := GetGroup(req.Group)
+	group := s.node.GetGroup(req.Group)
 	if group == nil {
-		return nil, fmt.Errorf("group %s not found", req.Group)
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, req.Group)
 	}
 
-	err := group.Delete(ctx, req.Key)
+	err := group.Delete(s.writeContext(ctx), req.Key)
 	return &pb.ResponseForDelete{Value: err == nil}, err
 }
 
-// loadTLSCredentials 加载TLS证书
-func loadTLSCredentials(certFile, keyFile string) (credentials.TransportCredentials, error) {
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+// writeContext 按写入请求携带的元数据标记上下文，只信任 trustedPeer 认可的节点：
+// 其他节点转发来的客户端写入由本节点持久化，同步来的副本只更新缓存，
+// 其余写入与直接调用 Group 一样，由所有者持久化并同步给其他节点
+func (s *Server) writeContext(ctx context.Context) context.Context {
+	if ctx.Value("from_peer") != nil || !s.trustedPeer(ctx) {
+		return ctx
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	switch {
+	case len(md.Get(persistMetadataKey)) > 0:
+		return context.WithValue(context.WithValue(ctx, ownerWriteKey{}, true), "from_peer", true)
+	case len(md.Get(replicaMetadataKey)) > 0:
+		return context.WithValue(ctx, "from_peer", true)
+	}
+	return ctx
+}
+
+// peerContext 可信节点携带 peerMetadataKey 的请求标记为来自其他节点，由本节点直接处理；
+// 其他调用方的请求与直接调用 Group 一样按所有者路由
+func (s *Server) peerContext(ctx context.Context) context.Context {
+	if ctx.Value("from_peer") != nil || !s.trustedPeer(ctx) {
+		return ctx
+	}
+	if md, _ := metadata.FromIncomingContext(ctx); len(md.Get(peerMetadataKey)) > 0 {
+		return context.WithValue(ctx, "from_peer", true)
+	}
+	return ctx
+}
+
+// trustedPeer 判断调用方是否为集群中的节点。未开启认证时无法区分调用方，所有调用方都视为节点；
+// 开启认证后只有身份在 PeerIdentities 中的调用方才是节点
+func (s *Server) trustedPeer(ctx context.Context) bool {
+	if s.opts.Authenticator == nil {
+		return true
+	}
+	identity, ok := IdentityFromContext(ctx)
+	return ok && slices.Contains(s.opts.PeerIdentities, identity)
+}
+
+// Lease 实现Cache服务的Lease方法
+func (s *Server) Lease(ctx context.Context, req *pb.Request) (*pb.ResponseForLease, error) {
+	group := s.node.GetGroup(req.Group)
+	if group == nil {
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, req.Group)
+	}
+
+	res, err := group.Lease(ctx, req.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ResponseForLease{
+		Status: pb.LeaseStatus(res.Status),
+		Value:  res.Value.data(),
+		Token:  res.Token,
+	}, nil
+}
+
+// Incr 实现Cache服务的Incr方法
+func (s *Server) Incr(ctx context.Context, req *pb.IncrRequest) (*pb.ResponseForIncr, error) {
+	group := s.node.GetGroup(req.Group)
+	if group == nil {
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, req.Group)
+	}
+
+	// 其他节点转发来的请求在本节点执行，避免再次转发；其他调用方的请求转发给所有者
+	ctx = s.peerContext(ctx)
+
+	// 其他节点转发的 IncrWithFloor 携带下限，IncrIfExists 要求 key 已存在
+	floor, err := requestedFloor(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %w", incrFloorMetadataKey, err)
+	}
+
+	value, err := group.incr(ctx, req.Key, req.Delta, time.Duration(req.TtlMs)*time.Millisecond, floor, requestedExists(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ResponseForIncr{Value: value}, nil
+}
+
+// errorUnaryInterceptor 将处理函数返回的错误转换为 gRPC 状态错误
+func errorUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return resp, nil
+}
+
+// loadTLSReloader 加载服务端TLS证书
+func loadTLSReloader(opts *ServerOptions) (*certReloader, error) {
+	reloader, err := newCertReloader(TLSOptions{
+		CAFile:         opts.ClientCAFile,
+		CertFile:       opts.CertFile,
+		KeyFile:        opts.KeyFile,
+		ReloadInterval: opts.TLSReload,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return credentials.NewTLS(&tls.Config{
-		Certificates: []tls.Certificate{cert},
-	}), nil
+	if reloader.cert == nil {
+		return nil, errors.New("server certificate is required")
+	}
+	return reloader, nil
 }
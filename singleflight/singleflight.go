@@ -1,41 +1,174 @@
 package singleflight
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
 )
 
+// errGoexit 表示 fn 调用了 runtime.Goexit
+var errGoexit = errors.New("runtime.Goexit was called")
+
+// PanicError 表示 fn 执行过程中发生的 panic，会传递给所有等待者
+type PanicError struct {
+	Value interface{} // recover() 得到的值
+	Stack []byte      // panic 发生时的调用栈
+}
+
+// Error 实现 error 接口
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: panic in fn: %v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap 在 panic 值为 error 时返回该 error
+func (p *PanicError) Unwrap() error {
+	err, ok := p.Value.(error)
+	if !ok {
+		return nil
+	}
+	return err
+}
+
+// Result 保存 DoChan 的返回结果
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool // 结果是否被多个调用方共享
+}
+
 // 代表正在进行或已结束的请求
 type call struct {
-	wg  sync.WaitGroup
-	val interface{}
-	err error
+	done chan struct{} // fn 执行结束后关闭
+	val  interface{}
+	err  error
+
+	dups  int             // 共享该调用的其他调用方数量
+	chans []chan<- Result // 通过 DoChan 等待的调用方
 }
 
 // Group manages all kinds of calls
 type Group struct {
-	m sync.Map // 使用sync.Map来优化并发性能
+	mu sync.Mutex
+	m  map[string]*call
 }
 
 // Do 针对相同的key，保证多次调用Do()，都只会调用一次fn
-func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
-	// Check if there is already an ongoing call for this key
-	if existing, ok := g.m.Load(key); ok {
-		c := existing.(*call)
-		c.wg.Wait()         // Wait for the existing request to finish
-		return c.val, c.err // Return the result from the ongoing call
+// fn 发生 panic 时，所有等待者都会以 *PanicError 重新 panic
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
 	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.mu.Unlock()
 
-	// If no ongoing request, create a new one
-	c := &call{}
-	c.wg.Add(1)
-	g.m.Store(key, c) // Store the call in the map
+		<-c.done // 等待正在进行的请求结束
+		if e, ok := c.err.(*PanicError); ok {
+			panic(e)
+		}
+		return c.val, c.err, true
+	}
 
-	// Execute the function and set the result
-	c.val, c.err = fn()
-	c.wg.Done() // Mark the request as done
+	c := &call{done: make(chan struct{})}
+	g.m[key] = c
+	g.mu.Unlock()
 
-	// After the request is done, clean up the map
-	g.m.Delete(key)
+	g.doCall(c, key, fn)
+	if e, ok := c.err.(*PanicError); ok {
+		panic(e)
+	}
+	return c.val, c.err, c.dups > 0
+}
+
+// DoChan 与 Do 相同，但立即返回一个 channel，结果就绪后写入该 channel
+// fn 在新的 goroutine 中执行，发生 panic 时 Result.Err 为 *PanicError
+func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+		return ch
+	}
 
-	return c.val, c.err
+	c := &call{done: make(chan struct{}), chans: []chan<- Result{ch}}
+	g.m[key] = c
+	g.mu.Unlock()
+
+	go g.doCall(c, key, fn)
+
+	return ch
+}
+
+// DoContext 与 Do 相同，但在 ctx 结束时提前返回 ctx.Err()
+// 提前返回只会让当前调用方停止等待，不会中断共享的 fn，其他等待者仍能拿到结果
+func (g *Group) DoContext(ctx context.Context, key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	ch := g.DoChan(key, fn)
+
+	select {
+	case r := <-ch:
+		if e, ok := r.Err.(*PanicError); ok {
+			panic(e)
+		}
+		return r.Val, r.Err, r.Shared
+	case <-ctx.Done():
+		return nil, ctx.Err(), false
+	}
+}
+
+// Forget 让后续对 key 的调用不再等待正在进行的 fn，而是重新执行
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+}
+
+// doCall 执行 fn 并将结果通知所有等待者
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
+	normalReturn := false
+	recovered := false
+
+	defer func() {
+		// fn 既没有正常返回也没有 panic，说明调用了 runtime.Goexit
+		if !normalReturn && !recovered {
+			c.err = errGoexit
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		close(c.done)
+		// 调用过 Forget 后 key 可能已对应新的调用
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+		for _, ch := range c.chans {
+			ch <- Result{Val: c.val, Err: c.err, Shared: c.dups > 0}
+		}
+	}()
+
+	func() {
+		defer func() {
+			if !normalReturn {
+				if r := recover(); r != nil {
+					c.err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}
+		}()
+
+		c.val, c.err = fn()
+		normalReturn = true
+	}()
+
+	if !normalReturn {
+		recovered = true
+	}
 }
@@ -0,0 +1,157 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试并发调用只执行一次
+func TestDoDedup(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	var sharedCount int32
+	started := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started <- struct{}{}
+			v, err, shared := g.Do("key", fn)
+			if err != nil || v.(string) != "value" {
+				t.Errorf("返回值不一致: %v, %v", v, err)
+			}
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		<-started
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("fn 应只执行1次，实际执行%d次", got)
+	}
+	if atomic.LoadInt32(&sharedCount) != n {
+		t.Fatalf("所有调用方的 shared 都应为 true，实际为%d", sharedCount)
+	}
+}
+
+// 测试调用方取消时不影响共享的加载
+func TestDoContextCancel(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+
+	fn := func() (interface{}, error) {
+		<-release
+		return "value", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContext(ctx, "key", fn)
+		errCh <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	waiter := g.DoChan("key", fn)
+
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后应返回 context.Canceled，实际为 %v", err)
+	}
+
+	close(release)
+	select {
+	case r := <-waiter:
+		if r.Err != nil || r.Val.(string) != "value" || !r.Shared {
+			t.Fatalf("其他等待者应拿到共享结果: %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("等待共享结果超时")
+	}
+}
+
+// 测试 Forget 后重新执行
+func TestForget(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	var calls int32
+
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil, nil
+	}
+
+	first := g.DoChan("key", fn)
+	time.Sleep(20 * time.Millisecond)
+	g.Forget("key")
+	second := g.DoChan("key", fn)
+
+	close(release)
+	<-first
+	<-second
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("Forget 后应重新执行 fn，实际执行%d次", got)
+	}
+}
+
+// 测试 panic 传递给所有等待者
+func TestPanicPropagation(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+
+	fn := func() (interface{}, error) {
+		<-release
+		panic("boom")
+	}
+
+	waiter := g.DoChan("key", fn)
+	time.Sleep(20 * time.Millisecond)
+
+	panicCh := make(chan interface{}, 1)
+	go func() {
+		defer func() { panicCh <- recover() }()
+		g.Do("key", fn)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	r := <-waiter
+	var pe *PanicError
+	if !errors.As(r.Err, &pe) || pe.Value != "boom" {
+		t.Fatalf("DoChan 应收到 *PanicError，实际为 %v", r.Err)
+	}
+
+	if p, ok := (<-panicCh).(*PanicError); !ok || p.Value != "boom" {
+		t.Fatalf("Do 的等待者应以 *PanicError 重新 panic，实际为 %v", p)
+	}
+
+	// panic 之后 key 应被清理，可以再次执行
+	v, err, _ := g.Do("key", func() (interface{}, error) { return 1, nil })
+	if err != nil || v.(int) != 1 {
+		t.Fatalf("panic 后再次调用失败: %v, %v", v, err)
+	}
+}
@@ -0,0 +1,68 @@
+package store
+
+import "time"
+
+// EvictionReason 缓存项被移除的原因
+type EvictionReason int
+
+const (
+	// EvictionDeleted 被显式删除
+	EvictionDeleted EvictionReason = iota
+	// EvictionExpired 超过过期时间
+	EvictionExpired
+	// EvictionCapacity 超出容量被淘汰
+	EvictionCapacity
+	// EvictionCleared 被 Clear 清空
+	EvictionCleared
+
+	// NumEvictionReasons 移除原因的数量，新增的原因需放在它之前
+	NumEvictionReasons
+)
+
+// EvictionReasons 所有的移除原因
+var EvictionReasons = []EvictionReason{EvictionDeleted, EvictionExpired, EvictionCapacity, EvictionCleared}
+
+// String 返回移除原因的名称
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionDeleted:
+		return "deleted"
+	case EvictionExpired:
+		return "expired"
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionCleared:
+		return "cleared"
+	default:
+		return "unknown"
+	}
+}
+
+// Eviction 一次缓存项移除的信息
+type Eviction struct {
+	Key    string
+	Value  Value
+	Reason EvictionReason
+	Age    time.Duration // 写入后经过的时间
+	TTL    time.Duration // 写入时设置的过期时间，0表示永不过期
+}
+
+// evictionNotifier 按配置调用两种移除回调
+type evictionNotifier struct {
+	onEvicted  func(key string, value Value)
+	onEviction func(e Eviction)
+}
+
+// notify 通知一次移除，setAt 和 expireAt 为纳秒时间戳，expireAt 为0表示永不过期
+func (n evictionNotifier) notify(key string, value Value, reason EvictionReason, setAt, expireAt int64) {
+	if n.onEvicted != nil {
+		n.onEvicted(key, value)
+	}
+	if n.onEviction != nil {
+		e := Eviction{Key: key, Value: value, Reason: reason, Age: time.Duration(time.Now().UnixNano() - setAt)}
+		if expireAt > 0 {
+			e.TTL = time.Duration(expireAt - setAt)
+		}
+		n.onEviction(e)
+	}
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// 测试两种缓存实现上报的移除原因
+func TestEvictionReasons(t *testing.T) {
+	cases := []struct {
+		name      string
+		cacheType CacheType
+		opts      Options
+	}{
+		{"lru", LRU, Options{MaxBytes: 10, CleanupInterval: time.Hour}},
+		{"lru2", LRU2, Options{BucketCount: 1, CapPerBucket: 1, Level2Cap: 1, CleanupInterval: time.Hour}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			got := make(map[string]Eviction)
+			tc.opts.OnEviction = func(e Eviction) {
+				mu.Lock()
+				got[e.Key] = e
+				mu.Unlock()
+			}
+			lookup := func(key string) (Eviction, bool) {
+				mu.Lock()
+				defer mu.Unlock()
+				e, ok := got[key]
+				return e, ok
+			}
+
+			s := NewStore(tc.cacheType, tc.opts)
+			defer s.Close()
+
+			s.Set("a", testValue("1"))
+			s.Delete("a")
+			if e, ok := lookup("a"); !ok || e.Reason != EvictionDeleted {
+				t.Errorf("删除的原因应为 deleted，实际为 %v", e.Reason)
+			}
+
+			s.Set("b", testValue("12345"))
+			s.Set("c", testValue("12345"))
+			s.Set("d", testValue("12345"))
+			if e, ok := lookup("b"); !ok || e.Reason != EvictionCapacity {
+				t.Errorf("容量淘汰的原因应为 capacity，实际为 %v", e.Reason)
+			}
+
+			s.SetWithExpiration("e", testValue("1"), 50*time.Millisecond)
+			time.Sleep(300 * time.Millisecond)
+			if _, ok := s.Get("e"); ok {
+				t.Fatal("过期的项不应该被获取到")
+			}
+			deadline := time.Now().Add(time.Second)
+			e, ok := lookup("e")
+			for !ok && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+				e, ok = lookup("e")
+			}
+			if !ok || e.Reason != EvictionExpired {
+				t.Errorf("过期的原因应为 expired，实际为 %v", e.Reason)
+			}
+			if e.TTL != 50*time.Millisecond || e.Age < e.TTL {
+				t.Errorf("过期项的 TTL 和存活时间不正确: ttl=%v age=%v", e.TTL, e.Age)
+			}
+
+			s.Set("f", testValue("1"))
+			s.Clear()
+			if e, ok := lookup("f"); !ok || e.Reason != EvictionCleared {
+				t.Errorf("清空的原因应为 cleared，实际为 %v", e.Reason)
+			}
+		})
+	}
+}
+
+// 测试 EvictionReasons 包含所有的移除原因
+func TestEvictionReasonsComplete(t *testing.T) {
+	if len(EvictionReasons) != int(NumEvictionReasons) {
+		t.Fatalf("EvictionReasons has %d reasons, want %d", len(EvictionReasons), NumEvictionReasons)
+	}
+	for i, r := range EvictionReasons {
+		if r != EvictionReason(i) || r.String() == "unknown" {
+			t.Errorf("unexpected reason %v at %d", r, i)
+		}
+	}
+}
@@ -14,7 +14,7 @@ type lruCache struct {
 	expires         map[string]time.Time     // 过期时间映射
 	maxBytes        int64                    // 最大允许字节数
 	usedBytes       int64                    // 当前使用的字节数
-	onEvicted       func(key string, value Value)
+	evictions       evictionNotifier
 	cleanupInterval time.Duration
 	cleanupTicker   *time.Ticker
 	closeCh         chan struct{} // 用于优雅关闭清理协程
@@ -24,6 +24,7 @@ type lruCache struct {
 type lruEntry struct {
 	key   string
 	value Value
+	setAt time.Time // 写入时间
 }
 
 // newLRUCache 创建一个新的 LRU 缓存实例
@@ -39,7 +40,7 @@ func newLRUCache(opts Options) *lruCache {
 		items:           make(map[string]*list.Element),
 		expires:         make(map[string]time.Time),
 		maxBytes:        opts.MaxBytes,
-		onEvicted:       opts.OnEvicted,
+		evictions:       evictionNotifier{onEvicted: opts.OnEvicted, onEviction: opts.OnEviction},
 		cleanupInterval: cleanupInterval,
 		closeCh:         make(chan struct{}),
 	}
@@ -65,7 +66,7 @@ func (c *lruCache) Get(key string) (Value, bool) {
 		c.mu.RUnlock()
 
 		// 异步删除过期项，避免在读锁内操作
-		go c.Delete(key)
+		go c.remove(key, EvictionExpired)
 
 		return nil, false
 	}
@@ -102,9 +103,10 @@ func (c *lruCache) SetWithExpiration(key string, value Value, expiration time.Du
 	defer c.mu.Unlock()
 
 	// 计算过期时间
+	now := time.Now()
 	var expTime time.Time
 	if expiration > 0 {
-		expTime = time.Now().Add(expiration)
+		expTime = now.Add(expiration)
 		c.expires[key] = expTime
 	} else {
 		delete(c.expires, key)
@@ -113,17 +115,18 @@ func (c *lruCache) SetWithExpiration(key string, value Value, expiration time.Du
 	// 如果键已存在，更新值
 	if elem, ok := c.items[key]; ok {
 		oldEntry := elem.Value.(*lruEntry)
-		c.usedBytes += int64(value.Len() - oldEntry.value.Len())
+		c.usedBytes += int64(sizeOf(value) - sizeOf(oldEntry.value))
 		oldEntry.value = value
+		oldEntry.setAt = now
 		c.list.MoveToBack(elem)
 		return nil
 	}
 
 	// 添加新项
-	entry := &lruEntry{key: key, value: value}
+	entry := &lruEntry{key: key, value: value, setAt: now}
 	elem := c.list.PushBack(entry)
 	c.items[key] = elem
-	c.usedBytes += int64(len(key) + value.Len())
+	c.usedBytes += int64(len(key) + sizeOf(value))
 
 	// 检查是否需要淘汰旧项
 	c.evict()
@@ -133,14 +136,26 @@ func (c *lruCache) SetWithExpiration(key string, value Value, expiration time.Du
 
 // Delete 从缓存中删除指定键的项
 func (c *lruCache) Delete(key string) bool {
+	return c.remove(key, EvictionDeleted)
+}
+
+// remove 按指定原因删除缓存项
+func (c *lruCache) remove(key string, reason EvictionReason) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if elem, ok := c.items[key]; ok {
-		c.removeElement(elem)
-		return true
+	elem, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	// 异步删除过期项时，键可能已经被重新写入
+	if reason == EvictionExpired {
+		if expTime, hasExp := c.expires[key]; !hasExp || !time.Now().After(expTime) {
+			return false
+		}
 	}
-	return false
+	c.removeElement(elem, reason)
+	return true
 }
 
 // Clear 清空缓存
@@ -148,12 +163,10 @@ func (c *lruCache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// 如果设置了回调函数，遍历所有项调用回调
-	if c.onEvicted != nil {
-		for _, elem := range c.items {
-			entry := elem.Value.(*lruEntry)
-			c.onEvicted(entry.key, entry.value)
-		}
+	// 按淘汰顺序通知所有项被清空
+	for elem := c.list.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*lruEntry)
+		c.notify(entry, c.expires[entry.key], EvictionCleared)
 	}
 
 	c.list.Init()
@@ -170,16 +183,24 @@ func (c *lruCache) Len() int {
 }
 
 // removeElement 从缓存中删除元素，调用此方法前必须持有锁
-func (c *lruCache) removeElement(elem *list.Element) {
+func (c *lruCache) removeElement(elem *list.Element, reason EvictionReason) {
 	entry := elem.Value.(*lruEntry)
+	expTime := c.expires[entry.key]
 	c.list.Remove(elem)
 	delete(c.items, entry.key)
 	delete(c.expires, entry.key)
-	c.usedBytes -= int64(len(entry.key) + entry.value.Len())
+	c.usedBytes -= int64(len(entry.key) + sizeOf(entry.value))
+
+	c.notify(entry, expTime, reason)
+}
 
-	if c.onEvicted != nil {
-		c.onEvicted(entry.key, entry.value)
+// notify 通知缓存项被移除，expTime 为零值表示永不过期
+func (c *lruCache) notify(entry *lruEntry, expTime time.Time, reason EvictionReason) {
+	var expireAt int64
+	if !expTime.IsZero() {
+		expireAt = expTime.UnixNano()
 	}
+	c.evictions.notify(entry.key, entry.value, reason, entry.setAt.UnixNano(), expireAt)
 }
 
 // evict 清理过期和超出内存限制的缓存，调用此方法前必须持有锁
@@ -189,7 +210,7 @@ func (c *lruCache) evict() {
 	for key, expTime := range c.expires {
 		if now.After(expTime) {
 			if elem, ok := c.items[key]; ok {
-				c.removeElement(elem)
+				c.removeElement(elem, EvictionExpired)
 			}
 		}
 	}
@@ -198,7 +219,7 @@ func (c *lruCache) evict() {
 	for c.maxBytes > 0 && c.usedBytes > c.maxBytes && c.list.Len() > 0 {
 		elem := c.list.Front() // 获取最久未使用的项（链表头部）
 		if elem != nil {
-			c.removeElement(elem)
+			c.removeElement(elem, EvictionCapacity)
 		}
 	}
 }
@@ -225,6 +246,43 @@ func (c *lruCache) Close() {
 	}
 }
 
+// Range 遍历所有未过期的缓存项，遍历的是调用时的快照，fn 中可以安全地访问缓存
+func (c *lruCache) Range(fn func(key string, value Value) bool) {
+	c.mu.RLock()
+	now := time.Now()
+	entries := make([]*lruEntry, 0, c.list.Len())
+	for elem := c.list.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*lruEntry)
+		if expTime, hasExp := c.expires[entry.key]; hasExp && now.After(expTime) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	c.mu.RUnlock()
+
+	for _, entry := range entries {
+		if !fn(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+// Peek 实现Store接口，不改变缓存项的位置
+func (c *lruCache) Peek(key string) (Value, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	expTime, hasExp := c.expires[key]
+	if hasExp && time.Now().After(expTime) {
+		return nil, time.Time{}, false
+	}
+	return elem.Value.(*lruEntry).value, expTime, true
+}
+
 // GetWithExpiration 获取缓存项及其剩余过期时间
 func (c *lruCache) GetWithExpiration(key string) (Value, time.Duration, bool) {
 	c.mu.RLock()
@@ -1,7 +1,7 @@
 package store
 
 import (
-	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,9 +10,12 @@ import (
 type lru2Store struct {
 	locks       []sync.Mutex
 	caches      [][2]*cache
-	onEvicted   func(key string, value Value)
+	evictions   evictionNotifier
 	cleanupTick *time.Ticker
 	mask        int32
+	maxBytes    int64  // 最大字节数，不大于0表示只按每个桶的容量淘汰
+	usedBytes   int64  // 所有桶中有效项占用的字节数
+	cursor      uint32 // 超出最大字节数时下一个淘汰的桶
 }
 
 func newLRU2Cache(opts Options) *lru2Store {
@@ -33,14 +36,17 @@ func newLRU2Cache(opts Options) *lru2Store {
 	s := &lru2Store{
 		locks:       make([]sync.Mutex, mask+1),
 		caches:      make([][2]*cache, mask+1),
-		onEvicted:   opts.OnEvicted,
+		evictions:   evictionNotifier{onEvicted: opts.OnEvicted, onEviction: opts.OnEviction},
 		cleanupTick: time.NewTicker(opts.CleanupInterval),
 		mask:        int32(mask),
+		maxBytes:    opts.MaxBytes,
 	}
 
 	for i := range s.caches {
 		s.caches[i][0] = Create(opts.CapPerBucket)
 		s.caches[i][1] = Create(opts.Level2Cap)
+		s.caches[i][0].total = &s.usedBytes
+		s.caches[i][1].total = &s.usedBytes
 	}
 
 	if opts.CleanupInterval > 0 {
@@ -62,15 +68,16 @@ func (s *lru2Store) Get(key string) (Value, bool) {
 	if status1 > 0 {
 		// 从一级缓存找到项目
 		if expireAt > 0 && currentTime >= expireAt {
-			// 项目已过期，删除它
-			s.delete(key, idx)
-			fmt.Println("找到项目已过期，删除它")
+			// 项目已过期，一级缓存中已经移除，通知后清理二级缓存中可能残留的旧值
+			s.notify(key, n1.v, EvictionExpired, n1.setAt, expireAt)
+			s.caches[idx][1].del(key)
 			return nil, false
 		}
 
-		// 项目有效，将其移至二级缓存
-		s.caches[idx][1].put(key, n1.v, expireAt, s.onEvicted)
-		fmt.Println("项目有效，将其移至二级缓存")
+		// 项目有效，将其移至二级缓存，保留写入时间
+		if _, evicted, ok := s.caches[idx][1].insert(key, n1.v, expireAt, n1.setAt); ok {
+			s.evicted(evicted, currentTime)
+		}
 		return n1.v, true
 	}
 
@@ -79,8 +86,7 @@ func (s *lru2Store) Get(key string) (Value, bool) {
 	if status2 > 0 && n2 != nil {
 		if n2.expireAt > 0 && currentTime >= n2.expireAt {
 			// 项目已过期，删除它
-			s.delete(key, idx)
-			fmt.Println("找到项目已过期，删除它")
+			s.remove(key, idx, EvictionExpired)
 			return nil, false
 		}
 
@@ -90,13 +96,19 @@ func (s *lru2Store) Get(key string) (Value, bool) {
 	return nil, false
 }
 
+// neverExpire 永不过期的项使用的过期时间戳。
+// expireAt 为0的节点表示已删除，因此永不过期的项不能再用0表示，与 lruCache 一样 expiration 不大于0时永不过期
+const neverExpire = math.MaxInt64
+
+// Set 实现Store接口，添加永不过期的缓存项
 func (s *lru2Store) Set(key string, value Value) error {
-	return s.SetWithExpiration(key, value, 9999999999999999)
+	return s.SetWithExpiration(key, value, 0)
 }
 
+// SetWithExpiration 实现Store接口，expiration 不大于0表示永不过期
 func (s *lru2Store) SetWithExpiration(key string, value Value, expiration time.Duration) error {
-	// 计算过期时间 - 确保单位一致
-	expireAt := int64(0)
+	// 计算过期时间 - 确保单位一致，expiration 不大于0表示永不过期
+	expireAt := int64(neverExpire)
 	if expiration > 0 {
 		// now() 返回纳秒时间戳，确保 expiration 也是纳秒单位
 		expireAt = Now() + int64(expiration.Nanoseconds())
@@ -104,14 +116,43 @@ func (s *lru2Store) SetWithExpiration(key string, value Value, expiration time.D
 
 	idx := hashBKRD(key) & s.mask
 	s.locks[idx].Lock()
-	defer s.locks[idx].Unlock()
 
-	// 放入一级缓存
-	s.caches[idx][0].put(key, value, expireAt, s.onEvicted)
+	// 二级缓存中的旧值已失效，直接丢弃，新值放入一级缓存。
+	// 一级缓存满时直接淘汰最久未使用的项，只访问一次的键不会挤占二级缓存
+	s.caches[idx][1].del(key)
+	if _, evicted, ok := s.caches[idx][0].insert(key, value, expireAt, Now()); ok {
+		s.evicted(evicted, Now())
+	}
+	s.locks[idx].Unlock()
 
+	s.shrink()
 	return nil
 }
 
+// Peek 实现Store接口，不改变缓存项的位置，永不过期的项返回零值时间
+func (s *lru2Store) Peek(key string) (Value, time.Time, bool) {
+	idx := hashBKRD(key) & s.mask
+	s.locks[idx].Lock()
+	defer s.locks[idx].Unlock()
+
+	currentTime := Now()
+	for _, c := range s.caches[idx] {
+		i, ok := c.hmap[key]
+		if !ok {
+			continue
+		}
+		n := c.m[i-1]
+		if n.expireAt <= 0 || currentTime >= n.expireAt {
+			continue
+		}
+		if n.expireAt == neverExpire {
+			return n.v, time.Time{}, true
+		}
+		return n.v, time.Unix(0, n.expireAt), true
+	}
+	return nil, time.Time{}, false
+}
+
 // Delete 实现Store接口
 func (s *lru2Store) Delete(key string) bool {
 	idx := hashBKRD(key) & s.mask
@@ -123,9 +164,9 @@ func (s *lru2Store) Delete(key string) bool {
 
 // Clear 实现Store接口
 func (s *lru2Store) Clear() {
-	var keys []string
-
 	for i := range s.caches {
+		var keys []string
+
 		s.locks[i].Lock()
 
 		s.caches[i][0].walk(func(key string, value Value, expireAt int64) bool {
@@ -133,24 +174,17 @@ func (s *lru2Store) Clear() {
 			return true
 		})
 		s.caches[i][1].walk(func(key string, value Value, expireAt int64) bool {
-			// 检查键是否已经收集（避免重复）
-			for _, k := range keys {
-				if key == k {
-					return true
-				}
-			}
 			keys = append(keys, key)
 			return true
 		})
 
-		s.locks[i].Unlock()
-	}
+		// remove 会同时删除两级缓存中的键，重复的键第二次不会再通知
+		for _, key := range keys {
+			s.remove(key, int32(i), EvictionCleared)
+		}
 
-	for _, key := range keys {
-		s.Delete(key)
+		s.locks[i].Unlock()
 	}
-
-	//s.expirations = sync.Map{}
 }
 
 // Len 实现Store接口
@@ -175,6 +209,94 @@ func (s *lru2Store) Len() int {
 	return count
 }
 
+// Range 实现Store接口，逐个桶取快照后遍历，fn 中可以安全地访问缓存
+func (s *lru2Store) Range(fn func(key string, value Value) bool) {
+	type item struct {
+		key   string
+		value Value
+	}
+
+	for i := range s.caches {
+		var items []item
+		currentTime := Now()
+
+		s.locks[i].Lock()
+		for level := range s.caches[i] {
+			s.caches[i][level].walk(func(key string, value Value, expireAt int64) bool {
+				if expireAt > currentTime {
+					items = append(items, item{key, value})
+				}
+				return true
+			})
+		}
+		s.locks[i].Unlock()
+
+		for _, it := range items {
+			if !fn(it.key, it.value) {
+				return
+			}
+		}
+	}
+}
+
+// UsedBytes 实现 ByteCounter 接口
+func (s *lru2Store) UsedBytes() int64 {
+	return atomic.LoadInt64(&s.usedBytes)
+}
+
+// MaxBytes 实现 Resizer 接口
+func (s *lru2Store) MaxBytes() int64 {
+	return atomic.LoadInt64(&s.maxBytes)
+}
+
+// SetMaxBytes 实现 Resizer 接口，缩小时立即淘汰超出的缓存项
+func (s *lru2Store) SetMaxBytes(maxBytes int64) {
+	atomic.StoreInt64(&s.maxBytes, maxBytes)
+	s.shrink()
+}
+
+// shrink 占用的字节数超过最大字节数时，轮流从各个桶中淘汰最久未使用的项，优先淘汰一级缓存中的项。
+// 每次只持有一个桶的锁，调用方不能持有任何桶的锁
+func (s *lru2Store) shrink() {
+	for {
+		maxBytes := atomic.LoadInt64(&s.maxBytes)
+		if maxBytes <= 0 || atomic.LoadInt64(&s.usedBytes) <= maxBytes {
+			return
+		}
+
+		evicted := false
+		for range s.caches {
+			idx := int32(atomic.AddUint32(&s.cursor, 1)) & s.mask
+			s.locks[idx].Lock()
+			evicted = s.evictOldest(idx) || evicted
+			s.locks[idx].Unlock()
+
+			if atomic.LoadInt64(&s.usedBytes) <= maxBytes {
+				return
+			}
+		}
+		if !evicted {
+			return
+		}
+	}
+}
+
+// evictOldest 淘汰桶中最久未使用的一项，调用方需持有桶的锁
+func (s *lru2Store) evictOldest(idx int32) bool {
+	victim, ok := s.caches[idx][0].oldest()
+	if !ok {
+		if victim, ok = s.caches[idx][1].oldest(); !ok {
+			return false
+		}
+	}
+
+	reason := EvictionCapacity
+	if Now() >= victim.expireAt {
+		reason = EvictionExpired
+	}
+	return s.remove(victim.k, idx, reason)
+}
+
 // Close 关闭缓存相关资源
 func (s *lru2Store) Close() {
 	if s.cleanupTick != nil {
@@ -228,15 +350,31 @@ type node struct {
 	k        string
 	v        Value
 	expireAt int64 // 过期时间戳，expireAt = 0 表示已删除
+	setAt    int64 // 写入时间戳
 }
 
 // 内部缓存核心实现，包含双向链表和节点存储
 type cache struct {
 	// dlnk[0]是哨兵节点，记录链表头尾，dlnk[0][p]存储尾部索引，dlnk[0][n]存储头部索引
-	dlnk [][2]uint16       // 双向链表，0 表示前驱，1 表示后继
-	m    []node            // 预分配内存存储节点
-	hmap map[string]uint16 // 键到节点索引的映射
-	last uint16            // 最后一个节点元素的索引
+	dlnk  [][2]uint16       // 双向链表，0 表示前驱，1 表示后继
+	m     []node            // 预分配内存存储节点
+	hmap  map[string]uint16 // 键到节点索引的映射
+	last  uint16            // 最后一个节点元素的索引
+	bytes int64             // 有效项占用的字节数
+	total *int64            // 所属存储的字节数统计，可以为 nil
+}
+
+// entrySize 返回缓存项占用的字节数
+func entrySize(key string, value Value) int64 {
+	return int64(len(key) + sizeOf(value))
+}
+
+// addBytes 更新占用的字节数
+func (c *cache) addBytes(delta int64) {
+	c.bytes += delta
+	if c.total != nil {
+		atomic.AddInt64(c.total, delta)
+	}
 }
 
 func Create(cap uint16) *cache {
@@ -250,25 +388,41 @@ func Create(cap uint16) *cache {
 
 // 向缓存中添加项，如果是新增返回 1，更新返回 0
 func (c *cache) put(key string, val Value, expireAt int64, onEvicted func(string, Value)) int {
+	status, evicted, ok := c.insert(key, val, expireAt, Now())
+	if ok && onEvicted != nil {
+		onEvicted(evicted.k, evicted.v)
+	}
+	return status
+}
+
+// insert 向缓存中添加项，容量已满时覆盖链表尾部的项，返回值依次为新增状态、被覆盖的项以及它是否有效
+func (c *cache) insert(key string, val Value, expireAt, setAt int64) (int, node, bool) {
 	if idx, ok := c.hmap[key]; ok {
-		c.m[idx-1].v, c.m[idx-1].expireAt = val, expireAt
+		if old := &c.m[idx-1]; old.expireAt > 0 {
+			c.addBytes(-entrySize(old.k, old.v))
+		}
+		c.addBytes(entrySize(key, val))
+		c.m[idx-1].v, c.m[idx-1].expireAt, c.m[idx-1].setAt = val, expireAt, setAt
 		c.adjust(idx, p, n) // 刷新到链表头部
-		return 0
+		return 0, node{}, false
 	}
 
 	if c.last == uint16(cap(c.m)) {
 		tail := &c.m[c.dlnk[0][p]-1]
-		if onEvicted != nil && (*tail).expireAt > 0 {
-			onEvicted((*tail).k, (*tail).v)
+		evicted, ok := *tail, (*tail).expireAt > 0
+		if ok {
+			c.addBytes(-entrySize(evicted.k, evicted.v))
 		}
+		c.addBytes(entrySize(key, val))
 
 		delete(c.hmap, (*tail).k)
-		c.hmap[key], (*tail).k, (*tail).v, (*tail).expireAt = c.dlnk[0][p], key, val, expireAt
+		c.hmap[key], (*tail).k, (*tail).v, (*tail).expireAt, (*tail).setAt = c.dlnk[0][p], key, val, expireAt, setAt
 		c.adjust(c.dlnk[0][p], p, n)
 
-		return 1
+		return 1, evicted, ok
 	}
 
+	c.addBytes(entrySize(key, val))
 	c.last++
 	if len(c.hmap) <= 0 {
 		c.dlnk[0][p] = c.last
@@ -280,11 +434,12 @@ func (c *cache) put(key string, val Value, expireAt int64, onEvicted func(string
 	c.m[c.last-1].k = key
 	c.m[c.last-1].v = val
 	c.m[c.last-1].expireAt = expireAt
+	c.m[c.last-1].setAt = setAt
 	c.dlnk[c.last] = [2]uint16{0, c.dlnk[0][n]}
 	c.hmap[key] = c.last
 	c.dlnk[0][n] = c.last
 
-	return 1
+	return 1, node{}, false
 }
 
 // 从缓存中获取键对应的节点和状态
@@ -300,6 +455,7 @@ func (c *cache) get(key string) (*node, int) {
 func (c *cache) del(key string) (*node, int, int64) {
 	if idx, ok := c.hmap[key]; ok && c.m[idx-1].expireAt > 0 {
 		e := c.m[idx-1].expireAt
+		c.addBytes(-entrySize(key, c.m[idx-1].v))
 		c.m[idx-1].expireAt = 0 // 标记为已删除
 		c.adjust(idx, n, p)     // 移动到链表尾部
 		return &c.m[idx-1], 1, e
@@ -308,6 +464,16 @@ func (c *cache) del(key string) (*node, int, int64) {
 	return nil, 0, 0
 }
 
+// oldest 返回链表中最久未使用的有效项，已删除的项位于链表尾部，需要跳过
+func (c *cache) oldest() (node, bool) {
+	for idx := c.dlnk[0][p]; idx != 0; idx = c.dlnk[idx][p] {
+		if c.m[idx-1].expireAt > 0 {
+			return c.m[idx-1], true
+		}
+	}
+	return node{}, false
+}
+
 // 遍历缓存中的所有有效项
 func (c *cache) walk(walker func(key string, value Value, expireAt int64) bool) {
 	for idx := c.dlnk[0][n]; idx != 0; idx = c.dlnk[idx][n] {
@@ -344,23 +510,38 @@ func (s *lru2Store) _get(key string, idx, level int32) (*node, int) {
 }
 
 func (s *lru2Store) delete(key string, idx int32) bool {
-	n1, s1, _ := s.caches[idx][0].del(key)
-	n2, s2, _ := s.caches[idx][1].del(key)
-	deleted := s1 > 0 || s2 > 0
-
-	if deleted && s.onEvicted != nil {
-		if n1 != nil && n1.v != nil {
-			s.onEvicted(key, n1.v)
-		} else if n2 != nil && n2.v != nil {
-			s.onEvicted(key, n2.v)
-		}
+	return s.remove(key, idx, EvictionDeleted)
+}
+
+// remove 从两级缓存中删除键，按 reason 通知一次
+func (s *lru2Store) remove(key string, idx int32, reason EvictionReason) bool {
+	n1, s1, e1 := s.caches[idx][0].del(key)
+	n2, s2, e2 := s.caches[idx][1].del(key)
+
+	if s1 > 0 {
+		s.notify(key, n1.v, reason, n1.setAt, e1)
+	} else if s2 > 0 {
+		s.notify(key, n2.v, reason, n2.setAt, e2)
 	}
 
-	if deleted {
-		//s.expirations.Delete(key)
+	return s1 > 0 || s2 > 0
+}
+
+// evicted 通知因容量不足被覆盖的项，已经过期的项按过期通知
+func (s *lru2Store) evicted(e node, currentTime int64) {
+	reason := EvictionCapacity
+	if currentTime >= e.expireAt {
+		reason = EvictionExpired
 	}
+	s.notify(e.k, e.v, reason, e.setAt, e.expireAt)
+}
 
-	return deleted
+// notify 通知一次移除，永不过期的项不带过期时间
+func (s *lru2Store) notify(key string, value Value, reason EvictionReason, setAt, expireAt int64) {
+	if expireAt == neverExpire {
+		expireAt = 0
+	}
+	s.evictions.notify(key, value, reason, setAt, expireAt)
 }
 
 func (s *lru2Store) cleanupLoop() {
@@ -394,7 +575,7 @@ func (s *lru2Store) cleanupLoop() {
 			})
 
 			for _, key := range expiredKeys {
-				s.delete(key, int32(i))
+				s.remove(key, int32(i), EvictionExpired)
 			}
 
 			s.locks[i].Unlock()
@@ -427,20 +427,25 @@ func TestLRU2StoreLRUEviction(t *testing.T) {
 	store := newLRU2Cache(opts)
 	defer store.Close()
 
-	// 添加超过一级缓存容量的项
+	// 被访问过的项移入二级缓存，只写入过一次的项留在一级缓存
 	store.Set("key1", testValue("value1"))
 	store.Set("key2", testValue("value2"))
-	store.Set("key3", testValue("value3")) // 应该淘汰key1到二级缓存
+	store.Get("key1") // key1移入二级缓存
+	store.Set("key3", testValue("value3"))
+	store.Set("key4", testValue("value4")) // 一级缓存已满，淘汰从未访问过的key2
 
 	// key1应该在二级缓存中
 	value, found := store.Get("key1")
 	if !found || value != testValue("value1") {
 		t.Errorf("key1 should be in level2 cache, got %v, found: %v", value, found)
 	}
+	if _, found := store.Get("key2"); found || len(evictedKeys) != 1 || evictedKeys[0] != "key2" {
+		t.Errorf("key2 should be evicted from level1 cache, evicted: %v", evictedKeys)
+	}
 
-	// 添加更多项，超过二级缓存容量
-	store.Set("key4", testValue("value4")) // 应该淘汰key2到二级缓存
-	store.Set("key5", testValue("value5")) // 应该淘汰key3，key1应该从二级缓存中被淘汰
+	// 访问更多项，超过二级缓存容量
+	store.Get("key3") // key3移入二级缓存
+	store.Get("key4") // key4移入二级缓存，key1应该从二级缓存中被淘汰
 
 	// key1应该已被完全淘汰
 	value, found = store.Get("key1")
@@ -790,8 +795,9 @@ func TestLRU2StoreHitRatio(t *testing.T) {
 	// 计算命中率
 	hitRatio := float64(hits) / float64(attempts)
 
-	// 验证命中率大致为0.25-0.35（因为我们添加了50个项但有分桶和LRU淘汰）
-	if hitRatio < 0.25 || hitRatio > 0.35 {
+	// 只写入过一次的项留在一级缓存，一级缓存满时直接淘汰，4个桶最多保存40个项。
+	// 每个桶分到的键都多于10个，命中率为0.40
+	if hitRatio < 0.35 || hitRatio > 0.40 {
 		t.Errorf("Hit ratio out of expected range: got %.2f", hitRatio)
 	}
 }
@@ -856,3 +862,98 @@ func contains(slice []string, str string) bool {
 	}
 	return false
 }
+
+// 测试LRU2Store按字节数限制容量并在运行时调整
+func TestLRU2StoreMaxBytes(t *testing.T) {
+	var reasons []EvictionReason
+	store := newLRU2Cache(Options{
+		MaxBytes:        100,
+		BucketCount:     4,
+		CapPerBucket:    64,
+		Level2Cap:       64,
+		CleanupInterval: time.Minute,
+		OnEviction:      func(e Eviction) { reasons = append(reasons, e.Reason) },
+	})
+	defer store.Close()
+
+	// 每项占用 2+8=10 字节
+	for i := 0; i < 20; i++ {
+		store.Set(fmt.Sprintf("%02d", i), testValue("01234567"))
+	}
+	if used := store.UsedBytes(); used > 100 || used == 0 {
+		t.Fatalf("UsedBytes should not exceed MaxBytes, got %d", used)
+	}
+	if len(reasons) != 10 {
+		t.Fatalf("expected 10 capacity evictions, got %d", len(reasons))
+	}
+	for _, r := range reasons {
+		if r != EvictionCapacity {
+			t.Fatalf("expected capacity eviction, got %v", r)
+		}
+	}
+
+	// 缩小后立即淘汰超出的项
+	store.SetMaxBytes(30)
+	if store.MaxBytes() != 30 || store.UsedBytes() > 30 {
+		t.Fatalf("after shrinking, MaxBytes=%d UsedBytes=%d", store.MaxBytes(), store.UsedBytes())
+	}
+	if got := store.Len(); got != 3 {
+		t.Errorf("expected 3 items after shrinking, got %d", got)
+	}
+
+	// 删除和覆盖时同步更新字节数
+	var keys []string
+	store.Range(func(key string, value Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	store.Delete(keys[0])
+	store.Set(keys[1], testValue("0123"))
+	if used := store.UsedBytes(); used != 10+6 {
+		t.Errorf("expected 16 used bytes, got %d", used)
+	}
+}
+
+// 测试永不过期的项和 Peek
+func TestLRU2StorePeek(t *testing.T) {
+	store := newLRU2Cache(Options{
+		BucketCount:     4,
+		CapPerBucket:    8,
+		Level2Cap:       8,
+		CleanupInterval: time.Minute,
+	})
+	defer store.Close()
+
+	// expiration 不大于0表示永不过期
+	store.Set("forever", testValue("v1"))
+	store.SetWithExpiration("zero", testValue("v2"), 0)
+	for _, key := range []string{"forever", "zero"} {
+		v, expireAt, ok := store.Peek(key)
+		if !ok || v.(testValue) == "" {
+			t.Fatalf("expected %s to be found", key)
+		}
+		if !expireAt.IsZero() {
+			t.Fatalf("expected zero expiration for %s, got %v", key, expireAt)
+		}
+	}
+
+	store.SetWithExpiration("ttl", testValue("v3"), time.Minute)
+	_, expireAt, ok := store.Peek("ttl")
+	if !ok {
+		t.Fatal("expected ttl to be found")
+	}
+	if d := time.Until(expireAt); d <= 0 || d > time.Minute {
+		t.Fatalf("unexpected expiration %v", d)
+	}
+
+	if _, _, ok := store.Peek("missing"); ok {
+		t.Fatal("expected missing key not to be found")
+	}
+
+	// 过期的项不会返回
+	store.SetWithExpiration("short", testValue("v4"), 50*time.Millisecond)
+	time.Sleep(250 * time.Millisecond)
+	if _, _, ok := store.Peek("short"); ok {
+		t.Fatal("expected expired key not to be found")
+	}
+}
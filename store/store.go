@@ -7,14 +7,44 @@ type Value interface {
 	Len() int // 返回数据大小
 }
 
+// Sizer 可选接口，值实现后按 Size 计算占用的内存（例如压缩后的长度），否则按 Len 计算
+type Sizer interface {
+	Size() int
+}
+
+// sizeOf 返回值占用的内存
+func sizeOf(v Value) int {
+	if s, ok := v.(Sizer); ok {
+		return s.Size()
+	}
+	return v.Len()
+}
+
+// ByteCounter 可选接口，存储实现后按其统计占用的字节数，否则需要遍历所有缓存项
+type ByteCounter interface {
+	UsedBytes() int64
+}
+
+// Resizer 可选接口，存储实现后可以在运行时调整最大字节数，缩小时立即淘汰超出的缓存项
+type Resizer interface {
+	MaxBytes() int64
+	SetMaxBytes(maxBytes int64)
+}
+
 // Store 缓存接口
 type Store interface {
 	Get(key string) (Value, bool)
+	// Set 添加永不过期的缓存项
 	Set(key string, value Value) error
+	// SetWithExpiration 添加缓存项，expiration 不大于0表示永不过期
 	SetWithExpiration(key string, value Value, expiration time.Duration) error
 	Delete(key string) bool
 	Clear()
 	Len() int
+	// Peek 获取缓存项及其过期时间（零值表示永不过期），不改变缓存项的淘汰顺序
+	Peek(key string) (Value, time.Time, bool)
+	// Range 遍历所有未过期的缓存项，fn 返回 false 时停止遍历
+	Range(fn func(key string, value Value) bool)
 	Close()
 }
 
@@ -28,12 +58,13 @@ const (
 
 // Options 通用缓存配置选项
 type Options struct {
-	MaxBytes        int64  // 最大的缓存字节数（用于 lru）
+	MaxBytes        int64  // 最大的缓存字节数，不大于0表示不限制
 	BucketCount     uint16 // 缓存的桶数量（用于 lru-2）
 	CapPerBucket    uint16 // 每个桶的容量（用于 lru-2）
 	Level2Cap       uint16 // lru-2 中二级缓存的容量（用于 lru-2）
 	CleanupInterval time.Duration
-	OnEvicted       func(key string, value Value)
+	OnEvicted       func(key string, value Value) // 缓存项被移除时调用，不区分原因
+	OnEviction      func(e Eviction)              // 缓存项被移除时调用，带有原因、存活时间和过期时间
 }
 
 func NewOptions() Options {
@@ -0,0 +1,322 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	pb "github.com/SuperJinggg/mycache-go/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultStreamThreshold 默认的流式传输阈值，超过该长度的值通过 GetStream/SetStream 分片传输
+	defaultStreamThreshold = 1 << 20 // 1MB
+	// streamChunkSize 流式传输中每个分片的长度，需小于双方的单条消息上限
+	streamChunkSize = 1 << 20 // 1MB
+	// defaultMaxStreamSize 默认的流式传输的值的最大长度
+	defaultMaxStreamSize = 256 << 20 // 256MB
+)
+
+// WithStreamThreshold 设置流式传输的阈值，超过该长度的值自动改用分片传输，不大于0表示不使用流式传输
+func WithStreamThreshold(n int) ClientOption {
+	return func(c *Client) {
+		c.streamThreshold = n
+	}
+}
+
+// WithMaxMsgSize 设置客户端收发单条消息的最大长度，与 ServerOptions.MaxMsgSize 对应
+func WithMaxMsgSize(n int) ClientOption {
+	return func(c *Client) {
+		c.maxMsgSize = n
+	}
+}
+
+// WithMaxStreamRecvSize 设置从对端读取的值的最大长度，包括流式读取和解压后的长度，超过时拒绝，不在接收前按对端声明的长度分配内存
+func WithMaxStreamRecvSize(n int64) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxStreamSize = n
+		}
+	}
+}
+
+// getStream 通过 GetStream 分片获取值
+func (c *Client) getStream(ctx context.Context, group, key string) ([]byte, error) {
+	stream, err := c.grpcCli.GetStream(ctx, &pb.Request{Group: group, Key: key, AcceptEncoding: compressorNames()})
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	var encoding string
+	var total int64 = -1
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if total < 0 {
+			// 声明的长度来自对端，必须先按客户端的上限检查
+			total = chunk.GetTotalSize()
+			if limit := c.streamLimit(); total < 0 || total > limit {
+				return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrValueTooLarge, total, limit)
+			}
+			encoding = chunk.GetEncoding()
+			// 按实际收到的数据增长，不按声明的长度预先分配
+			value = make([]byte, 0, min(total, int64(streamChunkSize)))
+		}
+		if int64(len(value)+len(chunk.GetData())) > total {
+			return nil, status.Errorf(codes.DataLoss, "stream exceeds declared size of %d bytes", total)
+		}
+		value = append(value, chunk.GetData()...)
+	}
+
+	if int64(len(value)) != total {
+		return nil, status.Errorf(codes.DataLoss, "stream ended after %d of %d bytes", len(value), total)
+	}
+	return decodeValue(encoding, value, c.streamLimit())
+}
+
+// writeLimit 返回客户端写入组的值的最大长度，组拒绝超大值时不超过组的上限
+func (s *Server) writeLimit(group *Group) int64 {
+	limit := s.opts.MaxStreamSize
+	if group.maxValueSize > 0 && group.oversize == OversizeReject {
+		limit = min(limit, int64(group.maxValueSize))
+	}
+	return limit
+}
+
+// streamLimit 返回流式读取的值的最大长度
+func (c *Client) streamLimit() int64 {
+	if c.maxStreamSize > 0 {
+		return c.maxStreamSize
+	}
+	return defaultMaxStreamSize
+}
+
+// setStream 通过 SetStream 分片写入值
+func (c *Client) setStream(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	stream, err := c.grpcCli.SetStream(ctx)
+	if err != nil {
+		return err
+	}
+
+	for off := 0; off == 0 || off < len(value); off += streamChunkSize {
+		chunk := &pb.Chunk{Data: value[off:min(off+streamChunkSize, len(value))]}
+		if off == 0 {
+			chunk.Group = group
+			chunk.Key = key
+			chunk.TtlMs = ttl.Milliseconds()
+			chunk.TotalSize = int64(len(value))
+		}
+		if err := stream.Send(chunk); err != nil {
+			// 服务端提前结束时真正的错误由 CloseAndRecv 返回
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// GetStream 实现Cache服务的GetStream方法，将值分片发送
+func (s *Server) GetStream(req *pb.Request, stream pb.MyCache_GetStreamServer) error {
+	group := s.node.GetGroup(req.Group)
+	if group == nil {
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, req.Group)
+	}
+
+	ctx := s.peerContext(stream.Context())
+
+	view, err := group.get(ctx, req.Key)
+	if err != nil {
+		return err
+	}
+	if group.localOnly(view.Len()) {
+		return ErrValueTooLarge
+	}
+
+	// ByteView 不可变，可以直接按分片发送而无需整体复制
+	b, encoding, err := view.encode(req.AcceptEncoding)
+	if err != nil {
+		group.dropCorrupt(req.Key, err)
+		return err
+	}
+	for off := 0; off == 0 || off < len(b); off += streamChunkSize {
+		chunk := &pb.Chunk{Data: b[off:min(off+streamChunkSize, len(b))]}
+		if off == 0 {
+			chunk.Group = req.Group
+			chunk.Key = req.Key
+			chunk.TotalSize = int64(len(b))
+			chunk.Encoding = encoding
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStream 实现Cache服务的SetStream方法，接收全部分片后写入
+func (s *Server) SetStream(stream pb.MyCache_SetStreamServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	group := s.node.GetGroup(first.Group)
+	if group == nil {
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, first.Group)
+	}
+	// 声明的长度来自对端，必须先按服务器的上限检查
+	limit := s.writeLimit(group)
+	if first.TotalSize < 0 || first.TotalSize > limit {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrValueTooLarge, first.TotalSize, limit)
+	}
+
+	// 按实际收到的数据增长，不按声明的长度预先分配
+	value := make([]byte, 0, min(first.TotalSize, int64(streamChunkSize)))
+	value = append(value, first.Data...)
+	if int64(len(value)) > first.TotalSize {
+		return status.Errorf(codes.InvalidArgument, "stream exceeds declared size of %d bytes", first.TotalSize)
+	}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if int64(len(value)+len(chunk.Data)) > first.TotalSize {
+			return status.Errorf(codes.InvalidArgument, "stream exceeds declared size of %d bytes", first.TotalSize)
+		}
+		value = append(value, chunk.Data...)
+	}
+	if int64(len(value)) != first.TotalSize {
+		return status.Errorf(codes.DataLoss, "stream ended after %d of %d bytes", len(value), first.TotalSize)
+	}
+
+	ctx := s.writeContext(stream.Context())
+	if err := group.SetWithTTL(ctx, first.Key, value, time.Duration(first.TtlMs)*time.Millisecond); err != nil {
+		return err
+	}
+	return stream.SendAndClose(&pb.ResponseForGet{})
+}
+
+// errorStreamInterceptor 将流式处理函数返回的错误转换为 gRPC 状态错误
+func errorStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatusError(handler(srv, ss))
+}
+
+// authStreamInterceptor 对流式请求进行认证和授权，组名在收到第一条消息后才能确定
+func authStreamInterceptor(authn Authenticator, authz Authorizer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		op, protected, err := methodOperation(info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if !protected {
+			return handler(srv, ss)
+		}
+		return handler(srv, &authServerStream{ServerStream: ss, authn: authn, authz: authz, op: op})
+	}
+}
+
+// authServerStream 在收到第一条消息时完成授权，之后 Context 返回携带身份的上下文
+type authServerStream struct {
+	grpc.ServerStream
+	authn Authenticator
+	authz Authorizer
+	op    Operation
+	ctx   context.Context
+}
+
+// RecvMsg 接收消息，第一条消息需要通过授权
+func (s *authServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.ctx != nil {
+		return nil
+	}
+
+	var group string
+	if r, ok := m.(interface{ GetGroup() string }); ok {
+		group = r.GetGroup()
+	}
+	ctx, err := authorize(s.ServerStream.Context(), s.authn, s.authz, group, s.op)
+	if err != nil {
+		return err
+	}
+	s.ctx = ctx
+	return nil
+}
+
+// Context 返回授权后携带身份的上下文
+func (s *authServerStream) Context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return s.ServerStream.Context()
+}
+
+// observedClientStream 在客户端流结束时调用一次 finish
+type observedClientStream struct {
+	grpc.ClientStream
+	serverStreams bool        // 服务端是否发送多条消息
+	stop          func() bool // 停止监听上下文
+	finish        func(err error)
+	once          sync.Once
+}
+
+// observeClientStream 包装客户端流，出错、读到结尾、收到客户端流的唯一响应、关闭发送失败或上下文结束时调用一次 finish，
+// 正常结束时 err 为 nil。调用方放弃读取的流在上下文结束时结束
+func observeClientStream(ctx context.Context, cs grpc.ClientStream, serverStreams bool, finish func(err error)) grpc.ClientStream {
+	s := &observedClientStream{ClientStream: cs, serverStreams: serverStreams, finish: finish}
+	s.stop = context.AfterFunc(ctx, func() {
+		s.done(status.FromContextError(ctx.Err()).Err())
+	})
+	return s
+}
+
+// RecvMsg 接收消息，出错、读到结尾或收到客户端流的唯一响应时结束
+func (s *observedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.stop()
+		if errors.Is(err, io.EOF) {
+			s.done(nil)
+		} else {
+			s.done(err)
+		}
+	}
+	return err
+}
+
+// CloseSend 关闭发送方向，失败时结束
+func (s *observedClientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	if err != nil {
+		s.stop()
+		s.done(err)
+	}
+	return err
+}
+
+// done 只调用一次 finish
+func (s *observedClientStream) done(err error) {
+	s.once.Do(func() { s.finish(err) })
+}
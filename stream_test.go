@@ -0,0 +1,164 @@
+package kamacache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	pb "github.com/SuperJinggg/mycache-go/pb"
+	"google.golang.org/grpc"
+)
+
+// 测试超过单条消息上限的值通过流式RPC传输，以及组的大小限制
+func TestStreamTransfer(t *testing.T) {
+	srv, err := NewServer("127.0.0.1:0", "stream-test")
+	if err != nil {
+		t.Fatalf("创建服务器失败: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go srv.grpcServer.Serve(ln)
+	t.Cleanup(srv.grpcServer.Stop)
+
+	client, err := NewClient(ln.Addr().String(), "stream-test", nil)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	getter := GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	})
+	ctx := context.Background()
+
+	t.Run("大于 MaxMsgSize 的值分片传输", func(t *testing.T) {
+		g := NewGroup("stream-big", 64<<20, getter)
+		t.Cleanup(func() { g.Close() })
+
+		value := bytes.Repeat([]byte("0123456789abcdef"), (6<<20)/16)
+		if err := client.Set(ctx, "stream-big", "report", value, 0); err != nil {
+			t.Fatalf("流式写入失败: %v", err)
+		}
+		got, err := client.Get(ctx, "stream-big", "report")
+		if err != nil {
+			t.Fatalf("流式读取失败: %v", err)
+		}
+		if !bytes.Equal(got, value) {
+			t.Fatalf("读取到的值长度为 %d，期望 %d", len(got), len(value))
+		}
+
+		// 小值仍然使用普通RPC
+		if err := client.Set(ctx, "stream-big", "small", []byte("v"), 0); err != nil {
+			t.Fatalf("写入小值失败: %v", err)
+		}
+		if got, err := client.Get(ctx, "stream-big", "small"); err != nil || string(got) != "v" {
+			t.Fatalf("读取小值失败: %q, %v", got, err)
+		}
+	})
+
+	t.Run("超过大小限制时拒绝", func(t *testing.T) {
+		g := NewGroup("stream-reject", 64<<20, getter, WithMaxValueSize(1<<20, OversizeReject))
+		t.Cleanup(func() { g.Close() })
+
+		err := client.Set(ctx, "stream-reject", "k", make([]byte, 2<<20), 0)
+		if !errors.Is(err, ErrValueTooLarge) {
+			t.Fatalf("超过大小限制时应返回 ErrValueTooLarge，实际为 %v", err)
+		}
+	})
+
+	t.Run("只保存在本地的值不发送给其他节点", func(t *testing.T) {
+		g := NewGroup("stream-local", 64<<20, getter, WithMaxValueSize(1<<20, OversizeLocalOnly))
+		t.Cleanup(func() { g.Close() })
+
+		if err := g.Set(ctx, "k", make([]byte, 2<<20)); err != nil {
+			t.Fatalf("本地写入失败: %v", err)
+		}
+		if _, err := g.Get(ctx, "k"); err != nil {
+			t.Fatalf("本地应能读取到值: %v", err)
+		}
+		if _, err := client.Get(ctx, "stream-local", "k"); !errors.Is(err, ErrValueTooLarge) {
+			t.Fatalf("其他节点请求时应返回 ErrValueTooLarge，实际为 %v", err)
+		}
+	})
+
+	t.Run("声明的长度超过服务器上限时拒绝", func(t *testing.T) {
+		g := NewGroup("stream-declared", 64<<20, getter)
+		t.Cleanup(func() { g.Close() })
+
+		stream, err := client.grpcCli.SetStream(ctx)
+		if err != nil {
+			t.Fatalf("创建流失败: %v", err)
+		}
+		if err := stream.Send(&pb.Chunk{Group: "stream-declared", Key: "k", TotalSize: 1 << 62, Data: []byte("v")}); err != nil {
+			t.Fatalf("发送分片失败: %v", err)
+		}
+		if _, err := stream.CloseAndRecv(); !errors.Is(fromStatusError(err), ErrValueTooLarge) {
+			t.Fatalf("声明的长度过大时应返回 ErrValueTooLarge，实际为 %v", err)
+		}
+	})
+}
+
+// chunkStream 依次返回预设分片的 GetStream 客户端流
+type chunkStream struct {
+	grpc.ClientStream
+	chunks []*pb.Chunk
+}
+
+func (s *chunkStream) Recv() (*pb.Chunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+// streamCacheClient GetStream 返回预设分片的 gRPC 客户端
+type streamCacheClient struct {
+	pb.MyCacheClient
+	chunks []*pb.Chunk
+}
+
+func (f *streamCacheClient) GetStream(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (pb.MyCache_GetStreamClient, error) {
+	return &chunkStream{chunks: f.chunks}, nil
+}
+
+// 测试客户端按上限检查对端声明的长度，不按声明的长度分配内存
+func TestClientGetStreamSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  []*pb.Chunk
+		wantErr error
+		want    string
+	}{
+		{"正常读取", []*pb.Chunk{{TotalSize: 4, Data: []byte("ab")}, {Data: []byte("cd")}}, nil, "abcd"},
+		{"声明的长度为负数", []*pb.Chunk{{TotalSize: -1, Data: []byte("v")}}, ErrValueTooLarge, ""},
+		{"声明的长度超过上限", []*pb.Chunk{{TotalSize: 1 << 62, Data: []byte("v")}}, ErrValueTooLarge, ""},
+		{"收到的数据超过声明的长度", []*pb.Chunk{{TotalSize: 1, Data: []byte("ab")}}, nil, ""},
+		{"收到的数据不足", []*pb.Chunk{{TotalSize: 4, Data: []byte("ab")}}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{grpcCli: &streamCacheClient{chunks: tt.chunks}, maxStreamSize: 1 << 20}
+			got, err := c.getStream(context.Background(), "g", "k")
+			switch {
+			case tt.want != "":
+				if err != nil || string(got) != tt.want {
+					t.Fatalf("应读取到 %q，实际为 %q, %v", tt.want, got, err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("应返回 %v，实际为 %v", tt.wantErr, err)
+				}
+			case err == nil:
+				t.Fatal("应返回错误")
+			}
+		})
+	}
+}
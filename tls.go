@@ -0,0 +1,235 @@
+package kamacache
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSOptions TLS/mTLS 配置
+type TLSOptions struct {
+	CAFile         string        // 用于校验对端证书的CA证书，为空时客户端使用系统根证书
+	CertFile       string        // 本端证书，客户端配置后启用双向TLS
+	KeyFile        string        // 本端私钥
+	ServerName     string        // 覆盖校验服务端证书时使用的名称（仅客户端）
+	ReloadInterval time.Duration // 检查证书文件变化的间隔，0表示不重新加载
+}
+
+// certReloader 从磁盘加载证书，并在握手时按间隔检查文件变化后重新加载，证书轮换无需重启
+type certReloader struct {
+	opts TLSOptions
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader 创建证书加载器并立即加载一次
+func newCertReloader(opts TLSOptions) (*certReloader, error) {
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("both cert file and key file must be provided")
+	}
+
+	r := &certReloader{
+		opts:     opts,
+		modTimes: make(map[string]time.Time),
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load 从磁盘读取证书和CA
+func (r *certReloader) load() error {
+	var cert *tls.Certificate
+	if r.opts.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load key pair: %v", err)
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if r.opts.CAFile != "" {
+		pem, err := os.ReadFile(r.opts.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %v", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no valid certificates found in %s", r.opts.CAFile)
+		}
+	}
+
+	modTimes := make(map[string]time.Time)
+	for _, f := range r.files() {
+		if info, err := os.Stat(f); err == nil {
+			modTimes[f] = info.ModTime()
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.modTimes = cert, pool, modTimes
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// files 返回需要监视的文件
+func (r *certReloader) files() []string {
+	var files []string
+	for _, f := range []string{r.opts.CAFile, r.opts.CertFile, r.opts.KeyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// maybeReload 距上次检查超过 ReloadInterval 时检查文件是否变化
+func (r *certReloader) maybeReload() {
+	if r.opts.ReloadInterval <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < r.opts.ReloadInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	changed := false
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err == nil && !info.ModTime().Equal(r.modTimes[f]) {
+			changed = true
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	// 加载失败时继续使用旧证书
+	if err := r.load(); err != nil {
+		logrus.Errorf("[KamaCache] failed to reload TLS certificates: %v", err)
+		return
+	}
+	logrus.Infof("[KamaCache] reloaded TLS certificates")
+}
+
+// certificate 返回当前证书
+func (r *certReloader) certificate() *tls.Certificate {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// caPool 返回当前CA
+func (r *certReloader) caPool() *x509.CertPool {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+// serverConfig 返回服务端TLS配置，配置了CA时校验客户端证书，nextProtos 为通过 ALPN 协商的协议
+func (r *certReloader) serverConfig(requireClientCert bool, nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert := r.certificate()
+			if cert == nil {
+				return nil, errors.New("no server certificate configured")
+			}
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   nextProtos,
+			}
+			if pool := r.caPool(); pool != nil {
+				cfg.ClientCAs = pool
+				if requireClientCert {
+					cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				} else {
+					cfg.ClientAuth = tls.VerifyClientCertIfGiven
+				}
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// clientConfig 返回客户端TLS配置，使用当前加载的CA由标准流程校验服务端证书。
+// ServerName 为空时由 gRPC 设置为拨号地址中的主机名或IP，IP 按证书的 IP SAN 校验
+func (r *certReloader) clientConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.opts.ServerName,
+		RootCAs:    r.caPool(),
+	}
+
+	if r.opts.CertFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.certificate(), nil
+		}
+	}
+
+	return cfg
+}
+
+// reloadingCreds 每次握手时使用最新加载的CA创建TLS凭证，CA轮换后新建的连接立即生效
+type reloadingCreds struct {
+	credentials.TransportCredentials
+	r *certReloader
+}
+
+// newReloadingCreds 创建随证书文件更新的客户端凭证
+func newReloadingCreds(r *certReloader) credentials.TransportCredentials {
+	return &reloadingCreds{TransportCredentials: credentials.NewTLS(r.clientConfig()), r: r}
+}
+
+// ClientHandshake 使用最新的配置完成握手
+func (c *reloadingCreds) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.r.clientConfig()).ClientHandshake(ctx, authority, conn)
+}
+
+// Clone 复制凭证
+func (c *reloadingCreds) Clone() credentials.TransportCredentials {
+	return &reloadingCreds{TransportCredentials: c.TransportCredentials.Clone(), r: c.r}
+}
+
+// WithClientTLS 使用TLS连接对端节点，配置 CertFile/KeyFile 后启用双向TLS
+func WithClientTLS(opts TLSOptions) ClientOption {
+	// 同一个选项创建的所有 Client 共享证书加载器
+	var once sync.Once
+	var reloader *certReloader
+	var loadErr error
+
+	return func(c *Client) {
+		once.Do(func() {
+			reloader, loadErr = newCertReloader(opts)
+		})
+		if loadErr != nil {
+			c.credsErr = fmt.Errorf("failed to load client TLS credentials: %v", loadErr)
+			return
+		}
+		c.creds = newReloadingCreds(reloader)
+	}
+}
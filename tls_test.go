@@ -0,0 +1,119 @@
+package kamacache
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA 测试使用的CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+// newTestCA 创建CA并写入 dir
+func newTestCA(t *testing.T, dir string) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("创建CA失败: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	file := filepath.Join(dir, "ca.pem")
+	os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue 签发带有指定 IP SAN 的服务端证书，返回证书和私钥文件
+func (ca *testCA) issue(t *testing.T, dir, name string, ips ...net.IP) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("签发证书失败: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+// 测试客户端按拨号地址校验服务端证书
+func TestClientTLSVerifiesPeerAddress(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	g := NewGroup("tls-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("v"), nil
+	}))
+	t.Cleanup(func() { g.Close() })
+
+	tests := []struct {
+		name string
+		ip   net.IP
+		ok   bool
+	}{
+		{"证书包含拨号的IP", net.ParseIP("127.0.0.1"), true},
+		{"同一CA签发但IP不匹配的证书被拒绝", net.ParseIP("10.9.9.9"), false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certFile, keyFile := ca.issue(t, dir, "server"+string(rune('a'+i)), tt.ip)
+			srv, err := NewServer("127.0.0.1:0", "tls-test", WithTLS(certFile, keyFile))
+			if err != nil {
+				t.Fatalf("创建服务器失败: %v", err)
+			}
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("监听失败: %v", err)
+			}
+			go srv.grpcServer.Serve(ln)
+			t.Cleanup(srv.grpcServer.Stop)
+
+			client, err := NewClient(ln.Addr().String(), "tls-test", nil, WithClientTLS(TLSOptions{CAFile: ca.file}))
+			if err != nil {
+				t.Fatalf("创建客户端失败: %v", err)
+			}
+			t.Cleanup(func() { client.Close() })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			_, err = client.Get(ctx, "tls-test", "k")
+			if tt.ok && err != nil {
+				t.Fatalf("证书有效时请求应成功: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("证书的IP不匹配时请求应失败")
+			}
+		})
+	}
+}
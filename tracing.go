@@ -0,0 +1,190 @@
+package kamacache
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// tracerName 创建 Tracer 时使用的名称
+const tracerName = "github.com/SuperJinggg/mycache-go"
+
+// tracePropagator 通过 gRPC 元数据传递链路上下文，使用 W3C Trace Context 和 Baggage 格式
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// WithTracerProvider 为组开启链路追踪，记录 Get 的本地命中、singleflight 等待、对等节点获取和数据源加载。
+// 不设置时不创建任何 span
+func WithTracerProvider(tp trace.TracerProvider) GroupOption {
+	return func(g *Group) {
+		if tp != nil {
+			g.tracer = tp.Tracer(tracerName)
+		}
+	}
+}
+
+// WithTraceKeys 在 Group.Get 的 span 中记录 key（属性 cache.key）。key 可能包含用户标识等敏感信息，默认不记录
+func WithTraceKeys() GroupOption {
+	return func(g *Group) {
+		g.traceKeys = true
+	}
+}
+
+// WithClientTracerProvider 为客户端开启链路追踪，每个 RPC 创建一个 span 并将链路上下文传递给对端
+func WithClientTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		if tp != nil {
+			c.tracer = tp.Tracer(tracerName)
+		}
+	}
+}
+
+// WithServerTracerProvider 为服务端开启链路追踪，从请求的元数据中恢复链路上下文并为每个 RPC 创建 span
+func WithServerTracerProvider(tp trace.TracerProvider) ServerOption {
+	return func(o *ServerOptions) {
+		o.TracerProvider = tp
+	}
+}
+
+// startSpan 开启链路追踪时创建子 span，未开启时返回 nil
+func (g *Group) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if g.tracer == nil {
+		return ctx, nil
+	}
+	attrs = append(attrs, attribute.String("cache.group", g.name))
+	return g.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// keyAttributes 开启 WithTraceKeys 时返回记录 key 的 span 属性
+func (g *Group) keyAttributes(key string) []attribute.KeyValue {
+	if !g.traceKeys {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("cache.key", key)}
+}
+
+// endSpan 记录错误并结束 span，span 为 nil 时什么也不做
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	recordSpanError(span, err)
+	span.End()
+}
+
+// recordSpanError 在 span 上记录错误
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+}
+
+// metadataCarrier 将 gRPC 元数据适配为 propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// injectTrace 将链路上下文写入发出请求的元数据
+func injectTrace(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	tracePropagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// extractTrace 从收到请求的元数据中恢复链路上下文
+func extractTrace(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return tracePropagator.Extract(ctx, metadataCarrier(md))
+}
+
+// rpcAttributes 返回 RPC span 的属性
+func rpcAttributes(method string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("rpc.system", "grpc"), attribute.String("rpc.method", method))
+}
+
+// tracingUnaryClientInterceptor 为发往 addr 的普通 RPC 创建客户端 span
+func tracingUnaryClientInterceptor(tracer trace.Tracer, addr string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient), rpcAttributes(method),
+			trace.WithAttributes(attribute.String("server.address", addr)))
+		defer span.End()
+
+		err := invoker(injectTrace(ctx), method, req, reply, cc, opts...)
+		recordSpanError(span, err)
+		return err
+	}
+}
+
+// tracingStreamClientInterceptor 为发往 addr 的流式 RPC 创建客户端 span，流结束、关闭发送失败或上下文结束时结束 span，
+// 调用方放弃读取的流不会留下未结束的 span
+func tracingStreamClientInterceptor(tracer trace.Tracer, addr string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient), rpcAttributes(method),
+			trace.WithAttributes(attribute.String("server.address", addr)))
+
+		cs, err := streamer(injectTrace(ctx), desc, cc, method, opts...)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+		return observeClientStream(ctx, cs, desc.ServerStreams, func(err error) { endSpan(span, err) }), nil
+	}
+}
+
+// tracingUnaryServerInterceptor 恢复链路上下文并为普通 RPC 创建服务端 span
+func tracingUnaryServerInterceptor(tracer trace.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := tracer.Start(extractTrace(ctx), info.FullMethod, trace.WithSpanKind(trace.SpanKindServer), rpcAttributes(info.FullMethod))
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		recordSpanError(span, err)
+		return resp, err
+	}
+}
+
+// tracingStreamServerInterceptor 恢复链路上下文并为流式 RPC 创建服务端 span
+func tracingStreamServerInterceptor(tracer trace.Tracer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := tracer.Start(extractTrace(ss.Context()), info.FullMethod, trace.WithSpanKind(trace.SpanKindServer), rpcAttributes(info.FullMethod))
+		defer span.End()
+
+		err := handler(srv, &tracedServerStream{ServerStream: ss, ctx: ctx})
+		recordSpanError(span, err)
+		return err
+	}
+}
+
+// tracedServerStream 返回携带服务端 span 的上下文
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context 返回携带服务端 span 的上下文
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
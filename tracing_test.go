@@ -0,0 +1,162 @@
+package kamacache
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/SuperJinggg/mycache-go/pb"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanNames 返回导出的 span 名称及对应的 span
+func spanNames(exp *tracetest.InMemoryExporter) map[string]tracetest.SpanStub {
+	spans := make(map[string]tracetest.SpanStub)
+	for _, s := range exp.GetSpans() {
+		spans[s.Name] = s
+	}
+	return spans
+}
+
+// 测试 Group.Get 的 span
+func TestGroupTracing(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	g := NewGroup("tracing-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("v"), nil
+	}), WithTracerProvider(tp))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	t.Run("未命中时记录加载过程", func(t *testing.T) {
+		if _, err := g.Get(ctx, "k"); err != nil {
+			t.Fatalf("加载失败: %v", err)
+		}
+		spans := spanNames(exp)
+		get, sf, loader := spans["kamacache.Group.Get"], spans["kamacache.singleflight"], spans["kamacache.loader"]
+		if !get.SpanContext.IsValid() || !sf.SpanContext.IsValid() || !loader.SpanContext.IsValid() {
+			t.Fatalf("缺少 span: %v", spans)
+		}
+		if sf.Parent.SpanID() != get.SpanContext.SpanID() || loader.Parent.SpanID() != sf.SpanContext.SpanID() {
+			t.Fatal("span 的父子关系不正确")
+		}
+	})
+
+	t.Run("命中时只有一个span", func(t *testing.T) {
+		exp.Reset()
+		g.Get(ctx, "k")
+		if spans := exp.GetSpans(); len(spans) != 1 || spans[0].Name != "kamacache.Group.Get" {
+			t.Fatalf("命中时应只有 Group.Get 的 span，实际为 %d 个", len(spans))
+		}
+	})
+
+	t.Run("默认不记录key", func(t *testing.T) {
+		exp.Reset()
+		g.Get(ctx, "k")
+		if hasKeyAttribute(spanNames(exp)["kamacache.Group.Get"]) {
+			t.Fatal("未开启 WithTraceKeys 时不应记录 key")
+		}
+
+		keyed := NewGroup("tracing-key-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+			return []byte("v"), nil
+		}), WithTracerProvider(tp), WithTraceKeys())
+		t.Cleanup(func() { keyed.Close() })
+
+		exp.Reset()
+		keyed.Get(ctx, "k")
+		if !hasKeyAttribute(spanNames(exp)["kamacache.Group.Get"]) {
+			t.Fatal("开启 WithTraceKeys 后应记录 key")
+		}
+	})
+}
+
+// hasKeyAttribute 判断 span 是否记录了 key
+func hasKeyAttribute(s tracetest.SpanStub) bool {
+	for _, a := range s.Attributes {
+		if a.Key == "cache.key" {
+			return true
+		}
+	}
+	return false
+}
+
+// 测试链路上下文通过 gRPC 元数据在客户端与服务端之间传递
+func TestRPCTracing(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	srv, err := NewServer("127.0.0.1:0", "tracing-rpc-test", WithServerTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("创建服务器失败: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go srv.grpcServer.Serve(ln)
+	t.Cleanup(srv.grpcServer.Stop)
+
+	client, err := NewClient(ln.Addr().String(), "tracing-rpc-test", nil, WithClientTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	g := NewGroup("tracing-rpc", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("v"), nil
+	}), WithTracerProvider(tp))
+	t.Cleanup(func() { g.Close() })
+
+	if _, err := client.Get(context.Background(), "tracing-rpc", "k"); err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+
+	spans := exp.GetSpans()
+	var clientSpan, serverSpan, getSpan tracetest.SpanStub
+	for _, s := range spans {
+		switch {
+		case s.Name == "/pb.MyCache/Get" && s.SpanKind.String() == "client":
+			clientSpan = s
+		case s.Name == "/pb.MyCache/Get" && s.SpanKind.String() == "server":
+			serverSpan = s
+		case s.Name == "kamacache.Group.Get":
+			getSpan = s
+		}
+	}
+	if !clientSpan.SpanContext.IsValid() || !serverSpan.SpanContext.IsValid() || !getSpan.SpanContext.IsValid() {
+		t.Fatalf("缺少 span，共导出 %d 个", len(spans))
+	}
+	if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() || getSpan.Parent.SpanID() != serverSpan.SpanContext.SpanID() {
+		t.Fatal("服务端 span 应延续客户端的链路")
+	}
+
+	t.Run("放弃读取的流在上下文结束时结束span", func(t *testing.T) {
+		exp.Reset()
+		ctx, cancel := context.WithCancel(context.Background())
+		if _, err := client.grpcCli.GetStream(ctx, &pb.Request{Group: "tracing-rpc", Key: "k"}); err != nil {
+			t.Fatalf("建立流失败: %v", err)
+		}
+		cancel()
+
+		ended := func() bool {
+			for _, s := range exp.GetSpans() {
+				if s.Name == "/pb.MyCache/GetStream" && s.SpanKind.String() == "client" {
+					return true
+				}
+			}
+			return false
+		}
+		deadline := time.Now().Add(time.Second)
+		for !ended() {
+			if time.Now().After(deadline) {
+				t.Fatal("上下文结束后客户端流的 span 应已结束")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package kamacache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 类型 T 与缓存中字节的相互转换。Unmarshal 收到的是缓存中的数据，不能修改或在返回后继续引用
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSONCodec 使用 encoding/json 编解码
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// GobCodec 使用 encoding/gob 编解码，所有节点需使用相同的类型定义
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Marshal(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// ProtoCodec 使用 protobuf 编解码，T 为生成的消息指针类型，例如 *pb.Request
+type ProtoCodec[T proto.Message] struct{}
+
+func (ProtoCodec[T]) Marshal(v T) ([]byte, error) {
+	return proto.Marshal(v)
+}
+
+func (ProtoCodec[T]) Unmarshal(data []byte) (T, error) {
+	var zero T
+	v := zero.ProtoReflect().New().Interface().(T)
+	err := proto.Unmarshal(data, v)
+	return v, err
+}
+
+// TypedOption 定义 TypedGroup 的配置选项，GroupOption 也可以作为 TypedOption 传给底层的 Group
+type TypedOption interface {
+	applyTyped(c *typedConfig)
+}
+
+// typedConfig TypedGroup 的配置
+type typedConfig struct {
+	groupOpts        []GroupOption
+	decodedCacheSize int // 解码结果缓存的容量
+}
+
+// typedOptionFunc 只作用于 TypedGroup 的配置选项
+type typedOptionFunc func(c *typedConfig)
+
+func (f typedOptionFunc) applyTyped(c *typedConfig) {
+	f(c)
+}
+
+func (o GroupOption) applyTyped(c *typedConfig) {
+	c.groupOpts = append(c.groupOpts, o)
+}
+
+// WithDecodedCache 开启解码结果缓存，最多保存 size 个热点对象，避免重复反序列化。
+// 开启后同一个 key 的多次 Get 返回同一个对象，调用方不能修改返回的对象
+func WithDecodedCache(size int) TypedOption {
+	return typedOptionFunc(func(c *typedConfig) {
+		c.decodedCacheSize = size
+	})
+}
+
+// TypedGroup 在 Group 之上按类型 T 读写缓存，由 Codec 负责序列化
+type TypedGroup[T any] struct {
+	group   *Group
+	codec   Codec[T]
+	decoded *decodedCache[T] // 解码结果缓存，nil表示不开启
+}
+
+// NewTypedGroup 在默认节点上创建类型化的缓存组，loader 在缓存未命中时加载数据。
+// 与 NewGroup 一样无法满足配置时 panic，需要处理错误或使用其他节点时使用 CreateTypedGroup
+func NewTypedGroup[T any](name string, cacheBytes int64, codec Codec[T],
+	loader func(ctx context.Context, key string) (T, error), opts ...TypedOption) *TypedGroup[T] {
+	t, err := CreateTypedGroup(defaultNode, name, cacheBytes, codec, loader, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return t
+}
+
+// CreateTypedGroup 通过 node.CreateGroup 在指定节点上创建类型化的缓存组，node 为 nil 时使用默认节点，
+// 无法满足配置时返回错误
+func CreateTypedGroup[T any](node *Node, name string, cacheBytes int64, codec Codec[T],
+	loader func(ctx context.Context, key string) (T, error), opts ...TypedOption) (*TypedGroup[T], error) {
+	if loader == nil {
+		panic("nil loader")
+	}
+	if node == nil {
+		node = defaultNode
+	}
+
+	getter := GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		v, err := loader(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return codec.Marshal(v)
+	})
+
+	var cfg typedConfig
+	for _, opt := range opts {
+		opt.applyTyped(&cfg)
+	}
+
+	g, err := node.CreateGroup(name, cacheBytes, getter, cfg.groupOpts...)
+	if err != nil {
+		return nil, err
+	}
+	t := &TypedGroup[T]{group: g, codec: codec}
+	if cfg.decodedCacheSize > 0 {
+		t.decoded = newDecodedCache[T](cfg.decodedCacheSize)
+	}
+	return t, nil
+}
+
+// Group 返回底层的 Group
+func (t *TypedGroup[T]) Group() *Group {
+	return t.group
+}
+
+// Name 返回组名
+func (t *TypedGroup[T]) Name() string {
+	return t.group.name
+}
+
+// Get 获取 key 对应的对象
+func (t *TypedGroup[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+
+	view, err := t.group.Get(ctx, key)
+	if err != nil {
+		return zero, err
+	}
+
+	if v, ok := t.decoded.get(key, view); ok {
+		return v, nil
+	}
+
+	// Get 返回的视图已经解压，直接读取底层数据，不为解码复制
+	v, err := t.codec.Unmarshal(view.data())
+	if err != nil {
+		return zero, fmt.Errorf("failed to decode value of key %s: %w", key, err)
+	}
+	t.decoded.add(key, view, v)
+	return v, nil
+}
+
+// Set 设置 key 对应的对象，使用组的过期时间
+func (t *TypedGroup[T]) Set(ctx context.Context, key string, v T) error {
+	return t.SetWithTTL(ctx, key, v, 0)
+}
+
+// SetWithTTL 设置 key 对应的对象并指定过期时间，ttl 不大于0时使用组的过期时间
+func (t *TypedGroup[T]) SetWithTTL(ctx context.Context, key string, v T, ttl time.Duration) error {
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode value of key %s: %w", key, err)
+	}
+	return t.group.SetWithTTL(ctx, key, data, ttl)
+}
+
+// Delete 删除 key 对应的对象
+func (t *TypedGroup[T]) Delete(ctx context.Context, key string) error {
+	t.decoded.remove(key)
+	return t.group.Delete(ctx, key)
+}
+
+// Close 关闭底层的 Group
+func (t *TypedGroup[T]) Close() error {
+	return t.group.Close()
+}
+
+// decodedCache 按数量淘汰的解码结果缓存。缓存项记录解码时值的版本号，
+// 本地缓存每次写入都分配新的版本号，版本号不同说明值已被更新，无需额外的失效通知
+type decodedCache[T any] struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+// decodedEntry 解码结果缓存项
+type decodedEntry[T any] struct {
+	key     string
+	version uint64 // 解码时值的版本号
+	value   T
+}
+
+func newDecodedCache[T any](capacity int) *decodedCache[T] {
+	return &decodedCache[T]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get 返回与 view 对应的解码结果，没有版本号（未写入本地缓存）的值不缓存
+func (c *decodedCache[T]) get(key string, view ByteView) (T, bool) {
+	var zero T
+	if c == nil || view.version == 0 {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*decodedEntry[T])
+	if entry.version != view.version {
+		return zero, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// add 保存 view 的解码结果
+func (c *decodedCache[T]) add(key string, view ByteView, v T) {
+	if c == nil || view.version == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &decodedEntry[T]{key: key, version: view.version, value: v}
+	if elem, ok := c.items[key]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*decodedEntry[T]).key)
+	}
+}
+
+// remove 删除 key 的解码结果
+func (c *decodedCache[T]) remove(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
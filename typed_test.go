@@ -0,0 +1,125 @@
+package kamacache
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/SuperJinggg/mycache-go/pb"
+)
+
+type typedUser struct {
+	ID   int
+	Name string
+}
+
+// 测试内置编解码器
+func TestCodecs(t *testing.T) {
+	u := typedUser{ID: 1, Name: "kama"}
+	for name, c := range map[string]Codec[typedUser]{"json": JSONCodec[typedUser]{}, "gob": GobCodec[typedUser]{}} {
+		t.Run(name, func(t *testing.T) {
+			data, err := c.Marshal(u)
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			out, err := c.Unmarshal(data)
+			if err != nil || out != u {
+				t.Fatalf("解码结果与原值不一致: %+v, %v", out, err)
+			}
+		})
+	}
+
+	t.Run("protobuf", func(t *testing.T) {
+		c := ProtoCodec[*pb.Request]{}
+		data, err := c.Marshal(&pb.Request{Group: "g", Key: "k"})
+		if err != nil {
+			t.Fatalf("编码失败: %v", err)
+		}
+		out, err := c.Unmarshal(data)
+		if err != nil || out.GetGroup() != "g" || out.GetKey() != "k" {
+			t.Fatalf("解码结果与原值不一致: %v, %v", out, err)
+		}
+	})
+}
+
+// 测试类型化缓存组的读写与解码结果缓存
+func TestTypedGroup(t *testing.T) {
+	loads := 0
+	g := NewTypedGroup("typed-test", 1<<20, JSONCodec[*typedUser]{},
+		func(ctx context.Context, key string) (*typedUser, error) {
+			loads++
+			return &typedUser{ID: loads, Name: key}, nil
+		}, WithDecodedCache(16))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	t.Run("未命中时通过loader加载", func(t *testing.T) {
+		u, err := g.Get(ctx, "alice")
+		if err != nil || u.Name != "alice" || loads != 1 {
+			t.Fatalf("加载结果不正确: %+v, %v", u, err)
+		}
+	})
+
+	t.Run("命中时复用解码结果", func(t *testing.T) {
+		a, _ := g.Get(ctx, "alice")
+		b, _ := g.Get(ctx, "alice")
+		if a != b {
+			t.Fatal("同一个值应返回同一个解码对象")
+		}
+	})
+
+	t.Run("写入后返回新值", func(t *testing.T) {
+		old, _ := g.Get(ctx, "alice")
+		if err := g.Set(ctx, "alice", &typedUser{ID: 100, Name: "alice"}); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		u, err := g.Get(ctx, "alice")
+		if err != nil || u.ID != 100 || u == old {
+			t.Fatalf("应返回写入的新值，实际为 %+v", u)
+		}
+	})
+}
+
+// 测试压缩保存的值也能复用解码结果
+func TestTypedGroupDecodedCacheCompressed(t *testing.T) {
+	g := NewTypedGroup("typed-compressed-test", 1<<20, JSONCodec[*typedUser]{},
+		func(ctx context.Context, key string) (*typedUser, error) {
+			return &typedUser{ID: 1, Name: key}, nil
+		}, WithCompression(ZstdCompressor(), 1), WithDecodedCache(16))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	g.Get(ctx, "bob")
+	a, _ := g.Get(ctx, "bob")
+	b, _ := g.Get(ctx, "bob")
+	if a == nil || a != b {
+		t.Fatal("每次解压得到新的字节，仍应返回同一个解码对象")
+	}
+}
+
+// 测试在指定节点上创建类型化的缓存组，无法满足配置时返回错误
+func TestCreateTypedGroup(t *testing.T) {
+	loader := func(ctx context.Context, key string) (*typedUser, error) {
+		return &typedUser{ID: 1, Name: key}, nil
+	}
+	node := NewNode()
+	t.Cleanup(func() { node.Close() })
+
+	g, err := CreateTypedGroup(node, "typed-node-test", 1<<20, JSONCodec[*typedUser]{}, loader)
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	if node.GetGroup("typed-node-test") != g.Group() || GetGroup("typed-node-test") != nil {
+		t.Fatal("组应只属于指定的节点")
+	}
+	if u, err := g.Get(context.Background(), "alice"); err != nil || u.Name != "alice" {
+		t.Fatalf("读取结果不正确: %+v, %v", u, err)
+	}
+
+	m := NewMemoryManager(100)
+	t.Cleanup(m.Close)
+	_, err = CreateTypedGroup(node, "typed-overcommit-test", 1<<20, JSONCodec[*typedUser]{}, loader,
+		WithMemoryManager(m, MemoryQuota{Min: 1000}))
+	if err == nil || node.GetGroup("typed-overcommit-test") != nil {
+		t.Fatalf("最小配额超过预算时应返回错误，实际为 %v", err)
+	}
+}
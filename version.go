@@ -0,0 +1,234 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrKeyExists 条件写入要求 key 不存在，但 key 已存在
+var ErrKeyExists = errors.New("key already exists")
+
+// ErrVersionMismatch 条件写入时 key 的版本号已改变
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// WriteCondition 条件写入的前提
+type WriteCondition int
+
+const (
+	IfAbsent  WriteCondition = iota + 1 // key 不存在时写入，否则返回 ErrKeyExists
+	IfPresent                           // key 存在时写入，否则返回 ErrNotFound
+	IfVersion                           // key 存在且版本号一致时写入，否则返回 ErrNotFound 或 ErrVersionMismatch
+)
+
+// check 根据 key 的当前值检查条件是否成立
+func (c WriteCondition) check(current ByteView, ok bool, version uint64) error {
+	switch c {
+	case IfAbsent:
+		if ok {
+			return ErrKeyExists
+		}
+	case IfPresent:
+		if !ok {
+			return ErrNotFound
+		}
+	case IfVersion:
+		if !ok {
+			return ErrNotFound
+		}
+		if current.version != version {
+			return ErrVersionMismatch
+		}
+	default:
+		return fmt.Errorf("unknown write condition %d", c)
+	}
+	return nil
+}
+
+// versionMetadataKey 请求中表示需要返回版本号，回复的头部中携带版本号
+const versionMetadataKey = "kamacache-version"
+
+// conditionMetadataKey 条件写入的前提，请求的 versionMetadataKey 为期望的版本号
+const conditionMetadataKey = "kamacache-condition"
+
+// versionedPeer 支持带版本号读写的节点，Client 实现了该接口
+type versionedPeer interface {
+	GetWithVersion(ctx context.Context, group string, key string) ([]byte, uint64, error)
+	SetIf(ctx context.Context, group string, key string, value []byte, ttl time.Duration, cond WriteCondition, version uint64) (uint64, error)
+}
+
+// versionedOwner 返回 key 的所有者，所有者是本节点时返回 nil
+func (g *Group) versionedOwner(ctx context.Context, key string) (versionedPeer, error) {
+	if ctx.Value("from_peer") != nil {
+		return nil, nil
+	}
+	peer, err := g.pickOwner(key)
+	if err != nil || peer == nil {
+		return nil, err
+	}
+	vp, ok := peer.(versionedPeer)
+	if !ok {
+		return nil, fmt.Errorf("peer for key %s does not support versioned operations", key)
+	}
+	return vp, nil
+}
+
+// GetWithVersion 从 key 的所有者获取值及其版本号，版本号由所有者在每次写入时分配，可用于 SetIf 的 IfVersion 条件。
+// 值过大而没有被缓存时版本号为0
+func (g *Group) GetWithVersion(ctx context.Context, key string) (ByteView, uint64, error) {
+	// 检查组是否已关闭
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return ByteView{}, 0, ErrGroupClosed
+	}
+
+	if key == "" {
+		return ByteView{}, 0, ErrKeyRequired
+	}
+
+	owner, err := g.versionedOwner(ctx, key)
+	if err != nil {
+		return ByteView{}, 0, err
+	}
+	if owner != nil {
+		value, version, err := owner.GetWithVersion(ctx, g.name, key)
+		if err != nil {
+			return ByteView{}, 0, err
+		}
+		return ByteView{b: value}, version, nil
+	}
+
+	view, err := g.get(ctx, key)
+	if err != nil {
+		return ByteView{}, 0, err
+	}
+	// 刚加载的值在写入缓存时才分配版本号
+	if view.version == 0 {
+		if cached, _, ok := g.mainCache.peek(key); ok {
+			view = cached
+		}
+	}
+
+	decoded, err := g.decode(ctx, key, view)
+	if err != nil {
+		return ByteView{}, 0, err
+	}
+	return decoded, view.version, nil
+}
+
+// SetIf 在 key 的所有者上原子地检查条件并写入，返回新值的版本号。
+// 本地未缓存的 key 会先通过 Getter 加载，因此条件与 Get 看到的结果一致
+func (g *Group) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond WriteCondition, version uint64) (uint64, error) {
+	// 检查组是否已关闭
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return 0, ErrGroupClosed
+	}
+
+	if key == "" {
+		return 0, ErrKeyRequired
+	}
+	if len(value) == 0 {
+		return 0, ErrValueRequired
+	}
+	if g.tooLarge(len(value)) {
+		return 0, fmt.Errorf("%w: %d bytes exceeds %d", ErrValueTooLarge, len(value), g.maxValueSize)
+	}
+
+	owner, err := g.versionedOwner(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if owner != nil {
+		// 本地副本不再可信，直接删除；所有者负责持久化
+		g.mainCache.Delete(key)
+		ctx = metadata.AppendToOutgoingContext(ctx, persistMetadataKey, "1")
+		return owner.SetIf(ctx, g.name, key, value, ttl, cond, version)
+	}
+
+	if _, _, ok := g.mainCache.peek(key); !ok {
+		if _, err := g.get(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return 0, err
+		}
+	}
+
+	if ttl <= 0 {
+		ttl = g.expiration
+	}
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+
+	view := g.compress(ByteView{b: cloneBytes(value)})
+	newVersion, err := g.mainCache.addIf(key, view, expireAt, func(current ByteView, ok bool) error {
+		return cond.check(current, ok, version)
+	})
+	if err != nil {
+		return 0, err
+	}
+	g.dropStale(key)
+
+	// 新值写入后，之前发放的租约不再允许回写
+	if g.leases != nil {
+		g.leases.invalidate(key, ByteView{}, false)
+	}
+
+	// 条件检查通过后才持久化，持久化失败时删除缓存项，之后的读取从后端存储重新加载
+	if persistsHere(ctx) {
+		if err := g.persist(ctx, key, value, false); err != nil {
+			g.mainCache.Delete(key)
+			return 0, err
+		}
+	}
+
+	if ctx.Value("from_peer") == nil && g.peers != nil {
+		go g.syncToPeers(ctx, "set", key, value, ttl)
+	}
+	return newVersion, nil
+}
+
+// requestedVersion 解析请求中的版本号元数据，requested 为 false 表示请求没有携带
+func requestedVersion(ctx context.Context) (version uint64, requested bool, err error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false, nil
+	}
+	values := md.Get(versionMetadataKey)
+	if len(values) == 0 {
+		return 0, false, nil
+	}
+	version, err = strconv.ParseUint(values[0], 10, 64)
+	return version, true, err
+}
+
+// requestedCondition 解析请求中的条件写入元数据，请求没有携带时返回0
+func requestedCondition(ctx context.Context) (WriteCondition, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+	values := md.Get(conditionMetadataKey)
+	if len(values) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(values[0])
+	return WriteCondition(n), err
+}
+
+// versionHeader 返回携带版本号的回复头部
+func versionHeader(version uint64) metadata.MD {
+	return metadata.Pairs(versionMetadataKey, strconv.FormatUint(version, 10))
+}
+
+// headerVersion 从回复头部解析版本号
+func headerVersion(header metadata.MD) (uint64, error) {
+	values := header.Get(versionMetadataKey)
+	if len(values) == 0 {
+		return 0, fmt.Errorf("response is missing %s header", versionMetadataKey)
+	}
+	return strconv.ParseUint(values[0], 10, 64)
+}
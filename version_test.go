@@ -0,0 +1,192 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// 测试条件写入的各个条件
+func TestSetIf(t *testing.T) {
+	g := NewGroup("set-if-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if key == "db" {
+			return []byte("loaded"), nil
+		}
+		return nil, ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	if err := g.Set(ctx, "k", []byte("v1")); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	_, current, err := g.GetWithVersion(ctx, "k")
+	if err != nil || current == 0 {
+		t.Fatalf("应返回版本号，实际为 %d: %v", current, err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		cond    WriteCondition
+		version uint64
+		wantErr error
+	}{
+		{"不存在时写入", "new", IfAbsent, 0, nil},
+		{"已存在时拒绝写入", "k", IfAbsent, 0, ErrKeyExists},
+		{"Getter 能加载的键视为已存在", "db", IfAbsent, 0, ErrKeyExists},
+		{"不存在时拒绝替换", "missing", IfPresent, 0, ErrNotFound},
+		{"不存在时拒绝 CAS", "missing", IfVersion, current, ErrNotFound},
+		{"版本号不一致时拒绝写入", "k", IfVersion, current + 1, ErrVersionMismatch},
+		{"版本号一致时写入", "k", IfVersion, current, nil},
+		{"旧版本号不能再次写入", "k", IfVersion, current, ErrVersionMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := g.SetIf(ctx, tt.key, []byte("v2"), 0, tt.cond, tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("错误应为 %v，实际为 %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			view, got, err := g.GetWithVersion(ctx, tt.key)
+			if err != nil || view.String() != "v2" || got != version {
+				t.Fatalf("应读到写入的值和版本号 %d，实际为 %q, %d: %v", version, view.String(), got, err)
+			}
+		})
+	}
+
+	t.Run("普通写入改变版本号", func(t *testing.T) {
+		_, before, _ := g.GetWithVersion(ctx, "k")
+		g.Set(ctx, "k", []byte("v3"))
+		if _, after, _ := g.GetWithVersion(ctx, "k"); after == before {
+			t.Fatal("写入后版本号应改变")
+		}
+	})
+}
+
+// 测试并发的 CAS 不会丢失更新
+func TestSetIfConcurrent(t *testing.T) {
+	g := NewGroup("set-if-concurrent-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+	g.Set(ctx, "n", []byte("0"))
+
+	const workers, rounds = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				for {
+					view, version, err := g.GetWithVersion(ctx, "n")
+					if err != nil {
+						t.Errorf("读取失败: %v", err)
+						return
+					}
+					n, _ := strconv.Atoi(view.String())
+					_, err = g.SetIf(ctx, "n", []byte(strconv.Itoa(n+1)), 0, IfVersion, version)
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, ErrVersionMismatch) {
+						t.Errorf("写入失败: %v", err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if view, _ := g.Get(ctx, "n"); view.String() != strconv.Itoa(workers*rounds) {
+		t.Fatalf("计数应为 %d，实际为 %s", workers*rounds, view.String())
+	}
+}
+
+// 测试非所有者节点的条件写入和下限由所有者执行
+func TestVersionedOnOwner(t *testing.T) {
+	ctx := context.Background()
+	addrs := make([]string, 2)
+	listeners := make([]net.Listener, 2)
+	for i := range listeners {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("监听失败: %v", err)
+		}
+		listeners[i], addrs[i] = ln, ln.Addr().String()
+	}
+
+	groups := make([]*Group, 2)
+	var picker *ClientPicker
+	for i := range groups {
+		node := NewNode()
+		p := newTestPicker(t, addrs[i], addrs[1-i])
+		if i == 0 {
+			picker = p
+		}
+		groups[i] = node.NewGroup("versioned-owner-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+			return nil, ErrNotFound
+		}), WithPeers(p))
+
+		srv, err := node.NewServer(addrs[i], "versioned-owner-test")
+		if err != nil {
+			t.Fatalf("创建服务器失败: %v", err)
+		}
+		go srv.grpcServer.Serve(listeners[i])
+		t.Cleanup(func() { node.Close() })
+	}
+
+	local, owner := groups[0], groups[1]
+	key := keyOwnedBy(t, picker, addrs[1])
+
+	t.Run("版本号由所有者分配", func(t *testing.T) {
+		version, err := local.SetIf(ctx, key, []byte("v1"), 0, IfAbsent, 0)
+		if err != nil {
+			t.Fatalf("条件写入失败: %v", err)
+		}
+		if _, err := local.SetIf(ctx, key, []byte("v2"), 0, IfAbsent, 0); !errors.Is(err, ErrKeyExists) {
+			t.Fatalf("已存在时应返回 ErrKeyExists，实际为 %v", err)
+		}
+
+		view, got, err := owner.GetWithVersion(ctx, key)
+		if err != nil || view.String() != "v1" || got != version {
+			t.Fatalf("所有者应保存 v1 和版本号 %d，实际为 %q, %d: %v", version, view.String(), got, err)
+		}
+		view, got, err = local.GetWithVersion(ctx, key)
+		if err != nil || view.String() != "v1" || got != version {
+			t.Fatalf("非所有者应读到所有者的版本号 %d，实际为 %q, %d: %v", version, view.String(), got, err)
+		}
+
+		if _, err := local.SetIf(ctx, key, []byte("v3"), 0, IfVersion, version+1); !errors.Is(err, ErrVersionMismatch) {
+			t.Fatalf("版本号不一致时应返回 ErrVersionMismatch，实际为 %v", err)
+		}
+		if _, err := local.SetIf(ctx, key, []byte("v3"), 0, IfVersion, version); err != nil {
+			t.Fatalf("版本号一致时应写入成功: %v", err)
+		}
+	})
+
+	t.Run("下限由所有者截断", func(t *testing.T) {
+		// 非所有者的 Delete 异步同步给所有者，直接在所有者上删除
+		if err := owner.Delete(ctx, key); err != nil {
+			t.Fatalf("删除失败: %v", err)
+		}
+		if _, err := local.Incr(ctx, key, 3, 0); err != nil {
+			t.Fatalf("创建计数器失败: %v", err)
+		}
+		if n, err := local.IncrWithFloor(ctx, key, -10, 0, 0); err != nil || n != 0 {
+			t.Fatalf("结果应截断为0，实际为 %d: %v", n, err)
+		}
+		if n, err := owner.Incr(ctx, key, 0, 0); err != nil || n != 0 {
+			t.Fatalf("所有者保存的值应为0，实际为 %d: %v", n, err)
+		}
+	})
+}
@@ -0,0 +1,418 @@
+package kamacache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrWriteBacklogFull write-behind 队列已满错误
+var ErrWriteBacklogFull = errors.New("write-behind backlog is full")
+
+// Setter 将写入持久化到后端存储的接口
+type Setter interface {
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// SetterFunc 函数类型实现 Setter 接口
+type SetterFunc func(ctx context.Context, key string, value []byte) error
+
+// Set 实现 Setter 接口
+func (f SetterFunc) Set(ctx context.Context, key string, value []byte) error {
+	return f(ctx, key, value)
+}
+
+// Deleter 从后端存储删除数据的接口
+type Deleter interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// DeleterFunc 函数类型实现 Deleter 接口
+type DeleterFunc func(ctx context.Context, key string) error
+
+// Delete 实现 Deleter 接口
+func (f DeleterFunc) Delete(ctx context.Context, key string) error {
+	return f(ctx, key)
+}
+
+// Write 一次待持久化的写入，Delete 为 true 时表示删除
+type Write struct {
+	Key    string
+	Value  []byte
+	Delete bool
+}
+
+// BatchWriter 可选接口，Setter 同时实现时 write-behind 模式按批写入，返回错误时整批重试
+type BatchWriter interface {
+	WriteBatch(ctx context.Context, writes []Write) error
+}
+
+// WriteMode 组的写入模式
+type WriteMode int
+
+const (
+	// WriteAround 默认模式，Set 和 Delete 只作用于缓存
+	WriteAround WriteMode = iota
+	// WriteThrough 先同步写入后端存储，成功后再更新缓存
+	WriteThrough
+	// WriteBehind 先更新缓存，再由后台异步批量写入后端存储
+	WriteBehind
+)
+
+// String 返回写入模式的名称
+func (m WriteMode) String() string {
+	switch m {
+	case WriteThrough:
+		return "write-through"
+	case WriteBehind:
+		return "write-behind"
+	default:
+		return "write-around"
+	}
+}
+
+// WriteBehindOptions write-behind 模式的配置
+type WriteBehindOptions struct {
+	FlushInterval time.Duration // 刷新间隔，默认100ms
+	BatchSize     int           // 每批写入的最大数量，队列达到该数量时立即刷新，默认100
+	MaxBacklog    int           // 队列中最多等待写入的 key 数量，超过时 Set 返回 ErrWriteBacklogFull，默认10000
+	MaxRetries    int           // 单个写入的最大尝试次数，失败后在下一次刷新时重试，默认3
+	Timeout       time.Duration // 每批写入的超时时间，默认5s
+}
+
+// WithWriteThrough 开启 write-through 模式，Set 和 Delete 先写入后端存储，成功后再更新缓存。
+// deleter 为 nil 时删除只作用于缓存
+func WithWriteThrough(setter Setter, deleter Deleter) GroupOption {
+	return func(g *Group) {
+		g.writeMode = WriteThrough
+		g.setter = setter
+		g.deleter = deleter
+	}
+}
+
+// WithWriteBehind 开启 write-behind 模式，Set 和 Delete 更新缓存后进入队列，由后台异步批量写入后端存储。
+// 同一个 key 的多次写入在队列中合并为最后一次，写入失败时重试，超过 MaxRetries 后丢弃并记录到统计信息中。
+// 尚未写入的值在本地缓存淘汰后仍会从队列中读取，组关闭时写入剩余的数据
+func WithWriteBehind(setter Setter, deleter Deleter, opts WriteBehindOptions) GroupOption {
+	return func(g *Group) {
+		g.writeMode = WriteBehind
+		g.setter = setter
+		g.deleter = deleter
+		g.writeBehindOpts = opts
+	}
+}
+
+// persistMetadataKey 非所有者节点将客户端写入转发给所有者时携带的元数据，所有者收到后负责持久化
+const persistMetadataKey = "kamacache-persist"
+
+// replicaMetadataKey 节点之间同步副本或移交数据时携带的元数据，收到的节点只更新缓存，不持久化也不再同步
+const replicaMetadataKey = "kamacache-replica"
+
+// ownerWriteKey 标记其他节点转发来、需要由本节点持久化的写入
+type ownerWriteKey struct{}
+
+// peerWriteContext 为发往其他节点的写入标记来源：同步副本或移交的数据携带 replicaMetadataKey
+func peerWriteContext(ctx context.Context) context.Context {
+	if ctx.Value("from_peer") != nil {
+		return metadata.AppendToOutgoingContext(ctx, replicaMetadataKey, "1")
+	}
+	return ctx
+}
+
+// persistsHere 判断写入是否由本节点持久化：客户端直接写入本节点，或其他节点转发来的客户端写入
+func persistsHere(ctx context.Context) bool {
+	return ctx.Value("from_peer") == nil || ctx.Value(ownerWriteKey{}) != nil
+}
+
+// forwardWrite 将需要持久化的客户端写入同步转发给 key 的所有者，由所有者写入后端存储，
+// 与 Incr 一样保证同一个 key 只在一个节点上持久化。forwarded 为 false 表示应由本节点持久化
+// （本节点是所有者、没有其他节点或值只保存在本地）
+func (g *Group) forwardWrite(ctx context.Context, key string, value []byte, ttl time.Duration, del bool) (forwarded bool, err error) {
+	if g.writeMode == WriteAround || g.peers == nil || (!del && g.localOnly(len(value))) {
+		return false, nil
+	}
+
+	peer, err := g.pickOwner(key)
+	if err != nil || peer == nil {
+		return false, err
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, persistMetadataKey, "1")
+	if del {
+		_, err = peer.Delete(ctx, g.name, key)
+	} else {
+		err = peer.Set(ctx, g.name, key, value, ttl)
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to forward write of key %s to owner: %w", key, err)
+	}
+	return true, nil
+}
+
+// persist 按组的写入模式将写入传递给后端存储，只在 key 的所有者节点上执行
+func (g *Group) persist(ctx context.Context, key string, value []byte, del bool) error {
+	switch g.writeMode {
+	case WriteThrough:
+		var err error
+		if del {
+			if g.deleter == nil {
+				return nil
+			}
+			err = g.deleter.Delete(ctx, key)
+		} else {
+			err = g.setter.Set(ctx, key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to persist key %s: %w", key, err)
+		}
+		return nil
+	case WriteBehind:
+		return g.writeBehind.enqueue(key, value, del)
+	}
+	return nil
+}
+
+// writeBehind 异步写入队列，按 key 合并写入，由单个后台协程按批写入后端存储
+type writeBehind struct {
+	name    string
+	setter  Setter
+	deleter Deleter
+	opts    WriteBehindOptions
+
+	mu       sync.Mutex
+	pending  map[string]*pendingWrite // 等待写入的数据
+	order    []string                 // pending 中 key 的入队顺序
+	inflight map[string]*pendingWrite // 正在写入的数据
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+
+	flushed  int64 // 写入成功次数
+	failures int64 // 重试耗尽后丢弃的次数
+	retries  int64 // 重试次数
+}
+
+// pendingWrite 队列中一个 key 的最新写入
+type pendingWrite struct {
+	value    []byte
+	delete   bool
+	attempts int
+}
+
+// newWriteBehind 创建并启动写入队列
+func newWriteBehind(name string, setter Setter, deleter Deleter, opts WriteBehindOptions) *writeBehind {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 100 * time.Millisecond
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.MaxBacklog <= 0 {
+		opts.MaxBacklog = 10000
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	w := &writeBehind{
+		name:     name,
+		setter:   setter,
+		deleter:  deleter,
+		opts:     opts,
+		pending:  make(map[string]*pendingWrite),
+		inflight: make(map[string]*pendingWrite),
+		flushCh:  make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+// enqueue 将写入加入队列，已在队列中的 key 直接替换为新值
+func (w *writeBehind) enqueue(key string, value []byte, del bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// 后端存储不支持删除时，丢弃尚未写入的旧值即可
+	if del && w.deleter == nil {
+		if _, ok := w.pending[key]; ok {
+			delete(w.pending, key)
+			w.removeOrder(key)
+		}
+		return nil
+	}
+
+	if p, ok := w.pending[key]; ok {
+		p.value = cloneBytes(value)
+		p.delete = del
+		p.attempts = 0
+		return nil
+	}
+	if len(w.pending) >= w.opts.MaxBacklog {
+		return fmt.Errorf("%w: %d keys pending in group %s", ErrWriteBacklogFull, len(w.pending), w.name)
+	}
+
+	w.pending[key] = &pendingWrite{value: cloneBytes(value), delete: del}
+	w.order = append(w.order, key)
+	if len(w.order) >= w.opts.BatchSize {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// removeOrder 从入队顺序中移除 key
+func (w *writeBehind) removeOrder(key string) {
+	for i, k := range w.order {
+		if k == key {
+			w.order = append(w.order[:i], w.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// lookup 返回 key 尚未写入后端存储的最新写入
+func (w *writeBehind) lookup(key string) (*pendingWrite, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if p, ok := w.pending[key]; ok {
+		return p, true
+	}
+	p, ok := w.inflight[key]
+	return p, ok
+}
+
+// depth 返回队列中等待写入的数量
+func (w *writeBehind) depth() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
+
+// loop 定期刷新队列，关闭时写入剩余的数据
+func (w *writeBehind) loop() {
+	defer close(w.doneCh)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-w.flushCh:
+			w.flush()
+		case <-w.stopCh:
+			for w.flush() > 0 {
+			}
+			return
+		}
+	}
+}
+
+// flush 按批写入本次开始时队列中的数据，返回重新入队等待重试的数量
+func (w *writeBehind) flush() int {
+	w.mu.Lock()
+	n := len(w.order)
+	w.mu.Unlock()
+
+	requeued := 0
+	for n > 0 {
+		batch := w.take(min(n, w.opts.BatchSize))
+		if len(batch) == 0 {
+			break
+		}
+		n -= len(batch)
+		requeued += w.write(batch)
+	}
+	return requeued
+}
+
+// take 从队列头部取出最多 n 个写入，标记为正在写入
+func (w *writeBehind) take(n int) []Write {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n = min(n, len(w.order))
+	batch := make([]Write, 0, n)
+	for _, key := range w.order[:n] {
+		p := w.pending[key]
+		delete(w.pending, key)
+		w.inflight[key] = p
+		batch = append(batch, Write{Key: key, Value: p.value, Delete: p.delete})
+	}
+	w.order = w.order[n:]
+	return batch
+}
+
+// write 写入一批数据，失败的写入重新入队，返回重新入队的数量
+func (w *writeBehind) write(batch []Write) int {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.Timeout)
+	defer cancel()
+
+	errs := make([]error, len(batch))
+	if bw, ok := w.setter.(BatchWriter); ok {
+		if err := bw.WriteBatch(ctx, batch); err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+		}
+	} else {
+		for i, wr := range batch {
+			if wr.Delete {
+				errs[i] = w.deleter.Delete(ctx, wr.Key)
+			} else {
+				errs[i] = w.setter.Set(ctx, wr.Key, wr.Value)
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	requeued := 0
+	for i, wr := range batch {
+		p := w.inflight[wr.Key]
+		delete(w.inflight, wr.Key)
+		if errs[i] == nil {
+			atomic.AddInt64(&w.flushed, 1)
+			continue
+		}
+
+		// 写入期间已有更新的值入队，旧值无需重试
+		if _, ok := w.pending[wr.Key]; ok {
+			continue
+		}
+		p.attempts++
+		if p.attempts >= w.opts.MaxRetries {
+			atomic.AddInt64(&w.failures, 1)
+			logrus.Errorf("[KamaCache] write-behind dropped key %s of group %s after %d attempts: %v", wr.Key, w.name, p.attempts, errs[i])
+			continue
+		}
+		atomic.AddInt64(&w.retries, 1)
+		w.pending[wr.Key] = p
+		w.order = append(w.order, wr.Key)
+		requeued++
+	}
+	return requeued
+}
+
+// close 停止后台协程并写入剩余的数据
+func (w *writeBehind) close() {
+	close(w.stopCh)
+	<-w.doneCh
+}
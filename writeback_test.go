@@ -0,0 +1,328 @@
+package kamacache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// memStore 模拟后端存储，fail 为 true 时写入失败
+type memStore struct {
+	mu     sync.Mutex
+	data   map[string]string
+	writes int
+	fail   bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string]string)}
+}
+
+func (s *memStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.data[key]; ok {
+		return []byte(v), nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memStore) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	s.data[key] = string(value)
+	return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	delete(s.data, key)
+	return nil
+}
+
+func (s *memStore) value(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+// 测试 write-through 模式
+func TestWriteThrough(t *testing.T) {
+	store := newMemStore()
+	g := NewGroup("write-through-test", 1<<20, store, WithWriteThrough(store, store))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	t.Run("写入后端存储后再缓存", func(t *testing.T) {
+		if err := g.Set(ctx, "k", []byte("v1")); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		if v, _ := store.value("k"); v != "v1" {
+			t.Fatalf("后端存储中的值应为 v1，实际为 %q", v)
+		}
+	})
+
+	t.Run("后端存储失败时不更新缓存", func(t *testing.T) {
+		store.fail = true
+		defer func() { store.fail = false }()
+		if err := g.Set(ctx, "k", []byte("v2")); err == nil {
+			t.Fatal("后端存储失败时 Set 应返回错误")
+		}
+		if view, _ := g.Get(ctx, "k"); view.String() != "v1" {
+			t.Fatalf("缓存中应保留旧值，实际为 %q", view.String())
+		}
+	})
+
+	t.Run("删除传递给后端存储", func(t *testing.T) {
+		if err := g.Delete(ctx, "k"); err != nil {
+			t.Fatalf("删除失败: %v", err)
+		}
+		if _, ok := store.value("k"); ok {
+			t.Fatal("后端存储中的值应已删除")
+		}
+	})
+
+	t.Run("没有 Setter 时返回错误", func(t *testing.T) {
+		if _, err := CreateGroup("write-through-nil-test", 1<<20, store, WithWriteThrough(nil, nil)); !errors.Is(err, ErrSetterRequired) {
+			t.Fatalf("应返回 ErrSetterRequired，实际为 %v", err)
+		}
+		if GetGroup("write-through-nil-test") != nil {
+			t.Fatal("创建失败的组不应注册")
+		}
+	})
+}
+
+// 测试 write-behind 模式的合并、重试与读取未写入的值
+func TestWriteBehind(t *testing.T) {
+	store := newMemStore()
+	g := NewGroup("write-behind-test", 1<<20, store, WithWriteBehind(store, store, WriteBehindOptions{
+		FlushInterval: time.Hour,
+		MaxBacklog:    2,
+		MaxRetries:    2,
+	}))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	t.Run("同一个key的写入合并", func(t *testing.T) {
+		for _, v := range []string{"v1", "v2", "v3"} {
+			if err := g.Set(ctx, "a", []byte(v)); err != nil {
+				t.Fatalf("写入失败: %v", err)
+			}
+		}
+		if depth := g.Stats()["write_queue_depth"]; depth != 1 {
+			t.Fatalf("队列长度应为1，实际为 %v", depth)
+		}
+	})
+
+	t.Run("队列已满时拒绝写入", func(t *testing.T) {
+		if err := g.Set(ctx, "b", []byte("v")); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		if err := g.Set(ctx, "c", []byte("v")); !errors.Is(err, ErrWriteBacklogFull) {
+			t.Fatalf("应返回 ErrWriteBacklogFull，实际为 %v", err)
+		}
+	})
+
+	t.Run("缓存淘汰后从队列读取", func(t *testing.T) {
+		g.Clear()
+		if view, err := g.Get(ctx, "a"); err != nil || view.String() != "v3" {
+			t.Fatalf("应读取到队列中的值 v3，实际为 %q, %v", view.String(), err)
+		}
+	})
+
+	t.Run("写入失败后重试", func(t *testing.T) {
+		store.fail = true
+		g.writeBehind.flush()
+		if depth := g.writeBehind.depth(); depth != 2 {
+			t.Fatalf("失败的写入应重新入队，队列长度为 %d", depth)
+		}
+		g.writeBehind.flush()
+		if failures := g.Stats()["write_flush_failures"]; failures != int64(2) {
+			t.Fatalf("重试耗尽后应记录失败次数，实际为 %v", failures)
+		}
+		store.fail = false
+	})
+
+	t.Run("关闭时写入剩余数据", func(t *testing.T) {
+		if err := g.Set(ctx, "a", []byte("final")); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		g.Close()
+		if v, _ := store.value("a"); v != "final" {
+			t.Fatalf("关闭后后端存储中的值应为 final，实际为 %q", v)
+		}
+	})
+}
+
+// 测试非所有者节点收到的写入由所有者持久化
+func TestPersistOnOwner(t *testing.T) {
+	ctx := context.Background()
+	type member struct {
+		addr  string
+		store *memStore
+		group *Group
+	}
+
+	listeners := make([]net.Listener, 2)
+	members := make([]*member, 2)
+	for i := range members {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("监听失败: %v", err)
+		}
+		listeners[i] = ln
+		members[i] = &member{addr: ln.Addr().String(), store: newMemStore()}
+	}
+
+	var picker *ClientPicker
+	for i, m := range members {
+		node := NewNode()
+		p := newTestPicker(t, m.addr, members[1-i].addr)
+		if i == 0 {
+			picker = p
+		}
+		m.group = node.NewGroup("persist-owner-test", 1<<20, m.store, WithPeers(p), WithWriteThrough(m.store, m.store))
+
+		srv, err := node.NewServer(m.addr, "persist-owner-test")
+		if err != nil {
+			t.Fatalf("创建服务器失败: %v", err)
+		}
+		go srv.grpcServer.Serve(listeners[i])
+		t.Cleanup(func() { node.Close() })
+	}
+
+	local, owner := members[0], members[1]
+	key := keyOwnedBy(t, picker, owner.addr)
+
+	t.Run("写入由所有者持久化", func(t *testing.T) {
+		if err := local.group.Set(ctx, key, []byte("v1")); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		if v, ok := owner.store.value(key); !ok || v != "v1" {
+			t.Fatalf("所有者的后端存储中应为 v1，实际为 %q", v)
+		}
+		if _, ok := local.store.value(key); ok {
+			t.Fatal("非所有者节点不应持久化")
+		}
+		if view, err := owner.group.Get(ctx, key); err != nil || view.String() != "v1" {
+			t.Fatalf("所有者应已缓存写入的值，实际为 %q, %v", view.String(), err)
+		}
+	})
+
+	t.Run("所有者持久化失败时写入失败", func(t *testing.T) {
+		owner.store.fail = true
+		defer func() { owner.store.fail = false }()
+		if err := local.group.Set(ctx, key, []byte("v2")); err == nil {
+			t.Fatal("所有者持久化失败时 Set 应返回错误")
+		}
+	})
+
+	t.Run("客户端直接写入所有者时由所有者持久化", func(t *testing.T) {
+		client, err := NewClient(owner.addr, "persist-owner-test", nil)
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		defer client.Close()
+
+		if err := client.Set(ctx, "persist-owner-test", key, []byte("v3"), 0); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		if v, ok := owner.store.value(key); !ok || v != "v3" {
+			t.Fatalf("所有者的后端存储中应为 v3，实际为 %q", v)
+		}
+	})
+
+	t.Run("删除由所有者持久化", func(t *testing.T) {
+		if err := local.group.Delete(ctx, key); err != nil {
+			t.Fatalf("删除失败: %v", err)
+		}
+		if _, ok := owner.store.value(key); ok {
+			t.Fatal("所有者的后端存储中的值应已删除")
+		}
+		if local.store.writes != 0 {
+			t.Fatalf("非所有者节点不应写入后端存储，实际写入 %d 次", local.store.writes)
+		}
+	})
+}
+
+// 测试服务器只信任节点携带的持久化和副本标记
+func TestServerWriteContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		auth        bool
+		identity    string
+		md          []string
+		wantPeer    bool
+		wantPersist bool
+	}{
+		{"客户端直接写入", false, "", nil, false, true},
+		{"转发来的写入", false, "", []string{persistMetadataKey, "1"}, true, true},
+		{"同步来的副本", false, "", []string{replicaMetadataKey, "1"}, true, false},
+		{"认证节点转发来的写入", true, "node-b", []string{persistMetadataKey, "1"}, true, true},
+		{"认证节点同步来的副本", true, "node-b", []string{replicaMetadataKey, "1"}, true, false},
+		{"非节点身份的持久化标记被忽略", true, "app", []string{persistMetadataKey, "1"}, false, true},
+		{"非节点身份的副本标记被忽略", true, "app", []string{replicaMetadataKey, "1"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &ServerOptions{PeerIdentities: []string{"node-a", "node-b"}}
+			if tt.auth {
+				opts.Authenticator = StaticTokenAuthenticator{}
+			}
+			s := &Server{opts: opts}
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(tt.md...))
+			if tt.identity != "" {
+				ctx = context.WithValue(ctx, identityKey{}, tt.identity)
+			}
+			ctx = s.writeContext(ctx)
+			if got := ctx.Value("from_peer") != nil; got != tt.wantPeer {
+				t.Errorf("是否视为节点请求应为 %v，实际为 %v", tt.wantPeer, got)
+			}
+			if got := persistsHere(ctx); got != tt.wantPersist {
+				t.Errorf("是否由本节点持久化应为 %v，实际为 %v", tt.wantPersist, got)
+			}
+		})
+	}
+}
+
+// 测试从写入队列读取的值与数据源加载的值一样经过压缩并保存旧值
+func TestWriteBehindQueuedValuePopulate(t *testing.T) {
+	store := newMemStore()
+	g := NewGroup("write-behind-populate-test", 1<<20, store,
+		WithWriteBehind(store, store, WriteBehindOptions{FlushInterval: time.Hour}),
+		WithCompression(ZstdCompressor(), 64))
+	t.Cleanup(func() { g.Close() })
+	ctx := context.Background()
+
+	value := []byte(strings.Repeat("queued value ", 100))
+	if err := g.Set(ctx, "k", value); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	g.mainCache.Delete("k")
+
+	if view, err := g.Get(ctx, "k"); err != nil || !bytes.Equal(view.ByteSLice(), value) {
+		t.Fatalf("应读取到队列中的值: %v", err)
+	}
+	if cached, err := g.get(ctx, "k"); err != nil || cached.codec == nil {
+		t.Fatalf("队列中的值写入缓存时应压缩: %v", err)
+	}
+}